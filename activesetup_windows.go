@@ -0,0 +1,130 @@
+//+build windows
+
+package autoruns
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// compareActiveSetupVersions compares two versions of an Active Setup
+// component, such as "10,0,19041,1", part by part. Parts can be separated by
+// commas or dots, and missing parts count as zero.
+func compareActiveSetupVersions(a string, b string) int {
+	split := func(version string) []string {
+		return strings.FieldsFunc(version, func(r rune) bool { return r == ',' || r == '.' })
+	}
+	partsA, partsB := split(a), split(b)
+
+	for i := 0; i < len(partsA) || i < len(partsB); i++ {
+		var numberA, numberB int
+		if i < len(partsA) {
+			numberA, _ = strconv.Atoi(strings.TrimSpace(partsA[i]))
+		}
+		if i < len(partsB) {
+			numberB, _ = strconv.Atoi(strings.TrimSpace(partsB[i]))
+		}
+
+		if numberA != numberB {
+			if numberA < numberB {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// activeSetupPending checks whether the StubPath of a component runs at the
+// next logon of the current user, whose copy of the component is kept at
+// the same path in CURRENT_USER. It does if the user has no copy of the
+// component yet, or an older version of it than the machine. Without a
+// version on the machine, a component only runs once per user.
+func activeSetupPending(opts Options, componentKey string, machineVersion string) (pending bool, known bool) {
+	key, err := registryFor(opts).OpenKey(registry.CURRENT_USER, componentKey)
+	if errors.Is(err, errOutOfScope) {
+		return false, false
+	} else if err != nil {
+		return true, true
+	}
+
+	userVersion, _, err := key.GetStringValue("Version")
+	key.Close()
+	if machineVersion == "" {
+		return false, true
+	}
+	if err != nil || userVersion == "" {
+		return true, true
+	}
+
+	return compareActiveSetupVersions(userVersion, machineVersion) < 0, true
+}
+
+// This function enumerates the Active Setup components, whose StubPath is
+// run once for every user at logon. Version holds the version of the
+// component on the machine, Trigger is "next_logon" if it has not been run
+// for the current user at that version yet, and StartMode is "disabled" if
+// the component is marked as not installed.
+func windowsGetActiveSetup(opts Options) (records []*Autorun) {
+	var reg registry.Key = registry.LOCAL_MACHINE
+	componentsKeys := []string{
+		"Software\\Microsoft\\Active Setup\\Installed Components",
+		"Software\\Wow6432Node\\Microsoft\\Active Setup\\Installed Components",
+	}
+
+	for _, componentsKey := range componentsKeys {
+		// Open registry key.
+		key, err := openKey(opts, reg, componentsKey)
+		if err != nil {
+			continue
+		}
+
+		// Enumerate subkeys.
+		names, err := key.ReadSubKeyNames(0)
+		key.Close()
+		if err != nil {
+			continue
+		}
+
+		for _, name := range names {
+			subkeyPath := fmt.Sprintf("%s\\%s", componentsKey, name)
+			subkey, err := openKey(opts, reg, subkeyPath)
+			if err != nil {
+				continue
+			}
+
+			// Check if there is a StubPath value.
+			stubPath, _, err := subkey.GetStringValue("StubPath")
+			if err != nil || strings.TrimSpace(stubPath) == "" {
+				subkey.Close()
+				continue
+			}
+			version, _, _ := subkey.GetStringValue("Version")
+			isInstalled, _, err := subkey.GetIntegerValue("IsInstalled")
+			installed := err != nil || isInstalled != 0
+			subkey.Close()
+
+			imageLocation := fmt.Sprintf("%s\\%s", registryToString(reg), subkeyPath)
+
+			// We pass the value string to a function to return an Autorun.
+			newAutorun := stringToAutorun(opts, "active_setup", imageLocation, stubPath, true, name)
+			newAutorun.RawName = "StubPath"
+			newAutorun.Version = version
+			if !installed {
+				newAutorun.StartMode = "disabled"
+			} else if pending, known := activeSetupPending(opts, subkeyPath, version); known && pending {
+				newAutorun.Trigger = "next_logon"
+			}
+
+			// Add the new autorun to the records.
+			records = append(records, newAutorun)
+		}
+	}
+
+	return
+}
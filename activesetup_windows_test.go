@@ -0,0 +1,72 @@
+//+build windows
+
+package autoruns
+
+import "testing"
+
+func TestCompareActiveSetupVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"10,0,19041,1", "10,0,19041,1", 0},
+		// Commas and dots separate the parts alike.
+		{"10.0.19041.1", "10,0,19041,1", 0},
+		{"10,0,19041,1", "10,0,19041,2", -1},
+		{"11,0", "10,9,9999", 1},
+		// Parts are compared as numbers, not as strings.
+		{"10,0,9", "10,0,10", -1},
+		// Missing parts count as zero.
+		{"10", "10,0,0,0", 0},
+		{"10,0", "10,0,0,1", -1},
+		{"10,0,0,1", "10", 1},
+		{"", "1", -1},
+		{" 10, 1", "10,1", 0},
+	}
+	for _, test := range tests {
+		if got := compareActiveSetupVersions(test.a, test.b); got != test.want {
+			t.Errorf("compareActiveSetupVersions(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestActiveSetupPending(t *testing.T) {
+	component := `Software\Microsoft\Active Setup\Installed Components\{89820200-ECBD-11cf-8B85-00AA005B4340}`
+
+	tests := []struct {
+		name           string
+		userValues     fakeValues
+		machineVersion string
+		want           bool
+	}{
+		{"no per-user key", nil, "10,0,19041,1", true},
+		{"no per-user key nor version", nil, "", true},
+		{"same version", fakeValues{"Version": "10,0,19041,1"}, "10,0,19041,1", false},
+		{"same version with dots", fakeValues{"Version": "10.0.19041.1"}, "10,0,19041,1", false},
+		{"older version", fakeValues{"Version": "10,0,19041,0"}, "10,0,19041,1", true},
+		{"fewer parts", fakeValues{"Version": "10,0"}, "10,0,19041,1", true},
+		{"newer version", fakeValues{"Version": "10,1"}, "10,0,19041,1", false},
+		{"no per-user version", fakeValues{}, "10,0,19041,1", true},
+		{"no machine version", fakeValues{}, "", false},
+	}
+	for _, test := range tests {
+		reg := fakeRegistry{`LOCAL_MACHINE\` + component: {"Version": test.machineVersion}}
+		if test.userValues != nil {
+			reg[`CURRENT_USER\`+component] = test.userValues
+		}
+		opts := Options{}
+		opts.registry = reg
+
+		pending, known := activeSetupPending(opts, component, test.machineVersion)
+		if pending != test.want || !known {
+			t.Errorf("%s: got pending %v, known %v, want %v, true", test.name, pending, known, test.want)
+		}
+	}
+
+	// The hive of the current user is not loaded from offline images.
+	opts := Options{}
+	opts.registry = offlineRegistry{hives: fakeRegistry{}}
+	if _, known := activeSetupPending(opts, component, "1"); known {
+		t.Error("the state of a component without the user's hive is known")
+	}
+}
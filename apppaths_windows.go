@@ -0,0 +1,89 @@
+//+build windows
+
+package autoruns
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// This function enumerates App Paths entries, which map the name of an
+// executable to the file launched when a program is started by name, e.g.
+// through the Run dialog. Only the entries worth a look are reported: those
+// whose target is outside the Windows and Program Files directories, which
+// are flagged as NonDefault, and those whose target has a different name
+// than the entry or is in a directory writable by non-administrators, which
+// are flagged as Suspicious.
+func windowsGetAppPaths(opts Options) (records []*Autorun) {
+	regs := []registry.Key{
+		registry.LOCAL_MACHINE,
+		registry.CURRENT_USER,
+	}
+
+	keyNames := []string{
+		"Software\\Microsoft\\Windows\\CurrentVersion\\App Paths",
+		"Software\\Wow6432Node\\Microsoft\\Windows\\CurrentVersion\\App Paths",
+	}
+
+	// We loop through HKLM and HKCU.
+	for _, reg := range regs {
+		for _, keyName := range keyNames {
+			// Open the registry key.
+			key, err := openKey(opts, reg, keyName)
+			if err != nil {
+				continue
+			}
+
+			// Each entry is a subkey named after the executable.
+			names, err := key.ReadSubKeyNames(0)
+			key.Close()
+			if err != nil {
+				continue
+			}
+
+			for _, name := range names {
+				if opts.Context().Err() != nil {
+					return
+				}
+
+				subkeyPath := fmt.Sprintf("%s\\%s", keyName, name)
+				subkey, err := openKey(opts, reg, subkeyPath)
+				if err != nil {
+					continue
+				}
+
+				// The target is the default value of the key.
+				target, _, err := subkey.GetStringValue("")
+				subkey.Close()
+				if err != nil || target == "" {
+					continue
+				}
+
+				imageLocation := fmt.Sprintf("%s\\%s", registryToString(reg), subkeyPath)
+
+				// We pass the target to a function to return an Autorun.
+				newAutorun := stringToAutorun(opts, "app_paths", imageLocation, target, true, name)
+				newAutorun.User = keyUser(opts, reg)
+
+				imagePath := strings.Trim(newAutorun.ImagePath, "\"")
+				newAutorun.NonDefault = !standardLocation(imagePath)
+				newAutorun.Suspicious = !strings.EqualFold(filepath.Base(imagePath), name)
+				if !opts.QuickScan && !newAutorun.Suspicious && imagePath != "" {
+					newAutorun.Suspicious = imageDirWritable(imagePath)
+				}
+
+				if !newAutorun.NonDefault && !newAutorun.Suspicious {
+					continue
+				}
+
+				// Add the new autorun to the records.
+				records = append(records, newAutorun)
+			}
+		}
+	}
+
+	return
+}
@@ -0,0 +1,64 @@
+//+build !windows
+
+package autoruns
+
+import "strings"
+
+// splitArguments splits the arguments of a command line the way a POSIX
+// shell does, without expanding anything. Single quotes preserve
+// everything up to the next single quote, and backslashes escape the next
+// character, within double quotes only if it is special there.
+func splitArguments(arguments string) (argv []string) {
+	var current strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+
+	for _, r := range arguments {
+		switch {
+		case escaped:
+			if quote == '"' && !strings.ContainsRune("$`\"\\\n", r) {
+				current.WriteRune('\\')
+			}
+			if r != '\n' {
+				current.WriteRune(r)
+			}
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\\':
+			escaped = true
+			inWord = true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				argv = append(argv, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteRune(r)
+			inWord = true
+		}
+	}
+	if escaped {
+		current.WriteRune('\\')
+	}
+	if inWord {
+		argv = append(argv, current.String())
+	}
+
+	return
+}
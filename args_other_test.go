@@ -0,0 +1,66 @@
+//+build !windows
+
+package autoruns
+
+import (
+	"bytes"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitArgumentsShell(t *testing.T) {
+	tests := []struct {
+		arguments string
+		want      []string
+	}{
+		{`-a  -b`, []string{"-a", "-b"}},
+		{`'single quoted' "double quoted"`, []string{"single quoted", "double quoted"}},
+		{`'it''s' "say \"hi\""`, []string{"its", `say "hi"`}},
+		{`'back\slash' "back\slash" back\slash`, []string{`back\slash`, `back\slash`, "backslash"}},
+		{`"a\\b" a\\b 'a\\b'`, []string{`a\b`, `a\b`, `a\\b`}},
+		{`one\ word "" ''`, []string{"one word", "", ""}},
+		{`pre"mid dle"post`, []string{"premid dlepost"}},
+		{"line\\\ncontinued \"quoted\\\nline\"", []string{"linecontinued", "quotedline"}},
+	}
+	for _, test := range tests {
+		if got := splitArguments(test.arguments); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("splitArguments(%q) = %q, want %q", test.arguments, got, test.want)
+		}
+	}
+
+	// None of the arguments above are expanded by a shell, which has to
+	// split them the same way.
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no shell to compare with")
+	}
+	for _, test := range tests {
+		output, err := exec.Command(sh, "-c", `printf '%s\0' `+test.arguments).Output()
+		if err != nil {
+			t.Errorf("sh failed on %q: %v", test.arguments, err)
+			continue
+		}
+		got := strings.Split(string(bytes.TrimSuffix(output, []byte{0})), "\x00")
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("sh split %q into %q, want %q", test.arguments, got, test.want)
+		}
+	}
+}
+
+func TestSplitArgumentsEdgeCases(t *testing.T) {
+	// A shell would end the command at the newline.
+	if got := splitArguments("\t-x\n-y "); !reflect.DeepEqual(got, []string{"-x", "-y"}) {
+		t.Errorf("got %q", got)
+	}
+	if got := splitArguments(`-a "unterminated quote`); !reflect.DeepEqual(got, []string{"-a", "unterminated quote"}) {
+		t.Errorf("got %q", got)
+	}
+	if got := splitArguments(`-a trailing\`); !reflect.DeepEqual(got, []string{"-a", `trailing\`}) {
+		t.Errorf("got %q", got)
+	}
+	if got := splitArguments(" \t"); got != nil {
+		t.Errorf("splitArguments of blanks = %q, want nil", got)
+	}
+}
@@ -0,0 +1,28 @@
+package autoruns
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRunScannerSplitArguments(t *testing.T) {
+	s := scanner{name: "test", fn: func(opts Options) []*Autorun {
+		return []*Autorun{
+			{Entry: "split", Arguments: `-a "b c"`},
+			{Entry: "given", Arguments: "ignored", ArgumentsList: []string{"kept"}},
+		}
+	}}
+
+	records := runScanner(s, Options{QuickScan: true, SplitArguments: true})
+	if got := records[0].ArgumentsList; !reflect.DeepEqual(got, []string{"-a", "b c"}) {
+		t.Errorf("ArgumentsList = %q", got)
+	}
+	if got := records[1].ArgumentsList; !reflect.DeepEqual(got, []string{"kept"}) {
+		t.Errorf("ArgumentsList set by the scanner = %q", got)
+	}
+
+	records = runScanner(s, Options{QuickScan: true})
+	if records[0].ArgumentsList != nil {
+		t.Errorf("ArgumentsList = %q without SplitArguments", records[0].ArgumentsList)
+	}
+}
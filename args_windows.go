@@ -0,0 +1,19 @@
+//+build windows
+
+package autoruns
+
+import "golang.org/x/sys/windows"
+
+// splitArguments splits the arguments of a command line the way
+// CommandLineToArgvW does, which most programs use to parse their command
+// line.
+func splitArguments(arguments string) []string {
+	// The first token is parsed as the program name, which follows rules of
+	// its own.
+	argv, err := windows.DecomposeCommandLine("autoruns " + arguments)
+	if err != nil || len(argv) <= 1 {
+		return nil
+	}
+
+	return argv[1:]
+}
@@ -0,0 +1,37 @@
+//+build windows
+
+package autoruns
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitArgumentsWindows(t *testing.T) {
+	// The examples of the documentation of how C++ programs parse their
+	// command line, which CommandLineToArgvW follows, and forms found in
+	// launch strings.
+	tests := []struct {
+		arguments string
+		want      []string
+	}{
+		{`"a b c" d e`, []string{"a b c", "d", "e"}},
+		{`"ab\"c" "\\" d`, []string{`ab"c`, `\`, "d"}},
+		{`a\\\b d"e f"g h`, []string{`a\\\b`, "de fg", "h"}},
+		{`a\\\"b c d`, []string{`a\"b`, "c", "d"}},
+		{`a\\\\"b c" d e`, []string{`a\\b c`, "d", "e"}},
+		{`/s  /k "C:\Program Files\App\app.exe" ""`, []string{"/s", "/k", `C:\Program Files\App\app.exe`, ""}},
+		{`shell32.dll,Control_RunDLL "C:\dir\"`, []string{"shell32.dll,Control_RunDLL", `C:\dir"`}},
+		{"\t-a\t-b ", []string{"-a", "-b"}},
+		{`"unclosed quote`, []string{"unclosed quote"}},
+	}
+	for _, test := range tests {
+		if got := splitArguments(test.arguments); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("splitArguments(%q) = %q, want %q", test.arguments, got, test.want)
+		}
+	}
+
+	if got := splitArguments(""); got != nil {
+		t.Errorf("splitArguments of no arguments = %q, want nil", got)
+	}
+}
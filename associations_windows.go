@@ -0,0 +1,229 @@
+//+build windows
+
+package autoruns
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// The extensions and protocols whose handlers are checked when
+// Options.FileAssociations is not set.
+var defaultFileAssociations = []string{
+	".exe", ".com", ".bat", ".cmd", ".scr", ".pif", ".hta", ".vbs", ".js",
+	".ps1", ".txt", ".lnk", "ms-settings", "exefile",
+}
+
+// The handlers Windows registers by default, identified by the name of the
+// image they launch. "%1" means the file itself is executed, while an empty
+// name means there is no open command by default.
+var defaultAssociationHandlers = map[string]string{
+	".exe":        "%1",
+	".com":        "%1",
+	".bat":        "%1",
+	".cmd":        "%1",
+	".scr":        "%1",
+	".pif":        "%1",
+	"exefile":     "%1",
+	".hta":        "mshta.exe",
+	".vbs":        "wscript.exe",
+	".js":         "wscript.exe",
+	".ps1":        "notepad.exe",
+	".txt":        "notepad.exe",
+	".lnk":        "",
+	"ms-settings": "",
+}
+
+// commandImageName returns the lowercased file name of the image a command
+// line launches, without resolving it.
+func commandImageName(command string) string {
+	command = strings.TrimSpace(command)
+	if strings.HasPrefix(command, "\"") {
+		if closingQuote := strings.Index(command[1:], "\""); closingQuote >= 0 {
+			command = command[1 : closingQuote+1]
+		}
+	} else if space := strings.IndexAny(command, " \t"); space >= 0 {
+		command = command[:space]
+	}
+	if expanded, err := registry.ExpandString(command); err == nil {
+		command = expanded
+	}
+
+	return strings.ToLower(filepath.Base(command))
+}
+
+// readOpenCommand reads the open command of a class.
+func readOpenCommand(opts Options, reg registry.Key, classKey string) (command string, location string, ok bool) {
+	return readVerbCommand(opts, reg, classKey, "open")
+}
+
+// readVerbCommand reads the command of a verb of a class.
+func readVerbCommand(opts Options, reg registry.Key, classKey string, verb string) (command string, location string, ok bool) {
+	keyName := fmt.Sprintf("%s\\shell\\%s\\command", classKey, verb)
+	key, err := registryFor(opts).OpenKey(reg, keyName)
+	if err != nil {
+		return "", "", false
+	}
+
+	command, _, err = key.GetStringValue("")
+	key.Close()
+	if err != nil || command == "" {
+		return "", "", false
+	}
+
+	return command, fmt.Sprintf("%s\\%s", registryToString(reg), keyName), true
+}
+
+// This function reports the open commands of high-risk file types and
+// protocols which differ from the handlers Windows registers by default.
+func windowsGetFileAssociations(opts Options) (records []*Autorun) {
+	associations := opts.FileAssociations
+	if len(associations) == 0 {
+		associations = defaultFileAssociations
+	}
+
+	// The same command key can be reached through different paths.
+	seen := make(map[string]bool)
+
+	for _, association := range associations {
+		type classKey struct {
+			reg  registry.Key
+			path string
+		}
+
+		// The class can define a command itself, e.g. to hijack
+		// ms-settings for a UAC bypass, in either hive.
+		candidates := []classKey{
+			{registry.LOCAL_MACHINE, "Software\\Classes\\" + association},
+			{registry.CURRENT_USER, "Software\\Classes\\" + association},
+		}
+
+		// Otherwise, the command is the one of the ProgID it maps to.
+		if key, err := registryFor(opts).OpenKey(registry.CLASSES_ROOT, association); err == nil {
+			progID, _, err := key.GetStringValue("")
+			key.Close()
+			if err == nil && progID != "" {
+				candidates = append(candidates, classKey{registry.CLASSES_ROOT, progID})
+			}
+		}
+
+		for _, candidate := range candidates {
+			command, location, ok := readOpenCommand(opts, candidate.reg, candidate.path)
+			if !ok || seen[strings.ToLower(location)] {
+				continue
+			}
+			seen[strings.ToLower(location)] = true
+
+			expected, known := defaultAssociationHandlers[strings.ToLower(association)]
+			if known && expected != "" && commandImageName(command) == expected {
+				continue
+			}
+
+			// We pass the command to a function to return an Autorun.
+			newAutorun := stringToAutorun(opts, "file_association", location, command, true, association)
+			newAutorun.NonDefault = true
+			newAutorun.User = keyUser(opts, candidate.reg)
+
+			// Add the new autorun to the records.
+			records = append(records, newAutorun)
+		}
+	}
+
+	return
+}
+
+// Script hosts and other LOLBins which should not normally handle protocols.
+var scriptingEngines = map[string]bool{
+	"cmd.exe":        true,
+	"cscript.exe":    true,
+	"mshta.exe":      true,
+	"powershell.exe": true,
+	"pwsh.exe":       true,
+	"regsvr32.exe":   true,
+	"rundll32.exe":   true,
+	"wscript.exe":    true,
+}
+
+// standardLocation checks whether path is inside the Windows or Program
+// Files directories.
+func standardLocation(path string) bool {
+	path = strings.ToLower(path)
+	for _, variable := range []string{"SystemRoot", "ProgramFiles", "ProgramFiles(x86)", "ProgramW6432"} {
+		dir := strings.ToLower(os.Getenv(variable))
+		if dir != "" && strings.HasPrefix(path, dir+"\\") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// This function enumerates URL protocol handlers, which are classes marked
+// with a "URL Protocol" value.
+func windowsGetProtocolHandlers(opts Options) (records []*Autorun) {
+	regs := []registry.Key{
+		registry.LOCAL_MACHINE,
+		registry.CURRENT_USER,
+	}
+
+	var classesKey string = "Software\\Classes"
+
+	// We loop through HKLM and HKCU.
+	for _, reg := range regs {
+		// Open registry key.
+		key, err := openKey(opts, reg, classesKey)
+		if err != nil {
+			continue
+		}
+
+		// Enumerate subkeys.
+		names, err := key.ReadSubKeyNames(0)
+		key.Close()
+		if err != nil {
+			continue
+		}
+
+		for _, name := range names {
+			if opts.Context().Err() != nil {
+				return
+			}
+			// Extensions are never protocols.
+			if strings.HasPrefix(name, ".") {
+				continue
+			}
+
+			classKey := fmt.Sprintf("%s\\%s", classesKey, name)
+			subkey, err := registryFor(opts).OpenKey(reg, classKey)
+			if err != nil {
+				continue
+			}
+			_, _, err = subkey.GetStringValue("URL Protocol")
+			subkey.Close()
+			if err != nil {
+				continue
+			}
+
+			command, location, ok := readOpenCommand(opts, reg, classKey)
+			if !ok {
+				continue
+			}
+
+			// We pass the command to a function to return an Autorun.
+			newAutorun := stringToAutorun(opts, "protocol_handler", location, command, true, name)
+			newAutorun.User = keyUser(opts, reg)
+			if !opts.QuickScan {
+				newAutorun.Suspicious = scriptingEngines[strings.ToLower(newAutorun.ImageName)] ||
+					!standardLocation(newAutorun.ImagePath)
+			}
+
+			// Add the new autorun to the records.
+			records = append(records, newAutorun)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,145 @@
+//+build linux
+
+package autoruns
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// The spools of at, which differ between distributions.
+var atSpoolFolders = []string{
+	"/var/spool/cron/atjobs",
+	"/var/spool/at",
+}
+
+// Matches the header at writes at the top of every job with the user it
+// runs as.
+var atJobOwner = regexp.MustCompile(`^#\s*atrun uid=(\d+)`)
+
+// Matches the line of newer versions of at which feed the commands to the
+// shell of the user as a here document.
+var atJobHereDocument = regexp.MustCompile(`<<\s*'([^']+)'\s*$`)
+
+// parseAtJob returns the uid a job runs as and the commands it runs. The
+// commands follow the preamble at writes, which restores the environment
+// and the working directory of the user and ends with the block leaving if
+// the directory is gone.
+func parseAtJob(data []byte) (uid string, commands []string) {
+	var inPreamble = true
+	var sawChdir bool
+	var delimiter string
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if match := atJobOwner.FindStringSubmatch(line); match != nil {
+			uid = match[1]
+			continue
+		}
+
+		if inPreamble {
+			trimmed := strings.TrimSpace(line)
+			if strings.HasPrefix(trimmed, "cd ") {
+				sawChdir = true
+			} else if sawChdir && trimmed == "}" {
+				inPreamble = false
+			}
+			continue
+		}
+
+		if delimiter != "" && line == delimiter {
+			break
+		}
+		if delimiter == "" && len(commands) == 0 {
+			if match := atJobHereDocument.FindStringSubmatch(line); match != nil {
+				delimiter = match[1]
+				continue
+			}
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		commands = append(commands, line)
+	}
+
+	return
+}
+
+// passwdUsers returns the names of the users in /etc/passwd by uid.
+func passwdUsers(opts Options) map[string]string {
+	users := make(map[string]string)
+	data, err := readFile(fileSystemFor(opts), "/etc/passwd")
+	if err != nil {
+		return users
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) >= 3 {
+			users[fields[2]] = fields[0]
+		}
+	}
+
+	return users
+}
+
+// This function enumerates the jobs waiting in the spool of at. Each job
+// is a shell script running the commands it was given as the user who
+// queued it, and is reported with the first of them as the image.
+func linuxGetAtJobs(opts Options) (records []*Autorun) {
+	var users map[string]string
+	if opts.ResolveUsers {
+		users = passwdUsers(opts)
+	}
+
+	for _, folder := range atSpoolFolders {
+		jobs, err := fileSystemFor(opts).ReadDir(folder)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				opts.Warn(folder, err)
+			}
+			continue
+		}
+
+		for _, job := range jobs {
+			// The spool also holds the .SEQ counter.
+			if job.IsDir() || job.Name()[0] == '.' {
+				continue
+			}
+
+			jobPath := filepath.Join(folder, job.Name())
+			data, err := readFile(fileSystemFor(opts), jobPath)
+			if err != nil {
+				opts.Warn(jobPath, err)
+				continue
+			}
+
+			uid, commands := parseAtJob(data)
+			if len(commands) == 0 {
+				continue
+			}
+
+			newAutorun := shellCommandToAutorun(opts, "at_job", folder, commands[0], job.Name())
+			newAutorun.RawName = job.Name()
+			newAutorun.LaunchString = strings.Join(commands, "; ")
+			if uid != "" && uid != "0" {
+				newAutorun.Scope = "user"
+			}
+			if opts.ResolveUsers {
+				newAutorun.User = users[uid]
+			}
+
+			records = append(records, newAutorun)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,92 @@
+//+build windows
+
+package autoruns
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// isMicrosoftAutoplayHandler checks whether an AutoPlay handler is one of
+// those shipped with Windows. Their names start with "MS" and their
+// provider is a string resource of a system DLL.
+func isMicrosoftAutoplayHandler(name string, provider string) bool {
+	return strings.HasPrefix(name, "MS") && (provider == "" || strings.HasPrefix(strings.ToLower(provider), "@%systemroot%\\"))
+}
+
+// This function enumerates AutoPlay handlers, which are offered or run when
+// media is inserted. A handler either invokes a verb of a ProgID or starts
+// a COM drop target. Handlers which do not come with Windows are flagged as
+// NonDefault.
+func windowsGetAutoplayHandlers(opts Options) (records []*Autorun) {
+	var handlersKey string = "Software\\Microsoft\\Windows\\CurrentVersion\\Explorer\\AutoplayHandlers\\Handlers"
+
+	regs := []registry.Key{
+		registry.LOCAL_MACHINE,
+		registry.CURRENT_USER,
+	}
+
+	for _, reg := range regs {
+		// Open the registry key.
+		key, err := openKey(opts, reg, handlersKey)
+		if err != nil {
+			continue
+		}
+
+		// Each handler is a subkey.
+		names, err := key.ReadSubKeyNames(0)
+		key.Close()
+		if err != nil {
+			continue
+		}
+
+		for _, name := range names {
+			subkeyPath := fmt.Sprintf("%s\\%s", handlersKey, name)
+			subkey, err := openKey(opts, reg, subkeyPath)
+			if err != nil {
+				continue
+			}
+
+			provider, _, _ := subkey.GetStringValue("Provider")
+			progID, _, _ := subkey.GetStringValue("InvokeProgID")
+			verb, _, _ := subkey.GetStringValue("InvokeVerb")
+			clsid, _, _ := subkey.GetStringValue("CLSID")
+			subkey.Close()
+
+			imageLocation := fmt.Sprintf("%s\\%s", registryToString(reg), subkeyPath)
+
+			// Find what is launched by the handler.
+			var newAutorun *Autorun
+			if progID != "" {
+				if verb == "" {
+					verb = "open"
+				}
+				command, _, ok := readVerbCommand(opts, registry.CLASSES_ROOT, progID, verb)
+				if !ok {
+					continue
+				}
+				newAutorun = stringToAutorun(opts, "autoplay_handler", imageLocation, command, true, name)
+				newAutorun.RawName = "InvokeProgID"
+			} else if clsid != "" {
+				server, _, err := clsidServer(opts, clsid)
+				if err != nil {
+					continue
+				}
+				newAutorun = stringToAutorun(opts, "autoplay_handler", imageLocation, server, true, name)
+				newAutorun.RawName = "CLSID"
+			} else {
+				continue
+			}
+
+			newAutorun.NonDefault = !isMicrosoftAutoplayHandler(name, provider)
+			newAutorun.User = keyUser(opts, reg)
+
+			// Add the new autorun to the records.
+			records = append(records, newAutorun)
+		}
+	}
+
+	return
+}
@@ -1,18 +1,813 @@
 package autoruns
 
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
 type Autorun struct {
-	Type         string `json:"type"`
-	Location     string `json:"location"`
-	ImagePath    string `json:"image_path"`
-	ImageName    string `json:"image_name"`
-	Arguments    string `json:"arguments"`
-	MD5          string `json:"md5"`
-	SHA1         string `json:"sha1"`
-	SHA256       string `json:"sha256"`
-	Entry        string `json:"entry"`
-	LaunchString string `json:"launch_string"`
+	Type                 string     `json:"type"`
+	Location             string     `json:"location"`
+	Source               Source     `json:"source"`
+	LastModified         time.Time  `json:"last_modified"`
+	ImagePath            string     `json:"image_path"`
+	ImageName            string     `json:"image_name"`
+	Arguments            string     `json:"arguments"`
+	ArgumentsList        []string   `json:"arguments_list"`
+	WorkingDirectory     string     `json:"working_directory"`
+	MD5                  string     `json:"md5"`
+	SHA1                 string     `json:"sha1"`
+	SHA256               string     `json:"sha256"`
+	Entropy              float64    `json:"entropy"`
+	Entry                string     `json:"entry"`
+	RawName              string     `json:"raw_name"`
+	LaunchString         string     `json:"launch_string"`
+	Parsed               bool       `json:"parsed"`
+	Trigger              string     `json:"trigger"`
+	StartMode            string     `json:"start_mode"`
+	LoadPhase            string     `json:"load_phase"`
+	Version              string     `json:"version"`
+	CompileTime          time.Time  `json:"compile_time"`
+	SideloadRisk         bool       `json:"sideload_risk"`
+	Masquerade           bool       `json:"masquerade"`
+	FileMissing          bool       `json:"file_missing"`
+	MediaType            string     `json:"media_type"`
+	Signed               bool       `json:"signed"`
+	Signature            *Signature `json:"signature"`
+	NonDefault           bool       `json:"non_default"`
+	Suspicious           bool       `json:"suspicious"`
+	Hidden               bool       `json:"hidden"`
+	User                 string     `json:"user"`
+	Scope                string     `json:"scope"`
+	ExcludedFromDefender bool       `json:"excluded_from_defender"`
+	Suspicion            int        `json:"suspicion"`
+	SuspicionReasons     []string   `json:"suspicion_reasons"`
+	Flags                []string   `json:"flags"`
+
+	// remediable is set on the records of scans with AllowRemediation.
+	remediable bool
+	// nearDefenderTamper is set on the records modified around the time
+	// Defender was tampered with.
+	nearDefenderTamper bool
+	// unparsed is set on the records whose launch string could not be
+	// resolved to an executable.
+	unparsed bool
+	// unquotedPath is set on the records whose launch string has an
+	// unquoted executable containing spaces.
+	unquotedPath bool
+}
+
+// ID returns a stable identifier of the record, derived from where it was
+// found and what it launches, so that the same autorun can be matched
+// across scans even if the image itself changed.
+func (a *Autorun) ID() string {
+	hash := sha1.New()
+	for _, field := range []string{a.Type, a.Location, a.Entry, a.LaunchString} {
+		io.WriteString(hash, field)
+		hash.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Options controls how a scan is performed. The zero value runs every
+// registered scanner.
+type Options struct {
+	// QuickScan only inventories the persistence locations without
+	// touching the file system for the images they reference. Records are
+	// guaranteed to have Type, Location, Entry and LaunchString populated,
+	// and ImagePath holds the value as found, without being resolved.
+	// Nothing else is guaranteed: no hashing or any other analysis of the
+	// images is performed.
+	QuickScan bool
+
+	// FileAssociations lists the file extensions (e.g. ".exe") and
+	// protocols (e.g. "ms-settings") whose open command is checked for
+	// hijacks on Windows. It defaults to a set of high-risk extensions and
+	// protocols.
+	FileAssociations []string
+
+	// HashBufferSize is the size of the buffer images are read with while
+	// hashing them. It defaults to 1 MiB; larger values can improve
+	// throughput on slow or network-mounted storage.
+	HashBufferSize int
+
+	// MaxInFlightHashes is how many images of a category are hashed and
+	// analyzed at the same time. Every image in flight holds a buffer of
+	// HashBufferSize, so together they bound the memory used for hashing.
+	// It defaults to 1, hashing one image after the other.
+	MaxInFlightHashes int
+
+	// FileTimeout is how long the analysis of a single image, from
+	// checking that it exists to hashing it and verifying its signature,
+	// may take, e.g. on a disconnected network drive. The image is then
+	// left unhashed and ErrFileTimeout is reported in Result.Warnings.
+	// It defaults to 10s; a negative value disables the timeout.
+	FileTimeout time.Duration
+
+	// MaxEntriesPerCategory caps the number of records of a category, and
+	// of the subkeys the scanners of services and shell extensions read,
+	// so that a system crafted with millions of them cannot exhaust the
+	// scan. Enumeration stops at the cap, and ErrTruncated is reported in
+	// Result.Warnings. Zero means no cap.
+	MaxEntriesPerCategory int
+
+	// MaxDepth caps how deep scanners descend into nested folders, such as
+	// those of scheduled tasks or the Startup folders with
+	// RecurseStartupFolders, below the folder they start from. Deeper
+	// folders are skipped, and ErrTruncated is reported in
+	// Result.Warnings for them. Zero means no cap.
+	MaxDepth int
+
+	// AllowedHashes lists the SHA256 hashes of known-good images, given
+	// in hex in any case. Records whose image has one of them are left
+	// out of the results. As it relies on hashing, it has no effect on a
+	// QuickScan.
+	AllowedHashes map[string]bool
+
+	// ChangedSince makes the scan incremental, e.g. for frequent
+	// background scans: records found in a registry key or file which has
+	// not been modified since, whose image has not been modified since
+	// either, are not analyzed again. Their hashes and the results of the
+	// other analyses are instead carried forward from the record with the
+	// same ID in PreviousRecords, typically the results of the scan run at
+	// ChangedSince. Records without a previous record are analyzed as
+	// usual. This trades completeness for speed: an image replaced without
+	// changing its modification time is missed.
+	ChangedSince time.Time
+
+	// PreviousRecords are the records the analyses of ChangedSince are
+	// carried forward from.
+	PreviousRecords []*Autorun
+
+	// NetworkImages analyzes images on network shares, such as Group
+	// Policy scripts on the SYSVOL share of the domain. Without it, they
+	// are not accessed, so that a local scan does not make network
+	// requests, and only get MediaType "network". Images which are then
+	// unreachable are reported as FileMissing.
+	NetworkImages bool
+
+	// ComputeEntropy sets Entropy on every record to the Shannon entropy of
+	// its image in bits per byte, computed while hashing it. Values close
+	// to 8 indicate packed or encrypted payloads. Only the first 8 MiB of
+	// larger images are sampled. Entropy is left at zero for images which
+	// cannot be read. As it relies on hashing, it has no effect on a
+	// QuickScan.
+	ComputeEntropy bool
+
+	// RecurseStartupFolders also reports the files in the subfolders of
+	// the Startup folders on Windows, which the shell does not normally launch but
+	// can hide payloads. Their RawName is their path relative to the
+	// Startup folder.
+	RecurseStartupFolders bool
+
+	// SplitArguments sets ArgumentsList on every record to its Arguments
+	// split the way the system does: with the rules of CommandLineToArgvW
+	// on Windows, and of a shell elsewhere.
+	SplitArguments bool
+
+	// RecordTimings measures how long each category takes and reports it
+	// in Result.Timings.
+	RecordTimings bool
+
+	// Unsorted returns the records in the order the scanners found them,
+	// skipping the sort by Type, Location, Entry and ImagePath.
+	Unsorted bool
+
+	// RetryAttempts is how many times opening a registry key is retried
+	// after a transient failure, such as a sharing violation on a busy
+	// system. Missing keys and denied access are never retried. It
+	// defaults to 2; a negative value disables retries.
+	RetryAttempts int
+
+	// RetryBackoff is the delay before the first retry, which doubles with
+	// every further attempt. It defaults to 50ms.
+	RetryBackoff time.Duration
+
+	// ResolveUsers sets User on per-user records to the account they
+	// belong to. Machine-wide records are left without a user.
+	ResolveUsers bool
+
+	// ScanUserHives also scans the per-user locations of every other
+	// profile on Windows, including the built-in Administrator and the
+	// Default profile new users are created from, and of every other hive
+	// loaded under HKEY_USERS, such as those of service accounts. Hives of
+	// users who are not logged in are loaded for the duration of the scan,
+	// which requires administrative rights.
+	ScanUserHives bool
+
+	// UserScopeOnly restricts the scan to the locations of the current
+	// user, such as CURRENT_USER and the user's Startup folder, which can
+	// be read without administrative rights. Categories which only cover
+	// machine-wide locations are not run and are listed in Result.Skipped
+	// instead. See IsElevated and WithUserScopeUnlessElevated.
+	UserScopeOnly bool
+
+	// VerifySignatures checks the Authenticode signature of every image,
+	// including through the system catalogs, and sets Signed accordingly,
+	// and Signature to the certificate of the signer. It has no effect on
+	// other platforms than Windows.
+	VerifySignatures bool
+
+	// CheckSideloading reads the import table of every image and sets
+	// SideloadRisk on records whose image imports a commonly hijacked DLL
+	// that could be planted in its directory. This is expensive.
+	CheckSideloading bool
+
+	// ExecutionHistory also reports what users recently ran, such as the
+	// commands typed into the Run dialog on Windows, as records of type
+	// run_mru. They are not autoruns, but can tell how autoruns were
+	// installed. Like other context records, they are not counted as
+	// autoruns in the Summary.
+	ExecutionHistory bool
+
+	// ScoreSuspicion sets Suspicion on every record to a score from 0 to
+	// 100 of how much it is worth looking at, and SuspicionReasons to the
+	// signals it is made of. Each signal adds a fixed weight:
+	//
+	//   - hidden (40): the record is hidden from the tools of the system,
+	//     such as a scheduled task without a security descriptor.
+	//   - excluded_from_defender (30): the image is excluded from Defender.
+	//   - masquerade (30): see Masquerade.
+	//   - file_missing (25): the image does not exist.
+	//   - unsigned (25): the image is not signed. Requires VerifySignatures.
+	//   - suspicious (25): a scanner heuristic flagged the record.
+	//   - writable_directory (20): non-administrators can write to the
+	//     directory of the image.
+	//   - sideload_risk (15): see SideloadRisk. Requires CheckSideloading.
+	//   - non_default (15): the value differs from the system default.
+	//   - outside_system_directory (10): the image is not in a directory of
+	//     the operating system, such as System32.
+	//   - near_defender_tamper (10): the record was modified within a day of
+	//     a defender_tamper policy being set.
+	//
+	// The score is only as good as the analyses enabled along with it.
+	ScoreSuspicion bool
+
+	// RemoteHost scans another Windows machine, given by name or address,
+	// through its remote registry service instead of the local system.
+	// Only machine-wide locations are covered, as there is no current user
+	// on the remote machine. Files such as task definitions are read over the
+	// administrative shares, e.g. \\host\C$, and environment variables are
+	// expanded with the values of the local system. Images are only
+	// analyzed with RemoteFileAccess; otherwise the scan is a QuickScan.
+	RemoteHost string
+
+	// ImageRoot scans an offline Windows installation whose system drive
+	// is mounted at the given directory, such as a disk image, instead of
+	// the local system. Its SOFTWARE and SYSTEM hives are loaded for the
+	// duration of the scan, which requires administrative rights, and
+	// CurrentControlSet is read from the control set Select\Current points
+	// to. Like with RemoteHost, only machine-wide locations are covered,
+	// and paths are reported as they are on the image.
+	ImageRoot string
+
+	// RemoteFileAccess hashes and analyzes the images of a RemoteHost scan
+	// over the administrative shares, which requires administrative rights
+	// on the remote machine.
+	RemoteFileAccess bool
+
+	// AllowRemediation allows the records of the scan to be disabled or
+	// removed with Autorun.Disable and Autorun.Remove, which change the
+	// system. Records from other scans, including those decoded from JSON,
+	// can only be planned with Autorun.PlanDisable and Autorun.PlanRemove.
+	// The records of RemoteHost and ImageRoot scans cannot be remediated.
+	AllowRemediation bool
+
+	// Logger receives debug messages about the keys and files looked at
+	// and the entries skipped, to diagnose why an autorun is not reported.
+	// Nothing is logged if it is nil.
+	Logger Logger
+
+	ctx      context.Context
+	category string
+	state    *scanState
+	fs       fileSystem
+	platformOptions
+}
+
+// Context returns the context of the scan the options were passed to.
+// Scanners should stop early once it is done.
+func (o Options) Context() context.Context {
+	if o.ctx == nil {
+		return context.Background()
+	}
+	return o.ctx
+}
+
+// retryPolicy returns the retry settings with their defaults applied.
+func (o Options) retryPolicy() (retries int, backoff time.Duration) {
+	retries, backoff = o.RetryAttempts, o.RetryBackoff
+	if retries == 0 {
+		retries = 2
+	}
+	if backoff <= 0 {
+		backoff = 50 * time.Millisecond
+	}
+
+	return retries, backoff
+}
+
+// Result holds the outcome of a scan.
+type Result struct {
+	// Records are sorted by Type, then Location, Entry and ImagePath,
+	// unless Options.Unsorted is set.
+	Records []*Autorun `json:"records"`
+	// Warnings lists the locations which could not be read, each as a
+	// *ScanError.
+	Warnings []error `json:"-"`
+	Summary  Summary `json:"summary"`
+	// Skipped lists the categories which were not run because they are
+	// out of the scope of Options.UserScopeOnly.
+	Skipped []string `json:"skipped"`
+	// Timings holds how long each category took, including hashing and
+	// analysis of its records. It is only set with Options.RecordTimings.
+	Timings map[string]time.Duration `json:"timings,omitempty"`
+}
+
+// defaultFileTimeout is the FileTimeout used if none is set.
+const defaultFileTimeout = 10 * time.Second
+
+type scanner struct {
+	name string
+	fn   func(opts Options) []*Autorun
+	// machineOnly is set for scanners which only cover machine-wide
+	// locations.
+	machineOnly bool
+	// requiresAdmin is set for scanners whose locations can only be read
+	// with administrative rights.
+	requiresAdmin bool
+}
+
+var (
+	scannersMu sync.RWMutex
+	scanners   []scanner
+)
+
+// RegisterScanner adds fn to the set of scanners run by Scan and Autoruns.
+// The built-in scanners are registered through it as well, so custom
+// scanners receive the same Options, including the Context of the scan.
+// It is safe to call from init functions. RegisterScanner panics if fn is
+// nil or if a scanner with the same name is already registered.
+func RegisterScanner(name string, fn func(opts Options) []*Autorun) {
+	registerScanner(scanner{name: name, fn: fn})
+}
+
+// registerMachineScanner registers a built-in scanner which only covers
+// machine-wide locations, so that it is skipped with UserScopeOnly.
+func registerMachineScanner(name string, fn func(opts Options) []*Autorun) {
+	registerScanner(scanner{name: name, fn: fn, machineOnly: true})
+}
+
+// registerPrivilegedScanner registers a built-in scanner which only covers
+// machine-wide locations readable with administrative rights alone, such as
+// directories restricted to root.
+func registerPrivilegedScanner(name string, fn func(opts Options) []*Autorun) {
+	registerScanner(scanner{name: name, fn: fn, machineOnly: true, requiresAdmin: true})
+}
+
+func registerScanner(s scanner) {
+	if s.fn == nil {
+		panic("autoruns: RegisterScanner fn is nil")
+	}
+
+	scannersMu.Lock()
+	defer scannersMu.Unlock()
+
+	for _, registered := range scanners {
+		if registered.name == s.name {
+			panic("autoruns: RegisterScanner called twice for scanner " + s.name)
+		}
+	}
+	scanners = append(scanners, s)
+}
+
+// registeredScanners returns a snapshot of the registered scanners, in
+// registration order.
+func registeredScanners() []scanner {
+	scannersMu.RLock()
+	defer scannersMu.RUnlock()
+
+	return append([]scanner(nil), scanners...)
+}
+
+// runScanner invokes a scanner and enriches the records it returns.
+func runScanner(s scanner, opts Options) []*Autorun {
+	opts.category = s.name
+	opts.debugf("%s: scanning", s.name)
+	records := s.fn(opts)
+	opts.debugf("%s: found %d records", s.name, len(records))
+	// Scanners which do not stop at the cap themselves are cut short here.
+	if opts.MaxEntriesPerCategory > 0 && len(records) > opts.MaxEntriesPerCategory {
+		opts.Warn(records[opts.MaxEntriesPerCategory].Location, ErrTruncated)
+		records = records[:opts.MaxEntriesPerCategory]
+	}
+	for _, record := range records {
+		setSource(opts, record)
+		setScope(opts, record)
+		if record.LastModified.IsZero() {
+			record.LastModified = sourceModTime(opts, record.Source)
+		}
+		// A quick scan resolves nothing.
+		record.Parsed = !opts.QuickScan && !record.unparsed
+		if opts.SplitArguments && record.ArgumentsList == nil {
+			record.ArgumentsList = splitArguments(record.Arguments)
+		}
+	}
+	enrichAll(opts, records)
+	if opts.AllowRemediation {
+		for _, record := range records {
+			record.remediable = true
+		}
+	}
+
+	return dropAllowed(opts, records)
+}
+
+// enrichAll enriches records, with up to MaxInFlightHashes of them at a
+// time.
+func enrichAll(opts Options, records []*Autorun) {
+	if opts.MaxInFlightHashes <= 1 {
+		for _, record := range records {
+			enrich(opts, record)
+		}
+		return
+	}
+
+	inFlight := make(chan struct{}, opts.MaxInFlightHashes)
+	var wg sync.WaitGroup
+	for _, record := range records {
+		// Wait for a slot to free up.
+		inFlight <- struct{}{}
+		wg.Add(1)
+		go func(record *Autorun) {
+			defer func() {
+				<-inFlight
+				wg.Done()
+			}()
+			enrich(opts, record)
+		}(record)
+	}
+	wg.Wait()
+}
+
+// dropAllowed removes the records whose image has one of the
+// AllowedHashes.
+func dropAllowed(opts Options, records []*Autorun) []*Autorun {
+	if len(opts.AllowedHashes) == 0 {
+		return records
+	}
+
+	// The hashes are normalized once per scan.
+	allowed := opts.memo("allowed_hashes", func() interface{} {
+		allowed := make(map[string]bool, len(opts.AllowedHashes))
+		for hash, ok := range opts.AllowedHashes {
+			if ok {
+				allowed[strings.ToLower(hash)] = true
+			}
+		}
+		return allowed
+	}).(map[string]bool)
+
+	kept := records[:0]
+	for _, record := range records {
+		if record.SHA256 != "" && allowed[record.SHA256] {
+			opts.debugf("%s: dropping %s, whose hash is allowed", opts.category, record.ImagePath)
+			continue
+		}
+		kept = append(kept, record)
+	}
+
+	return kept
+}
+
+// This function just invokes all the registered scanners.
+func getAutoruns(opts Options, result *Result) error {
+	for _, s := range registeredScanners() {
+		if err := opts.Context().Err(); err != nil {
+			return err
+		}
+
+		if opts.UserScopeOnly && s.machineOnly {
+			opts.debugf("%s: skipping, as it only covers machine-wide locations", s.name)
+			result.Skipped = append(result.Skipped, s.name)
+			continue
+		}
+
+		if !opts.RecordTimings {
+			result.Records = append(result.Records, runScanner(s, opts)...)
+			continue
+		}
+
+		start := time.Now()
+		result.Records = append(result.Records, runScanner(s, opts)...)
+		if result.Timings == nil {
+			result.Timings = make(map[string]time.Duration)
+		}
+		result.Timings[s.name] = time.Since(start)
+	}
+
+	// Cross-reference the records with each other.
+	markDefenderExclusions(opts, result.Records)
+	markDefenderTamper(result.Records)
+	for _, record := range result.Records {
+		setFlags(opts, record)
+	}
+
+	if opts.ScoreSuspicion {
+		for _, record := range result.Records {
+			scoreSuspicion(opts, record)
+		}
+	}
+
+	return nil
+}
+
+// sortRecords sorts records by Type, Location, Entry and ImagePath, so
+// that scans of the same system return them in the same order. Records
+// equal in all of these keep their order.
+func sortRecords(records []*Autorun) {
+	sort.SliceStable(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		switch {
+		case a.Type != b.Type:
+			return a.Type < b.Type
+		case a.Location != b.Location:
+			return a.Location < b.Location
+		case a.Entry != b.Entry:
+			return a.Entry < b.Entry
+		default:
+			return a.ImagePath < b.ImagePath
+		}
+	})
+}
+
+// scansOtherSystem checks whether a scan reads another system than the
+// one it runs on, a remote machine or an offline image, whose paths do not
+// refer to local files.
+func scansOtherSystem(opts Options) bool {
+	return isRemote(opts) || opts.ImageRoot != ""
+}
+
+// isNetworkPath checks whether a path is on a network share, given by its
+// UNC path such as \\server\share\file. Device paths such as \\?\ are
+// local.
+func isNetworkPath(path string) bool {
+	return strings.HasPrefix(path, "\\\\") && !strings.HasPrefix(path, "\\\\?\\") && !strings.HasPrefix(path, "\\\\.\\")
+}
+
+// enrich hashes the image of a record and performs the optional analyses
+// selected in opts.
+func enrich(opts Options, autorun *Autorun) {
+	if opts.QuickScan || autorun.ImagePath == "" {
+		return
+	}
+	// What a launch string which could not be resolved points to is
+	// anyone's guess, so nothing is hashed for it.
+	if autorun.unparsed {
+		opts.debugf("%s: not analyzing %q, which was not resolved", opts.category, autorun.ImagePath)
+		return
+	}
+
+	autorun.Masquerade = masquerades(autorun.ImagePath)
+
+	// Images on network shares are only touched if requested.
+	if isNetworkPath(autorun.ImagePath) && !opts.NetworkImages {
+		opts.debugf("%s: not analyzing %s, which is on a network share", opts.category, autorun.ImagePath)
+		autorun.MediaType = "network"
+		return
+	}
+
+	// The analysis of an unchanged record is carried forward from the
+	// previous scan.
+	if previous := unchangedRecord(opts, autorun); previous != nil {
+		opts.debugf("%s: %s is unchanged since the previous scan", opts.category, autorun.ImagePath)
+		copyAnalysis(autorun, previous)
+		return
+	}
+
+	// The image is analyzed on a copy, which is abandoned if that takes too
+	// long.
+	var analysis *Autorun
+	if !withFileTimeout(opts, func() { analysis = analyzedImage(opts, autorun.ImagePath) }) {
+		opts.debugf("%s: timed out analyzing %s", opts.category, autorun.ImagePath)
+		opts.Warn(autorun.ImagePath, ErrFileTimeout)
+		return
+	}
+
+	copyAnalysis(autorun, analysis)
+}
+
+// withFileTimeout runs fn, giving up on waiting for it after the
+// FileTimeout of opts. It returns whether fn completed in time.
+func withFileTimeout(opts Options, fn func()) bool {
+	timeout := opts.FileTimeout
+	if timeout == 0 {
+		timeout = defaultFileTimeout
+	}
+	if timeout < 0 {
+		fn()
+		return true
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// analyzedImage returns the analysis of an image, which is only performed
+// once per scan no matter how many records reference the image, e.g. the
+// many services hosted by svchost.exe. Records waiting for an analysis
+// which takes too long time out just like the first one.
+func analyzedImage(opts Options, imagePath string) *Autorun {
+	return opts.memo("image:"+imagePath, func() interface{} {
+		analysis := &Autorun{ImagePath: imagePath}
+		analyzeImage(opts, analysis)
+		return analysis
+	}).(*Autorun)
+}
+
+// analyzeImage hashes the image of a record and performs the optional
+// analyses selected in opts.
+func analyzeImage(opts Options, autorun *Autorun) {
+	// The image has to be looked at the way the system sees it.
+	revert := disableFsRedirection()
+	defer revert()
+
+	// The type of media of the images of another system is not known.
+	if !scansOtherSystem(opts) {
+		autorun.MediaType = mediaType(autorun.ImagePath)
+	}
+	if _, err := fileSystemFor(opts).Stat(autorun.ImagePath); os.IsNotExist(err) {
+		opts.debugf("%s: image %s does not exist", opts.category, autorun.ImagePath)
+		autorun.FileMissing = true
+		return
+	}
+
+	var extra []io.Writer
+	var entropy *entropyCounter
+	if opts.ComputeEntropy {
+		entropy = &entropyCounter{limit: entropySampleSize}
+		extra = append(extra, entropy)
+	}
+	// The compile time of PE images is read from their headers as they are
+	// hashed.
+	headers := &peHeaderSampler{}
+	extra = append(extra, headers)
+
+	var err error
+	autorun.MD5, autorun.SHA1, autorun.SHA256, err = hashFile(fileSystemFor(opts), autorun.ImagePath, opts.HashBufferSize, extra...)
+	if err != nil {
+		opts.debugf("%s: could not hash %s: %v", opts.category, autorun.ImagePath, err)
+	} else {
+		if entropy != nil {
+			autorun.Entropy = entropy.value()
+		}
+		autorun.CompileTime = headers.compileTime()
+	}
+
+	if opts.VerifySignatures {
+		autorun.Signed, autorun.Signature = verifySignature(accessPath(opts, autorun.ImagePath))
+	}
+	if opts.CheckSideloading {
+		autorun.SideloadRisk = sideloadRisk(accessPath(opts, autorun.ImagePath))
+	}
+}
+
+// Scan runs all registered scanners with the given options. Locations which
+// could not be read are reported in the Warnings of the result. If ctx is
+// done before the scan completes, the records collected so far are returned
+// along with the context's error.
+func Scan(ctx context.Context, opts Options) (*Result, error) {
+	opts.ctx = ctx
+	opts.state = newScanState()
+	defer opts.state.finish()
+
+	result := &Result{}
+	if err := connectRemote(&opts); err != nil {
+		return result, err
+	}
+	if err := connectImage(&opts); err != nil {
+		return result, err
+	}
+	err := getAutoruns(opts, result)
+	if !opts.Unsorted {
+		sortRecords(result.Records)
+	}
+	result.Warnings = opts.state.warnings.list()
+	result.Summary = summarize(opts, result.Records, result.Warnings)
+
+	return result, err
+}
+
+// Categories returns the names of the registered scanners, in the order
+// they are run.
+func Categories() []string {
+	var names []string
+	for _, s := range registeredScanners() {
+		names = append(names, s.name)
+	}
+
+	return names
+}
+
+// CategoryInfo describes a registered scanner.
+type CategoryInfo struct {
+	// Name is the name of the scanner, as passed to ScanCategory.
+	Name string `json:"name"`
+	// Scope is "machine" for scanners which only cover machine-wide
+	// locations, and are skipped with Options.UserScopeOnly, and "user"
+	// for those which cover the locations of users as well.
+	Scope string `json:"scope"`
+	// RequiresAdmin is set for scanners which find nothing without
+	// administrative rights, see IsElevated. Scanning the hives of other
+	// users with Options.ScanUserHives requires them for every scanner
+	// with the user scope as well. Custom scanners never set it.
+	RequiresAdmin bool `json:"requires_admin"`
+}
+
+// CategoryDetails returns the registered scanners like Categories, along
+// with their scope and whether they require administrative rights, e.g.
+// to tell which categories an unprivileged scan misses.
+func CategoryDetails() []CategoryInfo {
+	var details []CategoryInfo
+	for _, s := range registeredScanners() {
+		info := CategoryInfo{Name: s.name, Scope: "user", RequiresAdmin: s.requiresAdmin}
+		if s.machineOnly {
+			info.Scope = "machine"
+		}
+		details = append(details, info)
+	}
+
+	return details
+}
+
+// ScanCategory runs only the scanner registered under name. It returns an
+// error if there is no such scanner, and no records if the scanner is out
+// of the scope of UserScopeOnly. The records are sorted like those of
+// Scan. They are not cross-referenced with those of other categories, so
+// their Flags lack the codes which depend on it, such as
+// EXCLUDED_FROM_DEFENDER.
+func ScanCategory(name string, opts Options) ([]*Autorun, error) {
+	for _, s := range registeredScanners() {
+		if s.name == name {
+			if opts.UserScopeOnly && s.machineOnly {
+				return nil, nil
+			}
+
+			opts.state = newScanState()
+			defer opts.state.finish()
+
+			if err := connectRemote(&opts); err != nil {
+				return nil, err
+			}
+			if err := connectImage(&opts); err != nil {
+				return nil, err
+			}
+
+			records := runScanner(s, opts)
+			for _, record := range records {
+				setFlags(opts, record)
+			}
+			if !opts.Unsorted {
+				sortRecords(records)
+			}
+			return records, nil
+		}
+	}
+
+	return nil, fmt.Errorf("autoruns: unknown category %q", name)
 }
 
 func Autoruns() []*Autorun {
-	return getAutoruns()
+	result, _ := Scan(context.Background(), Options{})
+	return result.Records
+}
+
+// AutorunsRemote scans the registry of another Windows machine, see
+// Options.RemoteHost. It returns an error if the registry of host cannot be
+// connected to, e.g. because it is unreachable or access is denied.
+func AutorunsRemote(host string) ([]*Autorun, error) {
+	result, err := Scan(context.Background(), Options{RemoteHost: host})
+	return result.Records, err
 }
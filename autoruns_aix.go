@@ -2,7 +2,4 @@
 
 package autoruns
 
-// This function just invokes all the platform-dependant functions.
-func getAutoruns() (records []*Autorun) {
-	return
-}
+// No scanners are registered on this platform yet, see RegisterScanner.
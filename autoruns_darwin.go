@@ -3,12 +3,10 @@
 package autoruns
 
 import (
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 
-	"github.com/botherder/go-files"
 	"howett.net/plist"
 )
 
@@ -18,16 +16,22 @@ type Plist struct {
 	RunAtLoad        bool     `plist:"RunAtLoad"`
 }
 
-func parsePlists(entryType string, folders []string) (records []*Autorun) {
+func parsePlists(opts Options, entryType string, folders []string) (records []*Autorun) {
 	for _, folder := range folders {
+		if opts.Context().Err() != nil {
+			return
+		}
+
 		// Check if the folders exists.
-		if _, err := os.Stat(folder); os.IsNotExist(err) {
+		if _, err := fileSystemFor(opts).Stat(folder); os.IsNotExist(err) {
+			opts.debugf("%s: skipping %s, which does not exist", opts.category, folder)
 			continue
 		}
 
 		// Get list of files in folder.
-		filesList, err := ioutil.ReadDir(folder)
+		filesList, err := fileSystemFor(opts).ReadDir(folder)
 		if err != nil {
+			opts.Warn(folder, err)
 			continue
 		}
 
@@ -35,8 +39,9 @@ func parsePlists(entryType string, folders []string) (records []*Autorun) {
 		for _, fileEntry := range filesList {
 			// Open the plist file.
 			filePath := filepath.Join(folder, fileEntry.Name())
-			reader, err := os.Open(filePath)
+			reader, err := fileSystemFor(opts).Open(filePath)
 			if err != nil {
+				opts.Warn(filePath, err)
 				continue
 			}
 
@@ -46,10 +51,12 @@ func parsePlists(entryType string, folders []string) (records []*Autorun) {
 			err = decoder.Decode(&p)
 			reader.Close()
 			if err != nil {
+				opts.Warn(filePath, err)
 				continue
 			}
 
 			if len(p.ProgramArguments) == 0 {
+				opts.debugf("%s: skipping %s, which has no ProgramArguments", opts.category, filePath)
 				continue
 			}
 
@@ -64,24 +71,23 @@ func parsePlists(entryType string, folders []string) (records []*Autorun) {
 				arguments = strings.Join(p.ProgramArguments[1:], " ")
 			}
 
-			md5, _ := files.HashFile(imagePath, "md5")
-			sha1, _ := files.HashFile(imagePath, "sha1")
-			sha256, _ := files.HashFile(imagePath, "sha256")
-
 			newAutorun := Autorun{
 				Type:         entryType,
 				Location:     filePath,
 				ImagePath:    imagePath,
 				ImageName:    filepath.Base(imagePath),
 				Arguments:    arguments,
-				MD5:          md5,
-				SHA1:         sha1,
-				SHA256:       sha256,
+				Entry:        p.Label,
+				RawName:      fileEntry.Name(),
 				LaunchString: imagePath,
 			}
 			if arguments != "" {
 				newAutorun.LaunchString += " " + arguments
 			}
+			// The arguments are already split.
+			if opts.SplitArguments {
+				newAutorun.ArgumentsList = append([]string{}, p.ProgramArguments[1:]...)
+			}
 
 			// Add new record to list.
 			records = append(records, &newAutorun)
@@ -91,32 +97,72 @@ func parsePlists(entryType string, folders []string) (records []*Autorun) {
 	return
 }
 
-// This function just invokes all the platform-dependant functions.
-func getAutoruns() (records []*Autorun) {
-	// Startup and run as root.
+func init() {
+	registerMachineScanner("launch_daemons", darwinGetLaunchDaemons)
+	registerMachineScanner("launch_agents", darwinGetLaunchAgents)
+	RegisterScanner("launch_agents_user", darwinGetLaunchAgentsUser)
+	registerMachineScanner("cron", darwinGetCron)
+	registerMachineScanner("periodic", darwinGetPeriodic)
+	registerPrivilegedScanner("at_jobs", darwinGetAtJobs)
+	registerMachineScanner("kexts", darwinGetKexts)
+	registerMachineScanner("system_extensions", darwinGetSystemExtensions)
+	registerPrivilegedScanner("config_profiles", darwinGetConfigProfiles)
+}
+
+// Startup and run as root.
+func darwinGetLaunchDaemons(opts Options) []*Autorun {
 	launchDaemons := []string{
 		"/Library/LaunchDaemons",
 		"/System/Library/LaunchDaemons",
 	}
-	// Launch when any user logs in.
+
+	return parsePlists(opts, "launch_daemons", launchDaemons)
+}
+
+// Launch when any user logs in.
+func darwinGetLaunchAgents(opts Options) []*Autorun {
 	launchAgents := []string{
 		"/Library/LaunchAgents",
 		"/System/Library/LaunchAgents",
 	}
-	// Launch when specific user logs in
-	launchAgentsUser := []string{}
-	if files, err := ioutil.ReadDir("/Users"); err == nil {
+
+	return parsePlists(opts, "launch_agents", launchAgents)
+}
+
+// Launch when specific user logs in
+func darwinGetLaunchAgentsUser(opts Options) (records []*Autorun) {
+	// Only our own home directory can be read without privileges.
+	if opts.UserScopeOnly {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return
+		}
+		records = parsePlists(opts, "launch_agents_user", []string{filepath.Join(home, "Library", "LaunchAgents")})
+		for _, record := range records {
+			record.Scope = "user"
+			if opts.ResolveUsers {
+				record.User = filepath.Base(home)
+			}
+		}
+		return
+	}
+
+	if files, err := fileSystemFor(opts).ReadDir("/Users"); err == nil {
 		for _, f := range files {
 			if f.IsDir() {
-				launchAgentsUser = append(launchAgentsUser, filepath.Join("/Users", f.Name(), "Library", "LaunchAgents"))
+				launchAgentsUser := []string{filepath.Join("/Users", f.Name(), "Library", "LaunchAgents")}
+				userRecords := parsePlists(opts, "launch_agents_user", launchAgentsUser)
+				for _, record := range userRecords {
+					record.Scope = "user"
+					if opts.ResolveUsers {
+						record.User = f.Name()
+					}
+				}
+				records = append(records, userRecords...)
 			}
 
 		}
 	}
 
-	records = append(records, parsePlists("launch_daemons", launchDaemons)...)
-	records = append(records, parsePlists("launch_agents", launchAgents)...)
-	records = append(records, parsePlists("launch_agents_user", launchAgentsUser)...)
-
 	return
 }
@@ -2,7 +2,39 @@
 
 package autoruns
 
-// This function just invokes all the platform-dependant functions.
-func getAutoruns() (records []*Autorun) {
+import (
+	"os"
+	"path/filepath"
+)
+
+func init() {
+	RegisterScanner("systemd", linuxGetSystemd)
+	registerMachineScanner("systemd_generators", linuxGetSystemdGenerators)
+	RegisterScanner("ld_preload", linuxGetLDPreload)
+	registerMachineScanner("pam_modules", linuxGetPAMModules)
+	registerMachineScanner("motd_scripts", linuxGetMotdScripts)
+	registerPrivilegedScanner("at_jobs", linuxGetAtJobs)
+	registerMachineScanner("tmpfiles", linuxGetTmpfiles)
+}
+
+// homeDirectories returns the home directories of root and of every user
+// under /home, or only the one of the current user with UserScopeOnly.
+func homeDirectories(opts Options) (homes []string) {
+	if opts.UserScopeOnly {
+		if home, err := os.UserHomeDir(); err == nil {
+			homes = append(homes, home)
+		}
+		return
+	}
+
+	homes = append(homes, "/root")
+	if files, err := fileSystemFor(opts).ReadDir("/home"); err == nil {
+		for _, f := range files {
+			if f.IsDir() {
+				homes = append(homes, filepath.Join("/home", f.Name()))
+			}
+		}
+	}
+
 	return
 }
@@ -0,0 +1,66 @@
+//+build linux
+
+package autoruns
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLinuxEntryAndRawName(t *testing.T) {
+	fsys := newFakeFileSystem(map[string]string{
+		"/var/spool/cron/atjobs/a0001":                                 "#!/bin/sh\n# atrun uid=1000 gid=1000\numask 22\ncd /home/alice || {\n\t echo 'Execution directory inaccessible' >&2\n\t exit 1\n}\n/usr/bin/later --once\n",
+		"/etc/systemd/system/multi-user.target.wants/backdoor.service": "",
+		"/etc/systemd/system/backdoor.service":                         "[Service]\nExecStartPre=/usr/bin/prepare\nExecStart=/opt/backdoor\n",
+	})
+	fsys.add("/etc/update-motd.d/00-header", "#!/bin/sh\n", 0755)
+	fsys.add("/usr/lib/systemd/system-generators/netplan", "", 0755)
+	opts := Options{fs: fsys, QuickScan: true}
+
+	tests := []struct {
+		name    string
+		scanner func(opts Options) []*Autorun
+		// The Type, Entry and RawName of each record.
+		want [][3]string
+	}{
+		{"at_jobs", linuxGetAtJobs, [][3]string{{"at_job", "a0001", "a0001"}}},
+		{"motd_scripts", linuxGetMotdScripts, [][3]string{{"motd_script", "00-header", "00-header"}}},
+		{"systemd_generators", linuxGetSystemdGenerators, [][3]string{{"systemd_generator", "netplan", "netplan"}}},
+		{"systemd", linuxGetSystemd, [][3]string{
+			{"systemd_service", "backdoor.service", "ExecStartPre"},
+			{"systemd_service", "backdoor.service", "ExecStart"},
+		}},
+	}
+	for _, test := range tests {
+		var got [][3]string
+		for _, record := range test.scanner(opts) {
+			got = append(got, [3]string{record.Type, record.Entry, record.RawName})
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got Type, Entry and RawName %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestLinuxCategoryDetails(t *testing.T) {
+	want := map[string]CategoryInfo{
+		"systemd":            {Name: "systemd", Scope: "user"},
+		"systemd_generators": {Name: "systemd_generators", Scope: "machine"},
+		"ld_preload":         {Name: "ld_preload", Scope: "user"},
+		"pam_modules":        {Name: "pam_modules", Scope: "machine"},
+		"motd_scripts":       {Name: "motd_scripts", Scope: "machine"},
+		// The spool of at is only readable by root.
+		"at_jobs":  {Name: "at_jobs", Scope: "machine", RequiresAdmin: true},
+		"tmpfiles": {Name: "tmpfiles", Scope: "machine"},
+	}
+
+	details := CategoryDetails()
+	for _, info := range details {
+		if info != want[info.Name] {
+			t.Errorf("got %+v, want %+v", info, want[info.Name])
+		}
+	}
+	if len(details) != len(want) {
+		t.Errorf("got %d categories, want %d", len(details), len(want))
+	}
+}
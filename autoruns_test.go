@@ -0,0 +1,399 @@
+package autoruns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestScanRecordTimings(t *testing.T) {
+	result, err := Scan(context.Background(), Options{QuickScan: true, RecordTimings: true})
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+
+	skipped := make(map[string]bool)
+	for _, name := range result.Skipped {
+		skipped[name] = true
+	}
+	for _, name := range Categories() {
+		if _, ok := result.Timings[name]; !ok && !skipped[name] {
+			t.Errorf("no timing for %s", name)
+		}
+	}
+
+	result, err = Scan(context.Background(), Options{QuickScan: true})
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if result.Timings != nil {
+		t.Errorf("Timings = %v without RecordTimings", result.Timings)
+	}
+}
+
+func TestAllowedHashesDropRecords(t *testing.T) {
+	bin := string(filepath.Separator) + "bin"
+	fsys := newFakeFileSystem(map[string]string{
+		filepath.Join(bin, "known"):   "abc",
+		filepath.Join(bin, "unknown"): "something else",
+	})
+	s := scanner{name: "test", fn: func(opts Options) []*Autorun {
+		return []*Autorun{
+			{Entry: "known", ImagePath: filepath.Join(bin, "known")},
+			{Entry: "unknown", ImagePath: filepath.Join(bin, "unknown")},
+			{Entry: "missing", ImagePath: filepath.Join(bin, "missing")},
+		}
+	}}
+
+	// The hashes are compared case-insensitively, and only those set to
+	// true are allowed.
+	opts := Options{fs: fsys, AllowedHashes: map[string]bool{
+		strings.ToUpper("ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"): true,
+		strings.Repeat("0", 64): false,
+	}}
+	var entries []string
+	for _, record := range runScanner(s, opts) {
+		entries = append(entries, record.Entry)
+	}
+	if !reflect.DeepEqual(entries, []string{"unknown", "missing"}) {
+		t.Errorf("got records %v, want unknown and missing", entries)
+	}
+
+	// Without hashes nothing is allowed.
+	opts.QuickScan = true
+	if records := runScanner(s, opts); len(records) != 3 {
+		t.Errorf("got %d records of a quick scan, want 3", len(records))
+	}
+}
+
+func TestCategoryDetails(t *testing.T) {
+	details := CategoryDetails()
+	names := Categories()
+	if len(details) != len(names) {
+		t.Fatalf("got %d details for %d categories", len(details), len(names))
+	}
+	for i, info := range details {
+		if info.Name != names[i] {
+			t.Errorf("detail %d is of %s, want %s", i, info.Name, names[i])
+		}
+		if info.Scope != "machine" && info.Scope != "user" {
+			t.Errorf("%s: Scope = %q", info.Name, info.Scope)
+		}
+		// What only administrators can read is not the user's own.
+		if info.RequiresAdmin && info.Scope != "machine" {
+			t.Errorf("%s requires administrative rights, but has the user scope", info.Name)
+		}
+	}
+}
+
+func TestRegisterScannerDeclarations(t *testing.T) {
+	scannersMu.Lock()
+	registered := scanners
+	scanners = nil
+	scannersMu.Unlock()
+	defer func() {
+		scannersMu.Lock()
+		scanners = registered
+		scannersMu.Unlock()
+	}()
+
+	none := func(opts Options) []*Autorun { return nil }
+	RegisterScanner("custom", none)
+	registerMachineScanner("machine", none)
+	registerPrivilegedScanner("privileged", none)
+
+	want := []CategoryInfo{
+		{Name: "custom", Scope: "user"},
+		{Name: "machine", Scope: "machine"},
+		{Name: "privileged", Scope: "machine", RequiresAdmin: true},
+	}
+	if got := CategoryDetails(); !reflect.DeepEqual(got, want) {
+		t.Errorf("CategoryDetails = %+v, want %+v", got, want)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a scanner twice did not panic")
+		}
+	}()
+	RegisterScanner("machine", none)
+}
+
+// recordsByEntry returns the records by their Entry.
+func recordsByEntry(records []*Autorun) map[string]*Autorun {
+	byEntry := make(map[string]*Autorun)
+	for _, record := range records {
+		byEntry[record.Entry] = record
+	}
+
+	return byEntry
+}
+
+// recordKeys returns the Type, Location, Entry and ImagePath of records.
+func recordKeys(records []*Autorun) (keys [][4]string) {
+	for _, record := range records {
+		keys = append(keys, [4]string{record.Type, record.Location, record.Entry, record.ImagePath})
+	}
+
+	return keys
+}
+
+func TestSortRecords(t *testing.T) {
+	fixture := func() []*Autorun {
+		return []*Autorun{
+			{Type: "services", Location: `SYSTEM\CurrentControlSet\Services`, Entry: "Updater"},
+			{Type: "run_key", Location: `CURRENT_USER\Run`, Entry: "Tray", ImagePath: `C:\b.exe`},
+			{Type: "run_key", Location: `CURRENT_USER\Run`, Entry: "Tray", ImagePath: `C:\a.exe`},
+			{Type: "run_key", Location: `LOCAL_MACHINE\Run`, Entry: "Agent"},
+			{Type: "run_key", Location: `CURRENT_USER\Run`, Entry: "Agent", Arguments: "first"},
+			{Type: "run_key", Location: `CURRENT_USER\Run`, Entry: "Agent", Arguments: "second"},
+		}
+	}
+
+	want := [][4]string{
+		{"run_key", `CURRENT_USER\Run`, "Agent", ""},
+		{"run_key", `CURRENT_USER\Run`, "Agent", ""},
+		{"run_key", `CURRENT_USER\Run`, "Tray", `C:\a.exe`},
+		{"run_key", `CURRENT_USER\Run`, "Tray", `C:\b.exe`},
+		{"run_key", `LOCAL_MACHINE\Run`, "Agent", ""},
+		{"services", `SYSTEM\CurrentControlSet\Services`, "Updater", ""},
+	}
+
+	// The order the scanners found the records in does not matter.
+	records := fixture()
+	sortRecords(records)
+	reversed := fixture()
+	for i, j := 0, len(reversed)-1; i < j; i, j = i+1, j-1 {
+		reversed[i], reversed[j] = reversed[j], reversed[i]
+	}
+	sortRecords(reversed)
+	if got := recordKeys(records); !reflect.DeepEqual(got, want) {
+		t.Errorf("sorted records = %v, want %v", got, want)
+	}
+	if got := recordKeys(reversed); !reflect.DeepEqual(got, want) {
+		t.Errorf("sorted reversed records = %v, want %v", got, want)
+	}
+
+	// Records equal in all keys keep their order.
+	if records[0].Arguments != "first" || records[1].Arguments != "second" {
+		t.Errorf("equal records were reordered: %q, %q", records[0].Arguments, records[1].Arguments)
+	}
+}
+
+func TestScanOrderIsStable(t *testing.T) {
+	first, err := Scan(context.Background(), Options{QuickScan: true})
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	second, err := Scan(context.Background(), Options{QuickScan: true})
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+
+	if !reflect.DeepEqual(recordKeys(first.Records), recordKeys(second.Records)) {
+		t.Errorf("two scans returned the records in different orders:\n%v\n%v", recordKeys(first.Records), recordKeys(second.Records))
+	}
+	sorted := append([]*Autorun(nil), first.Records...)
+	sortRecords(sorted)
+	if !reflect.DeepEqual(recordKeys(first.Records), recordKeys(sorted)) {
+		t.Error("the records of the scan are not sorted")
+	}
+}
+
+// sharedImageRecords returns n records referencing the given number of
+// images, along with a file system holding them.
+func sharedImageRecords(n int, images int) ([]*Autorun, *countingFileSystem) {
+	files := make(map[string]string)
+	var records []*Autorun
+	for i := 0; i < n; i++ {
+		imagePath := filepath.Join(string(filepath.Separator)+"bin", fmt.Sprintf("image%d", i%images))
+		files[imagePath] = fmt.Sprintf("contents of image %d", i%images)
+		records = append(records, &Autorun{Entry: fmt.Sprint(i), ImagePath: imagePath})
+	}
+
+	return records, &countingFileSystem{fileSystem: newFakeFileSystem(files)}
+}
+
+func TestEnrichAnalyzesImagesOnce(t *testing.T) {
+	records, fsys := sharedImageRecords(50, 3)
+	opts := Options{fs: fsys, MaxInFlightHashes: 8, state: newScanState()}
+	enrichAll(opts, records)
+
+	if fsys.opens != 3 {
+		t.Errorf("opened images %d times, want 3", fsys.opens)
+	}
+	for _, record := range records {
+		if want := records[0]; record.ImagePath == want.ImagePath && record.SHA256 != want.SHA256 {
+			t.Errorf("record %s: SHA256 %q, want %q", record.Entry, record.SHA256, want.SHA256)
+		}
+		if record.SHA256 == "" {
+			t.Errorf("record %s was not hashed", record.Entry)
+		}
+	}
+
+	// Every scan analyzes the images anew.
+	records, _ = sharedImageRecords(50, 3)
+	opts.state = newScanState()
+	enrichAll(opts, records)
+	if fsys.opens != 6 {
+		t.Errorf("opened images %d times over two scans, want 6", fsys.opens)
+	}
+}
+
+// inFlightFileSystem tracks how many files are open at the same time,
+// keeping each open for a while.
+type inFlightFileSystem struct {
+	fileSystem
+
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+}
+
+func (f *inFlightFileSystem) Open(name string) (fsFile, error) {
+	file, err := f.fileSystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	f.inFlight++
+	if f.inFlight > f.maxInFlight {
+		f.maxInFlight = f.inFlight
+	}
+	f.mu.Unlock()
+	time.Sleep(time.Millisecond)
+
+	return inFlightFile{file, f}, nil
+}
+
+type inFlightFile struct {
+	fsFile
+	fsys *inFlightFileSystem
+}
+
+func (f inFlightFile) Close() error {
+	f.fsys.mu.Lock()
+	f.fsys.inFlight--
+	f.fsys.mu.Unlock()
+
+	return f.fsFile.Close()
+}
+
+func TestMaxInFlightHashes(t *testing.T) {
+	for _, limit := range []int{1, 3} {
+		records, files := sharedImageRecords(40, 40)
+		fsys := &inFlightFileSystem{fileSystem: files}
+		enrichAll(Options{fs: fsys, MaxInFlightHashes: limit}, records)
+
+		if fsys.maxInFlight > limit {
+			t.Errorf("MaxInFlightHashes %d: hashed %d images at the same time", limit, fsys.maxInFlight)
+		}
+		for _, record := range records {
+			if record.SHA256 == "" {
+				t.Errorf("MaxInFlightHashes %d: record %s was not hashed", limit, record.Entry)
+			}
+		}
+	}
+}
+
+// hungFileSystem blocks opening the file at hung until release is closed,
+// like a disconnected network share.
+type hungFileSystem struct {
+	fileSystem
+	hung    string
+	release chan struct{}
+}
+
+func (f *hungFileSystem) Open(name string) (fsFile, error) {
+	if name == f.hung {
+		<-f.release
+	}
+
+	return f.fileSystem.Open(name)
+}
+
+func TestFileTimeout(t *testing.T) {
+	records, files := sharedImageRecords(2, 2)
+	fsys := &hungFileSystem{fileSystem: files, hung: records[0].ImagePath, release: make(chan struct{})}
+	defer close(fsys.release)
+
+	opts := Options{fs: fsys, FileTimeout: 20 * time.Millisecond, state: newScanState()}
+	start := time.Now()
+	enrichAll(opts, records)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("enriching took %v", elapsed)
+	}
+
+	if records[0].SHA256 != "" {
+		t.Error("the hung image was hashed")
+	}
+	if records[1].SHA256 == "" {
+		t.Error("the image after the hung one was not hashed")
+	}
+
+	var timedOut bool
+	for _, warning := range opts.state.warnings.list() {
+		var scanErr *ScanError
+		if errors.As(warning, &scanErr) && scanErr.Location == records[0].ImagePath && errors.Is(warning, ErrFileTimeout) {
+			timedOut = true
+		}
+	}
+	if !timedOut {
+		t.Errorf("the hung image is not reported as timed out: %v", opts.state.warnings.list())
+	}
+}
+
+func BenchmarkEnrichSharedImages(b *testing.B) {
+	for _, cached := range []bool{false, true} {
+		b.Run(fmt.Sprintf("cached=%v", cached), func(b *testing.B) {
+			var opens int
+			for i := 0; i < b.N; i++ {
+				records, fsys := sharedImageRecords(200, 5)
+				opts := Options{fs: fsys, MaxInFlightHashes: 8}
+				// Without the state of a scan, every record is analyzed.
+				if cached {
+					opts.state = newScanState()
+				}
+				enrichAll(opts, records)
+				opens += fsys.opens
+			}
+			b.ReportMetric(float64(opens)/float64(b.N), "hashes/op")
+		})
+	}
+}
+
+func BenchmarkScan(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if _, err := Scan(context.Background(), Options{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkScanNoHash(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if _, err := Scan(context.Background(), Options{QuickScan: true}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkScanCategory(b *testing.B) {
+	for _, name := range Categories() {
+		name := name
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := ScanCategory(name, Options{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
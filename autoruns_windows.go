@@ -5,13 +5,13 @@ package autoruns
 import (
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
-	"github.com/botherder/go-files"
+	"golang.org/x/sys/windows"
 	"golang.org/x/sys/windows/registry"
 )
 
@@ -21,30 +21,210 @@ func registryToString(reg registry.Key) string {
 		return "LOCAL_MACHINE"
 	} else if reg == registry.CURRENT_USER {
 		return "CURRENT_USER"
+	} else if reg == registry.CLASSES_ROOT {
+		return "CLASSES_ROOT"
+	} else if reg == registry.USERS {
+		return "USERS"
 	} else {
 		return ""
 	}
 }
 
-func parsePath(entryValue string) (string, string, error) {
+var (
+	dosDevicesOnce sync.Once
+	dosDevices     map[string]string
+)
+
+// dosDeviceMap maps native device names (e.g. \Device\HarddiskVolume1),
+// lowercased, to the drive letters they are mounted as.
+func dosDeviceMap() map[string]string {
+	dosDevicesOnce.Do(func() {
+		dosDevices = make(map[string]string)
+		buf := make([]uint16, windows.MAX_PATH)
+		for letter := 'A'; letter <= 'Z'; letter++ {
+			drive := string(letter) + ":"
+			drivePtr, err := windows.UTF16PtrFromString(drive)
+			if err != nil {
+				continue
+			}
+			n, err := windows.QueryDosDevice(drivePtr, &buf[0], uint32(len(buf)))
+			if err != nil || n == 0 {
+				continue
+			}
+			// The first of the returned strings is the current mapping.
+			dosDevices[strings.ToLower(windows.UTF16ToString(buf[:n]))] = drive
+		}
+	})
+
+	return dosDevices
+}
+
+// resolveDevicePath converts a path on a native device, such as
+// \Device\HarddiskVolume1\Windows\System32, to one on the corresponding
+// drive letter. Other paths are returned unchanged.
+func resolveDevicePath(path string) string {
+	lowerPath := strings.ToLower(path)
+	if !strings.HasPrefix(lowerPath, `\device\`) {
+		return path
+	}
+
+	for device, drive := range dosDeviceMap() {
+		// Make sure HarddiskVolume1 does not match HarddiskVolume10.
+		if strings.HasPrefix(lowerPath, device) && (len(lowerPath) == len(device) || lowerPath[len(device)] == '\\') {
+			return drive + path[len(device):]
+		}
+	}
+
+	return path
+}
+
+// Errors which indicate a busy system rather than a missing or protected
+// key, and are therefore worth retrying.
+var transientErrors = []error{
+	windows.ERROR_SHARING_VIOLATION,
+	windows.ERROR_LOCK_VIOLATION,
+	windows.ERROR_BUSY,
+	windows.ERROR_NOT_ENOUGH_MEMORY,
+	windows.ERROR_OUTOFMEMORY,
+	windows.ERROR_NO_SYSTEM_RESOURCES,
+	windows.ERROR_RETRY,
+	windows.ERROR_TIMEOUT,
+}
+
+func isTransient(err error) bool {
+	for _, transient := range transientErrors {
+		if errors.Is(err, transient) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// retryTransient calls fn until it succeeds, fails with an error which is
+// not transient, or runs out of the attempts configured in opts. The
+// backoff between attempts doubles every time.
+func retryTransient(opts Options, fn func() error) error {
+	retries, backoff := opts.retryPolicy()
+
+	err := fn()
+	for attempt := 0; attempt < retries && err != nil && isTransient(err); attempt++ {
+		select {
+		case <-opts.Context().Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		err = fn()
+	}
+
+	return err
+}
+
+// openKey opens a registry key for reading, reporting a failure as a
+// warning of the scan. Transient failures are retried.
+func openKey(opts Options, reg registry.Key, path string) (registryKey, error) {
+	var key registryKey
+	err := retryTransient(opts, func() (err error) {
+		key, err = registryFor(opts).OpenKey(reg, path)
+		return err
+	})
+	if errors.Is(err, errOutOfScope) {
+		opts.debugf("%s: skipping %s\\%s, which is out of scope", opts.category, registryToString(reg), path)
+	} else if err != nil {
+		opts.debugf("%s: could not open %s\\%s: %v", opts.category, registryToString(reg), path, err)
+		opts.Warn(fmt.Sprintf("%s\\%s", registryToString(reg), path), err)
+	} else {
+		opts.debugf("%s: opened %s\\%s", opts.category, registryToString(reg), path)
+	}
+
+	return key, err
+}
+
+// openOptionalKey opens a registry key which is normally absent, such as
+// one only older versions of Windows use. Unlike openKey, it does not warn
+// when the key does not exist.
+func openOptionalKey(opts Options, reg registry.Key, path string) (registryKey, error) {
+	key, err := registryFor(opts).OpenKey(reg, path)
+	if errors.Is(err, registry.ErrNotExist) {
+		opts.debugf("%s: %s\\%s does not exist", opts.category, registryToString(reg), path)
+		return nil, err
+	} else if err != nil {
+		return openKey(opts, reg, path)
+	}
+
+	opts.debugf("%s: opened %s\\%s", opts.category, registryToString(reg), path)
+	return key, nil
+}
+
+// The longest command line CreateProcess accepts, in characters.
+const maxCommandLineLength = 32767
+
+// How many prefixes of an unquoted command line parsePath looks up before
+// giving up on finding its executable.
+const maxExecutableLookups = 32
+
+// ParseCommandLine splits a command line, such as the value of a Run key
+// or the ImagePath of a service, into the executable it launches and its
+// arguments, resolving the executable the way the scanners do:
+//
+//   - The native prefix \??\ is stripped, NT device paths such as
+//     \Device\HarddiskVolume2\ are mapped to their drive letters, and a
+//     leading \SystemRoot or System32 is expanded to the Windows directory.
+//   - Environment variables such as %ProgramFiles% are expanded.
+//   - A quoted executable extends to the closing quote. An unclosed quote is
+//     an error.
+//   - An unquoted executable is resolved the way CreateProcess does: the
+//     command is cut at the first space or tab, and extended to the next
+//     one until the result names an existing executable. Names without an
+//     extension are tried with the extensions in PATHEXT, and names without
+//     a directory are searched in PATH. If no executable is found within
+//     the first 32 attempts, an error is returned.
+//   - The command ends at the first NUL character. Commands longer than
+//     CreateProcess accepts are an error.
+//
+// The executable found is cleaned, and the arguments have surrounding
+// whitespace removed.
+func ParseCommandLine(command string) (imagePath string, arguments string, err error) {
+	revert := disableFsRedirection()
+	defer revert()
+
+	return parsePath(Options{}, command)
+}
+
+// parsePath implements ParseCommandLine, looking up executables in the file
+// system of opts.
+func parsePath(opts Options, entryValue string) (string, string, error) {
+	// Windows reads strings up to the first NUL, and registry values can
+	// hold more after it.
+	if nul := strings.IndexByte(entryValue, 0); nul >= 0 {
+		entryValue = entryValue[:nul]
+	}
 	if entryValue == "" {
 		return "", "", errors.New("empty path")
 	}
+	if len(entryValue) > maxCommandLineLength {
+		return "", "", errors.New("command line too long")
+	}
 	// do some replacements to convert typical kernel paths to user paths
 	if strings.HasPrefix(entryValue, `\??\`) {
 		entryValue = entryValue[4:]
 	}
+	entryValue = resolveDevicePath(entryValue)
 	if len(entryValue) >= 11 && strings.ToLower(entryValue[:11]) == "\\systemroot" {
-		entryValue = strings.Replace(entryValue, entryValue[:11], os.Getenv("SystemRoot"), -1)
+		entryValue = os.Getenv("SystemRoot") + entryValue[11:]
 	}
 	if len(entryValue) >= 8 && strings.ToLower(entryValue[:8]) == "system32" {
-		entryValue = strings.Replace(entryValue, entryValue[:8], fmt.Sprintf("%s\\System32", os.Getenv("SystemRoot")), -1)
+		entryValue = fmt.Sprintf("%s\\System32", os.Getenv("SystemRoot")) + entryValue[8:]
 	}
 	// replace environment variables
 	entryValue, err := registry.ExpandString(entryValue)
 	if err != nil {
 		return "", "", err
 	}
+	if len(entryValue) > maxCommandLineLength {
+		return "", "", errors.New("command line too long")
+	}
 
 	// Now find the executable, analogous to how CreateProcess works
 	var executable string
@@ -57,6 +237,9 @@ func parsePath(entryValue string) (string, string, error) {
 		}
 		executable = entryValue[1 : closingQuote+1]
 		arguments = entryValue[closingQuote+2:]
+		if strings.TrimSpace(executable) == "" {
+			return "", "", errors.New("empty path")
+		}
 	} else {
 		// Unquoted executable. Try to look for first word first and then extend the path if that fails, e.g.:
 		// For C:\Program Files\My Application\app.exe some args, first search for:
@@ -66,9 +249,11 @@ func parsePath(entryValue string) (string, string, error) {
 		// And if that still fails, look for:
 		// C:\Program Files\My Application\app.exe
 		// ...
+		// Every attempt is looked up in the file system, so their number is
+		// bounded, and runs of whitespace only count once.
 		var spaceIndex int
-		for {
-			if spaceIndex == len(entryValue) {
+		for attempts := 0; ; {
+			if spaceIndex == len(entryValue) || attempts == maxExecutableLookups {
 				// Could not find file
 				return "", "", errors.New("executable not found")
 			}
@@ -78,7 +263,11 @@ func parsePath(entryValue string) (string, string, error) {
 				spaceIndex += nextSpace + 1
 			}
 			possibleExecutable := entryValue[:spaceIndex]
-			if exePath, err := exec.LookPath(possibleExecutable); err == nil {
+			if strings.TrimRight(possibleExecutable, " \t") != possibleExecutable {
+				continue
+			}
+			attempts++
+			if exePath, err := fileSystemFor(opts).LookPath(possibleExecutable); err == nil {
 				executable = exePath
 				if spaceIndex < len(entryValue) {
 					arguments = entryValue[spaceIndex+1:]
@@ -89,28 +278,128 @@ func parsePath(entryValue string) (string, string, error) {
 	}
 
 	arguments = strings.TrimSpace(arguments)
-	if v, err := cleanPath(executable); err == nil {
+	if v, err := cleanPath(opts, executable); err == nil {
 		executable = v
 	}
 	return executable, arguments, nil
 }
 
-func stringToAutorun(entryType string, entryLocation string, entryValue string, toParse bool, entry string) *Autorun {
+// systemDLLPath resolves a DLL name the way LoadLibrary does for system
+// components: environment variables are expanded and bare names are looked
+// up in System32.
+func systemDLLPath(dll string) string {
+	if expanded, err := registry.ExpandString(dll); err == nil {
+		dll = expanded
+	}
+	if !filepath.IsAbs(dll) {
+		dll = filepath.Join(os.Getenv("SystemRoot"), "System32", dll)
+	}
+
+	return dll
+}
+
+// canonicalPath normalizes a file path so that the same file is always
+// reported in the same form: separators are normalized, "." and ".."
+// elements are resolved, 8.3 short names (e.g. PROGRA~1) are expanded if the
+// file exists and the drive letter is uppercased.
+func canonicalPath(path string) string {
+	if path == "" {
+		return path
+	}
+
+	path = filepath.Clean(path)
+	if longPath, err := longPathName(path); err == nil {
+		path = longPath
+	}
+	if len(path) >= 2 && path[1] == ':' {
+		path = strings.ToUpper(path[:1]) + path[1:]
+	}
+
+	return path
+}
+
+// longPathName expands any 8.3 short names in path using GetLongPathName.
+func longPathName(path string) (string, error) {
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return "", err
+	}
+
+	buf := make([]uint16, windows.MAX_PATH)
+	for {
+		n, err := windows.GetLongPathName(pathPtr, &buf[0], uint32(len(buf)))
+		if err != nil {
+			return "", err
+		}
+		// If the buffer is too small, n is the required size.
+		if n < uint32(len(buf)) {
+			return windows.UTF16ToString(buf[:n]), nil
+		}
+		buf = make([]uint16, n)
+	}
+}
+
+// existingRawPath checks whether a launch string, which could not be
+// parsed as a command line, is the path of an existing file as a whole.
+func existingRawPath(opts Options, value string) (string, bool) {
+	path := strings.Trim(strings.TrimSpace(value), "\"")
+	if path == "" {
+		return "", false
+	}
+	if expanded, err := registry.ExpandString(path); err == nil {
+		path = expanded
+	}
+
+	info, err := fileSystemFor(opts).Stat(path)
+	if err != nil || info.IsDir() {
+		return "", false
+	}
+
+	return path, true
+}
+
+func stringToAutorun(opts Options, entryType string, entryLocation string, entryValue string, toParse bool, entry string) *Autorun {
+	// A quick scan does not touch the file system, so the value is reported
+	// exactly as found.
+	if opts.QuickScan {
+		return &Autorun{
+			Type:         entryType,
+			Location:     entryLocation,
+			ImagePath:    entryValue,
+			Entry:        entry,
+			LaunchString: entryValue,
+		}
+	}
+
+	// The executable has to be looked up the way the system sees it, like
+	// the image is analyzed.
+	revert := disableFsRedirection()
+	defer revert()
+
 	var imagePath = entryValue
 	var launchString = entryValue
 	var argsString = ""
 
+	var unparsed, unquotedPath bool
+
 	if toParse {
-		executable, args, err := parsePath(entryValue)
+		executable, args, err := parsePath(opts, entryValue)
 		if err == nil {
 			imagePath = executable
 			argsString = args
+			unquotedPath = !strings.HasPrefix(strings.TrimSpace(entryValue), "\"") && strings.ContainsAny(executable, " \t")
+		} else if path, ok := existingRawPath(opts, entryValue); ok {
+			// The value can still name a file which is not executable by
+			// itself, such as a DLL.
+			imagePath = path
+		} else {
+			opts.debugf("%s: could not resolve the executable of %q: %v", opts.category, entryValue, err)
+			unparsed = true
 		}
 	}
 
-	md5, _ := files.HashFile(imagePath, "md5")
-	sha1, _ := files.HashFile(imagePath, "sha1")
-	sha256, _ := files.HashFile(imagePath, "sha256")
+	// The original value is kept in LaunchString.
+	imagePath = canonicalPath(imagePath)
 
 	newAutorun := Autorun{
 		Type:         entryType,
@@ -118,46 +407,78 @@ func stringToAutorun(entryType string, entryLocation string, entryValue string,
 		ImagePath:    imagePath,
 		ImageName:    filepath.Base(imagePath),
 		Arguments:    argsString,
-		MD5:          md5,
-		SHA1:         sha1,
-		SHA256:       sha256,
 		Entry:        entry,
 		LaunchString: launchString,
+		unparsed:     unparsed,
+		unquotedPath: unquotedPath,
 	}
 
 	return &newAutorun
 }
 
-// This function invokes all the platform-dependant functions.
-func getAutoruns() (records []*Autorun) {
-	records = append(records, windowsGetCurrentVersionRun()...)
-	records = append(records, windowsGetServices()...)
-	records = append(records, windowsGetStartupFiles()...)
-	// records = append(records, windowsGetTasks()...)
-
-	return
+func init() {
+	RegisterScanner("run_keys", windowsGetCurrentVersionRun)
+	registerMachineScanner("services", windowsGetServices)
+	RegisterScanner("startup_files", windowsGetStartupFiles)
+	registerMachineScanner("print_processors", windowsGetPrintProcessors)
+	registerMachineScanner("winlogon_notify", windowsGetWinlogonNotify)
+	registerMachineScanner("winlogon_system", windowsGetWinlogonSystem)
+	registerMachineScanner("winlogon_vmapplet", windowsGetWinlogonVmApplet)
+	registerMachineScanner("autologon", windowsGetAutoLogon)
+	registerMachineScanner("gp_extensions", windowsGetGPExtensions)
+	registerMachineScanner("network_providers", windowsGetNetworkProviders)
+	registerMachineScanner("aedebug", windowsGetAeDebug)
+	registerMachineScanner("boot_programs", windowsGetBootPrograms)
+	RegisterScanner("namespace_extensions", windowsGetNamespaceExtensions)
+	registerPrivilegedScanner("defender_exclusions", windowsGetDefenderExclusions)
+	registerMachineScanner("defender_tamper", windowsGetDefenderTamper)
+	RegisterScanner("file_associations", windowsGetFileAssociations)
+	RegisterScanner("protocol_handlers", windowsGetProtocolHandlers)
+	registerPrivilegedScanner("tasks", windowsGetTasks)
+	RegisterScanner("rdp_initial_program", windowsGetRDPInitialProgram)
+	RegisterScanner("rdp_addins", windowsGetRDPAddIns)
+	RegisterScanner("logonui_background", windowsGetLogonUIBackground)
+	RegisterScanner("bits_jobs", windowsGetBitsJobs)
+	RegisterScanner("execution_history", windowsGetExecutionHistory)
+	RegisterScanner("autoplay_handlers", windowsGetAutoplayHandlers)
+	RegisterScanner("app_paths", windowsGetAppPaths)
+	RegisterScanner("powershell_profiles", windowsGetPowerShellProfiles)
+	RegisterScanner("startup_delay", windowsGetStartupDelay)
+	RegisterScanner("cor_profiler", windowsGetCORProfiler)
+	RegisterScanner("shell_handlers", windowsGetShellHandlers)
+	registerMachineScanner("known_dlls", windowsGetKnownDLLs)
+	registerMachineScanner("alternate_shell", windowsGetAlternateShell)
+	registerMachineScanner("gp_scripts", windowsGetGPScripts)
+	registerMachineScanner("lsa_extensions", windowsGetLsaExtensions)
+	registerMachineScanner("active_setup", windowsGetActiveSetup)
+	registerMachineScanner("ini_mappings", windowsGetIniMappings)
+	registerMachineScanner("pssession_configurations", windowsGetPSSessionConfigurations)
+	registerMachineScanner("provisioning_commands", windowsGetProvisioningCommands)
+	registerMachineScanner("media_codecs", windowsGetMediaCodecs)
 }
 
 // This function enumerates items registered through CurrentVersion\Run.
-func windowsGetCurrentVersionRun() (records []*Autorun) {
-	regs := []registry.Key{
-		registry.LOCAL_MACHINE,
-		registry.CURRENT_USER,
+func windowsGetCurrentVersionRun(opts Options) (records []*Autorun) {
+	roots := []registryRoot{
+		{reg: registry.LOCAL_MACHINE},
 	}
+	roots = append(roots, userRegistryRoots(opts)...)
 
 	keyNames := []string{
 		"Software\\Microsoft\\Windows\\CurrentVersion\\Run",
 		"Software\\Microsoft\\Windows\\CurrentVersion\\RunOnce",
+		"Software\\Microsoft\\Windows\\CurrentVersion\\RunOnce\\Setup",
+		"Software\\Microsoft\\Windows\\CurrentVersion\\Policies\\Explorer\\Run",
 		"Software\\Wow6432Node\\Microsoft\\Windows\\CurrentVersion\\Run",
 		"Software\\Wow6432Node\\Microsoft\\Windows\\CurrentVersion\\RunOnce",
 	}
 
-	// We loop through HKLM and HKCU.
-	for _, reg := range regs {
+	// We loop through HKLM, HKCU and the hives of other users.
+	for _, root := range roots {
 		// We loop through the keys we're interested in.
 		for _, keyName := range keyNames {
 			// Open registry key.
-			key, err := registry.OpenKey(reg, keyName, registry.READ)
+			key, err := openKey(opts, root.reg, root.prefix+keyName)
 			if err != nil {
 				continue
 			}
@@ -173,13 +494,19 @@ func windowsGetCurrentVersionRun() (records []*Autorun) {
 				// For each entry we get the string value.
 				value, _, err := key.GetStringValue(name)
 				if err != nil || value == "" {
+					opts.debugf("%s: skipping value %s of %s%s, which is empty or not a string", opts.category, name, root.prefix, keyName)
 					continue
 				}
 
-				imageLocation := fmt.Sprintf("%s\\%s", registryToString(reg), keyName)
+				imageLocation := fmt.Sprintf("%s\\%s%s", registryToString(root.reg), root.prefix, keyName)
 
 				// We pass the value string to a function to return an Autorun.
-				newAutorun := stringToAutorun("run_key", imageLocation, value, true, name)
+				newAutorun := stringToAutorun(opts, "run_key", imageLocation, value, true, name)
+				newAutorun.RawName = name
+				newAutorun.User = root.user
+				if startupDelayDisabled(opts) {
+					newAutorun.Trigger = "logon_without_delay"
+				}
 
 				// Add the new autorun to the records.
 				records = append(records, newAutorun)
@@ -191,35 +518,58 @@ func windowsGetCurrentVersionRun() (records []*Autorun) {
 	return
 }
 
-// This function enumerates Windows Services.
-func windowsGetServices() (records []*Autorun) {
+// This function enumerates Windows Services and drivers. Drivers loaded in
+// the boot or system phase have LoadPhase set to where they are in the load
+// order.
+func windowsGetServices(opts Options) (records []*Autorun) {
 	var reg registry.Key = registry.LOCAL_MACHINE
 	var servicesKey string = "System\\CurrentControlSet\\Services"
 
 	// Open the registry key.
-	key, err := registry.OpenKey(reg, servicesKey, registry.READ)
+	key, err := openKey(opts, reg, servicesKey)
 	if err != nil {
 		return
 	}
 
 	// Enumerate subkeys.
-	names, err := key.ReadSubKeyNames(0)
+	names, err := readSubKeyNames(opts, key, fmt.Sprintf("%s\\%s", registryToString(reg), servicesKey))
 	key.Close()
 	if err != nil {
 		return
 	}
 
+	groupOrder := readServiceGroupOrder(opts)
+
 	for _, name := range names {
+		if opts.Context().Err() != nil {
+			return
+		}
+
 		// We open each subkey.
 		subkeyPath := fmt.Sprintf("%s\\%s", servicesKey, name)
-		subkey, err := registry.OpenKey(reg, subkeyPath, registry.READ)
+		subkey, err := openKey(opts, reg, subkeyPath)
 		if err != nil {
 			continue
 		}
 
 		// Check if there is an ImagePath value.
 		imagePath, _, err := subkey.GetStringValue("ImagePath")
+		serviceType, _, _ := subkey.GetIntegerValue("Type")
+		startMode := serviceStartMode(subkey)
+		loadPhase, loadAnomaly := driverLoadPhase(groupOrder, subkey, startMode)
 		subkey.Close()
+
+		// Kernel and file system drivers are reported separately.
+		entryType := "service"
+		if serviceType&(windows.SERVICE_KERNEL_DRIVER|windows.SERVICE_FILE_SYSTEM_DRIVER) != 0 {
+			entryType = "driver"
+			// Drivers without an ImagePath are loaded from System32\drivers.
+			if err != nil {
+				imagePath = fmt.Sprintf("System32\\drivers\\%s.sys", name)
+				err = nil
+			}
+		}
+
 		// If not, we skip to the next one.
 		if err != nil {
 			continue
@@ -228,7 +578,20 @@ func windowsGetServices() (records []*Autorun) {
 		imageLocation := fmt.Sprintf("%s\\%s", registryToString(reg), subkeyPath)
 
 		// We pass the value string to a function to return an Autorun.
-		newAutorun := stringToAutorun("service", imageLocation, imagePath, true, "")
+		newAutorun := stringToAutorun(opts, entryType, imageLocation, imagePath, true, name)
+		newAutorun.RawName = "ImagePath"
+		newAutorun.Trigger = serviceTrigger(opts, reg, subkeyPath)
+		newAutorun.StartMode = startMode
+
+		// Drivers loading early are the hardest to spot once loaded, so we
+		// flag those loaded out of order or from outside the driver
+		// directories.
+		if entryType == "driver" {
+			newAutorun.LoadPhase = loadPhase
+			if loadPhase != "" && !opts.QuickScan {
+				newAutorun.Suspicious = loadAnomaly || !inDriverDirectory(newAutorun.ImagePath)
+			}
+		}
 
 		// Add the new autorun to the records.
 		records = append(records, newAutorun)
@@ -237,39 +600,105 @@ func windowsGetServices() (records []*Autorun) {
 	return
 }
 
+// startupEntry is a file in a Startup folder, along with its path relative
+// to the Startup folder and how many subfolders below it the file is.
+type startupEntry struct {
+	entry   os.FileInfo
+	rawName string
+	depth   int
+}
+
 // %ProgramData%\Microsoft\Windows\Start Menu\Programs\StartUp
 // %AppData%\Microsoft\Windows\Start Menu\Programs\Startup
-func windowsGetStartupFiles() (records []*Autorun) {
+func windowsGetStartupFiles(opts Options) (records []*Autorun) {
 	// We look for both global and user Startup folders.
 	folders := []string{
 		os.Getenv("ProgramData"),
 		os.Getenv("AppData"),
 	}
+	if opts.UserScopeOnly {
+		folders = folders[1:]
+	}
+	// The current user is local, and has no folder on another system.
+	if scansOtherSystem(opts) {
+		folders = folders[:1]
+	}
 
 	// The base path is the same for both.
 	var startupBasepath string = "Microsoft\\Windows\\Start Menu\\Programs\\StartUp"
 
+	// The user Startup folders of other profiles, if requested.
+	users := map[string]string{
+		os.Getenv("AppData"): keyUser(opts, registry.CURRENT_USER),
+	}
+	userFolders := map[string]bool{
+		os.Getenv("AppData"): true,
+	}
+	for _, hive := range userHives(opts) {
+		// Not every loaded hive has a known profile directory.
+		if hive.profile == "" {
+			continue
+		}
+		folder := filepath.Join(hive.profile, "AppData\\Roaming")
+		folders = append(folders, folder)
+		userFolders[folder] = true
+		if opts.ResolveUsers {
+			users[folder] = hive.user
+		}
+	}
+
 	for _, folder := range folders {
 		// Get the full path.
 		startupPath := filepath.Join(folder, startupBasepath)
 
 		// Get list of files in folder.
-		filesList, err := ioutil.ReadDir(startupPath)
+		folderList, err := fileSystemFor(opts).ReadDir(startupPath)
 		if err != nil {
+			opts.Warn(startupPath, err)
 			continue
 		}
+		var filesList []startupEntry
+		for _, fileEntry := range folderList {
+			filesList = append(filesList, startupEntry{fileEntry, fileEntry.Name(), 0})
+		}
+
+		// Loop through all files in folder, and in its subfolders if
+		// requested.
+		for len(filesList) > 0 {
+			fileEntry := filesList[0]
+			filesList = filesList[1:]
 
-		// Loop through all files in folder.
-		for _, fileEntry := range filesList {
 			// We skip desktop.ini files.
-			if fileEntry.Name() == "desktop.ini" {
+			if fileEntry.entry.Name() == "desktop.ini" {
 				continue
 			}
 
-			filePath := filepath.Join(startupPath, fileEntry.Name())
+			filePath := filepath.Join(startupPath, fileEntry.rawName)
+			if fileEntry.entry.IsDir() {
+				if !opts.RecurseStartupFolders || opts.tooDeep(filePath, fileEntry.depth+1) {
+					continue
+				}
+				subfolderList, err := fileSystemFor(opts).ReadDir(filePath)
+				if err != nil {
+					opts.Warn(filePath, err)
+					continue
+				}
+				for _, subfolderEntry := range subfolderList {
+					filesList = append(filesList, startupEntry{subfolderEntry, filepath.Join(fileEntry.rawName, subfolderEntry.Name()), fileEntry.depth + 1})
+				}
+				continue
+			}
 
 			// Instantiate new autorun record.
-			newAutorun := stringToAutorun("startup", startupPath, filePath, false, "")
+			newAutorun := stringToAutorun(opts, "startup", startupPath, filePath, false, fileEntry.entry.Name())
+			newAutorun.RawName = fileEntry.rawName
+			newAutorun.User = users[folder]
+			if userFolders[folder] {
+				newAutorun.Scope = "user"
+			}
+			if startupDelayDisabled(opts) {
+				newAutorun.Trigger = "logon_without_delay"
+			}
 
 			// Add new record to list.
 			records = append(records, newAutorun)
@@ -281,14 +710,10 @@ func windowsGetStartupFiles() (records []*Autorun) {
 
 // cleanPath uses lookPath to search for the correct path to
 // the executable and cleans the file path.
-func cleanPath(file string) (string, error) {
-	file, err := exec.LookPath(file)
+func cleanPath(opts Options, file string) (string, error) {
+	file, err := fileSystemFor(opts).LookPath(file)
 	if err != nil {
 		return "", err
 	}
 	return filepath.Clean(file), nil
 }
-
-// func windowsGetTasks() {
-
-// }
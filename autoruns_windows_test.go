@@ -0,0 +1,459 @@
+//+build windows
+
+package autoruns
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/windows"
+)
+
+func TestParseCommandLine(t *testing.T) {
+	dir := t.TempDir()
+	app := filepath.Join(dir, "My App", "app.exe")
+	tool := filepath.Join(dir, "tool.exe")
+	for _, path := range []string{app, tool} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte("MZ"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir)
+	t.Setenv("PATHEXT", ".COM;.EXE;.BAT;.CMD")
+	t.Setenv("APPDIR", dir)
+
+	tests := []struct {
+		command    string
+		executable string
+		arguments  string
+	}{
+		{`"` + app + `" --flag "quoted arg"`, app, `--flag "quoted arg"`},
+		{app + ` --flag`, app, "--flag"},
+		{filepath.Join(dir, "My App", "app") + ` --flag`, app, "--flag"},
+		{`tool /q`, tool, "/q"},
+		{`%APPDIR%\tool.exe /q`, tool, "/q"},
+		{`\??\` + tool, tool, ""},
+		{tool + "\t\t-x  ", tool, "-x"},
+		{tool + " -x\x00garbage", tool, "-x"},
+		{dir + `\.\My App\..\tool.exe`, tool, ""},
+	}
+	for _, test := range tests {
+		executable, arguments, err := ParseCommandLine(test.command)
+		if err != nil {
+			t.Errorf("ParseCommandLine(%q): %v", test.command, err)
+			continue
+		}
+		if !strings.EqualFold(executable, test.executable) || arguments != test.arguments {
+			t.Errorf("ParseCommandLine(%q) = %q, %q, want %q, %q", test.command, executable, arguments, test.executable, test.arguments)
+		}
+	}
+
+	for _, command := range []string{
+		"",
+		`"` + app,
+		`""`,
+		filepath.Join(dir, "missing.exe") + " -x",
+		tool + " " + strings.Repeat("x", maxCommandLineLength),
+		strings.Repeat("a ", maxExecutableLookups+1) + tool,
+	} {
+		if executable, arguments, err := ParseCommandLine(command); err == nil {
+			t.Errorf("ParseCommandLine(%.40q) = %q, %q, want an error", command, executable, arguments)
+		}
+	}
+}
+
+func TestParsePathWalksSpaces(t *testing.T) {
+	fsys := newFakeFileSystem(map[string]string{
+		`C:\Program Files\My App\app.exe`: "MZ",
+		`C:\Windows\System32\cmd.exe`:     "MZ",
+	})
+	opts := Options{fs: fsys}
+
+	tests := []struct {
+		value      string
+		executable string
+		arguments  string
+	}{
+		{`C:\Program Files\My App\app.exe --start  now`, `C:\Program Files\My App\app.exe`, "--start  now"},
+		{`"C:\Program Files\My App\app.exe" -x`, `C:\Program Files\My App\app.exe`, "-x"},
+		{`C:\Windows\System32\cmd.exe /c C:\Program Files\My App\app.exe`, `C:\Windows\System32\cmd.exe`, `/c C:\Program Files\My App\app.exe`},
+		{"C:\\Program Files\\My App\\app.exe\x00garbage", `C:\Program Files\My App\app.exe`, ""},
+	}
+	for _, test := range tests {
+		executable, arguments, err := parsePath(opts, test.value)
+		if err != nil {
+			t.Errorf("parsePath(%q): %v", test.value, err)
+			continue
+		}
+		if executable != test.executable || arguments != test.arguments {
+			t.Errorf("parsePath(%q) = %q, %q, want %q, %q", test.value, executable, arguments, test.executable, test.arguments)
+		}
+	}
+
+	for _, value := range []string{`C:\Program Files\Missing\app.exe -x`, `"C:\Program Files\My App\app.exe`, ""} {
+		if _, _, err := parsePath(opts, value); err == nil {
+			t.Errorf("parsePath(%q) did not fail", value)
+		}
+	}
+}
+
+func TestParsePathNativePaths(t *testing.T) {
+	drive := os.Getenv("SystemDrive")
+	if drive == "" {
+		drive = "C:"
+	}
+	t.Setenv("SystemRoot", drive+`\Windows`)
+	driver := drive + `\Windows\System32\drivers\x.sys`
+	opts := Options{fs: newFakeFileSystem(map[string]string{driver: "MZ"})}
+
+	values := []string{
+		`\SystemRoot\System32\drivers\x.sys`,
+		`\systemroot\System32\drivers\x.sys`,
+		`\??\` + driver,
+		`System32\drivers\x.sys`,
+	}
+	var device string
+	for name, letter := range dosDeviceMap() {
+		if strings.EqualFold(letter, drive) {
+			device = name
+		}
+	}
+	if device != "" {
+		values = append(values, device+`\Windows\System32\drivers\x.sys`, strings.ToUpper(device)+`\Windows\System32\drivers\x.sys`)
+	} else {
+		t.Logf("no device is mounted as %s", drive)
+	}
+
+	for _, value := range values {
+		executable, _, err := parsePath(opts, value)
+		if err != nil || executable != driver {
+			t.Errorf("parsePath(%q) = %q, %v, want %q", value, executable, err, driver)
+		}
+	}
+
+	// Devices which are not mounted are left alone, and so are those whose
+	// name only starts like one that is.
+	unmounted := []string{`\Device\HarddiskVolume999\x.sys`}
+	if _, ok := dosDeviceMap()[device+"0"]; device != "" && !ok {
+		unmounted = append(unmounted, device+`0\x.sys`)
+	}
+	for _, path := range unmounted {
+		if got := resolveDevicePath(path); got != path {
+			t.Errorf("resolveDevicePath(%q) = %q", path, got)
+		}
+	}
+}
+
+func TestStringToAutorunUnparsed(t *testing.T) {
+	fsys := newFakeFileSystem(map[string]string{`C:\Tools\payload.dll`: "MZ"})
+	opts := Options{fs: fsys}
+
+	// Like a program, a file such as a DLL is resolved by its extension.
+	autorun := stringToAutorun(opts, "run_key", "HKLM\\Run", `"C:\Tools\payload.dll"`, true, "payload")
+	if autorun.unparsed || autorun.ImagePath != `C:\Tools\payload.dll` {
+		t.Errorf("got ImagePath %q, unparsed %v", autorun.ImagePath, autorun.unparsed)
+	}
+
+	// Neither a file with trailing arguments nor garbage is resolved.
+	for _, value := range []string{`C:\Missing\tool.exe -x`, `C:\Tools\payload.dll,Start -x`, "\x01\x02 ???"} {
+		autorun = stringToAutorun(opts, "run_key", "HKLM\\Run", value, true, "tool")
+		if !autorun.unparsed || autorun.LaunchString != value {
+			t.Errorf("%q: got unparsed %v, LaunchString %q", value, autorun.unparsed, autorun.LaunchString)
+		}
+	}
+}
+
+func TestCanonicalPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{`c:\No Such Dir\app.exe`, `C:\No Such Dir\app.exe`},
+		{`c:/No Such Dir/./sub/../app.exe`, `C:\No Such Dir\app.exe`},
+		{`C:\No Such Dir\\app.exe`, `C:\No Such Dir\app.exe`},
+		{`\\server\share\app.exe`, `\\server\share\app.exe`},
+		{"", ""},
+	}
+	for _, test := range tests {
+		if got := canonicalPath(test.path); got != test.want {
+			t.Errorf("canonicalPath(%q) = %q, want %q", test.path, got, test.want)
+		}
+	}
+}
+
+func TestCanonicalPathShortName(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "Long Directory Name")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	longPath := filepath.Join(dir, "application.exe")
+	if err := ioutil.WriteFile(longPath, []byte("MZ"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]uint16, windows.MAX_PATH)
+	n, err := windows.GetShortPathName(windows.StringToUTF16Ptr(longPath), &buf[0], uint32(len(buf)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	shortPath := windows.UTF16ToString(buf[:n])
+	if !strings.Contains(shortPath, "~") {
+		t.Skip("the volume has no 8.3 names")
+	}
+
+	got := canonicalPath(shortPath)
+	if !strings.EqualFold(got, canonicalPath(longPath)) || !strings.HasSuffix(got, `\Long Directory Name\application.exe`) {
+		t.Errorf("canonicalPath(%q) = %q, want %q", shortPath, got, longPath)
+	}
+}
+
+func TestStringToAutorunCanonicalPath(t *testing.T) {
+	opts := Options{fs: newFakeFileSystem(map[string]string{`c:\No Such Dir\App\app.exe`: "MZ"})}
+
+	value := `c:\No Such Dir\.\App\app.exe -x`
+	autorun := stringToAutorun(opts, "run_key", `LOCAL_MACHINE\Run`, value, true, "app")
+	if autorun.ImagePath != `C:\No Such Dir\App\app.exe` || autorun.ImageName != "app.exe" {
+		t.Errorf("got ImagePath %q, ImageName %q", autorun.ImagePath, autorun.ImageName)
+	}
+	if autorun.LaunchString != value {
+		t.Errorf("LaunchString = %q, want the original value", autorun.LaunchString)
+	}
+}
+
+func TestWindowsGetStartupFilesRecursive(t *testing.T) {
+	t.Setenv("ProgramData", `C:\ProgramData`)
+	t.Setenv("AppData", `C:\Users\alice\AppData\Roaming`)
+	startup := `C:\Users\alice\AppData\Roaming\Microsoft\Windows\Start Menu\Programs\StartUp`
+	fsys := newFakeFileSystem(map[string]string{
+		startup + `\top.lnk`:           "link",
+		startup + `\desktop.ini`:       "",
+		startup + `\a\desktop.ini`:     "",
+		startup + `\a\nested.exe`:      "MZ nested",
+		startup + `\a\b\deep.exe`:      "MZ deep",
+		startup + `\a\b\c\desktop.ini`: "",
+	})
+
+	for _, recurse := range []bool{false, true} {
+		opts := Options{fs: fsys, RecurseStartupFolders: recurse}
+		opts.registry = fakeRegistry{}
+
+		var rawNames []string
+		for _, record := range runScanner(scanner{name: "startup_files", fn: windowsGetStartupFiles}, opts) {
+			rawNames = append(rawNames, record.RawName)
+			if info, err := fsys.Stat(record.ImagePath); err != nil || info.IsDir() || record.SHA256 == "" {
+				t.Errorf("%s: ImagePath %q is not a hashed file", record.RawName, record.ImagePath)
+			}
+		}
+
+		want := []string{"top.lnk"}
+		if recurse {
+			want = append(want, `a\nested.exe`, `a\b\deep.exe`)
+		}
+		if !reflect.DeepEqual(rawNames, want) {
+			t.Errorf("RecurseStartupFolders %v: got files %q, want %q", recurse, rawNames, want)
+		}
+	}
+}
+
+func TestWindowsGetStartupFilesMaxDepth(t *testing.T) {
+	t.Setenv("ProgramData", `C:\ProgramData`)
+	t.Setenv("AppData", `C:\Users\alice\AppData\Roaming`)
+	startup := `C:\Users\alice\AppData\Roaming\Microsoft\Windows\Start Menu\Programs\StartUp`
+
+	opts := Options{
+		RecurseStartupFolders: true,
+		MaxDepth:              1,
+		QuickScan:             true,
+		fs: newFakeFileSystem(map[string]string{
+			startup + `\top.lnk`:          "",
+			startup + `\desktop.ini`:      "",
+			startup + `\a\nested.lnk`:     "",
+			startup + `\a\b\too-deep.lnk`: "",
+		}),
+		state: newScanState(),
+	}
+	opts.registry = fakeRegistry{}
+
+	var rawNames []string
+	for _, record := range windowsGetStartupFiles(opts) {
+		rawNames = append(rawNames, record.RawName)
+		if record.Scope != "user" {
+			t.Errorf("%s: Scope = %q, want user", record.RawName, record.Scope)
+		}
+	}
+	if len(rawNames) != 2 || rawNames[0] != "top.lnk" || rawNames[1] != `a\nested.lnk` {
+		t.Errorf("got files %q, want top.lnk and a\\nested.lnk", rawNames)
+	}
+
+	var truncated bool
+	for _, warning := range opts.state.warnings.list() {
+		if errors.Is(warning, ErrTruncated) {
+			truncated = true
+		}
+	}
+	if !truncated {
+		t.Error("the folder below MaxDepth is not reported as truncated")
+	}
+}
+
+func TestWindowsEntryAndRawName(t *testing.T) {
+	t.Setenv("ProgramData", `C:\ProgramData`)
+	t.Setenv("AppData", `C:\Users\alice\AppData\Roaming`)
+	startup := `C:\Users\alice\AppData\Roaming\Microsoft\Windows\Start Menu\Programs\StartUp`
+
+	opts := Options{
+		QuickScan:             true,
+		RecurseStartupFolders: true,
+		fs: newFakeFileSystem(map[string]string{
+			startup + `\agent.lnk`:    "",
+			startup + `\a\nested.lnk`: "",
+		}),
+	}
+	opts.registry = fakeRegistry{
+		`LOCAL_MACHINE\Software\Microsoft\Windows\CurrentVersion\Run`: {
+			"Agent": `C:\Program Files\Agent\agent.exe --tray`,
+		},
+		`LOCAL_MACHINE\System\CurrentControlSet\Services\Updater`: {
+			"ImagePath": `C:\Program Files\Agent\updater.exe`,
+			"Type":      uint32(0x10),
+			"Start":     uint32(2),
+		},
+	}
+
+	tests := []struct {
+		name    string
+		scanner func(opts Options) []*Autorun
+		// The Type, Entry and RawName of each record.
+		want [][3]string
+	}{
+		// The name of the value is its label as well.
+		{"run_keys", windowsGetCurrentVersionRun, [][3]string{{"run_key", "Agent", "Agent"}}},
+		// A service is named by its key, and launched by its ImagePath.
+		{"services", windowsGetServices, [][3]string{{"service", "Updater", "ImagePath"}}},
+		// Files in subfolders are named by their path within the folder.
+		{"startup", windowsGetStartupFiles, [][3]string{
+			{"startup", "agent.lnk", "agent.lnk"},
+			{"startup", "nested.lnk", `a\nested.lnk`},
+		}},
+	}
+	for _, test := range tests {
+		var got [][3]string
+		for _, record := range test.scanner(opts) {
+			got = append(got, [3]string{record.Type, record.Entry, record.RawName})
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got Type, Entry and RawName %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+// The launch strings the fuzz targets of the parser are seeded with.
+var parsePathSeeds = []string{
+	`C:\Program Files\App\app.exe --flag value`,
+	`"C:\Program Files\App\app.exe" "quoted arg" tail`,
+	`"C:\Program Files\App\app.exe`,
+	`"" args`,
+	`C:\Program Files\App\app -x`,
+	`%ProgramFiles%\App\app.exe /s`,
+	`%UNDEFINED%\x.exe`,
+	`rundll32.exe shell32.dll,Control_RunDLL desk.cpl`,
+	`C:\Windows\System32\rundll32.exe "C:\Program Files\App\app.dll",#1`,
+	`\\server\share\tools\run.exe -q`,
+	`\\?\C:\Program Files\App\app.exe`,
+	`\??\C:\Program Files\App\app.exe`,
+	`\SystemRoot\System32\drivers\evil.sys`,
+	`system32\svchost.exe -k netsvcs`,
+	"C:\\Program Files\\App\\app.exe\x00trailing garbage",
+	"C:\\Program Files\\App\\app.exe \t  \t",
+	"\xff\xfe garbage \x01",
+	strings.Repeat("a ", 5000),
+	strings.Repeat("x", maxCommandLineLength+1),
+}
+
+// derivedFromInput checks whether executable is a prefix of the launch
+// string without its quotes, as parsePath cleaned it or appended the
+// extension of an executable to it.
+func derivedFromInput(unquoted string, executable string) bool {
+	for i := len(unquoted); i > 0; i-- {
+		prefix := filepath.Clean(unquoted[:i])
+		if prefix == executable {
+			return true
+		}
+		for _, ext := range fakePathExt {
+			if prefix+ext == executable {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func FuzzParsePath(f *testing.F) {
+	for _, seed := range parsePathSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, value string) {
+		fsys := &countingFileSystem{fileSystem: newFakeFileSystem(map[string]string{
+			`C:\Program Files\App\app.exe`:     "MZ",
+			`C:\Windows\System32\rundll32.exe`: "MZ",
+		})}
+
+		executable, arguments, err := parsePath(Options{fs: fsys}, value)
+		if fsys.lookups > maxExecutableLookups+1 {
+			t.Fatalf("parsePath(%q) looked up %d executables", value, fsys.lookups)
+		}
+		if err != nil {
+			return
+		}
+		if executable == "" {
+			t.Fatalf("parsePath(%q) returned no executable and no error", value)
+		}
+		if arguments != strings.TrimSpace(arguments) {
+			t.Fatalf("parsePath(%q) returned untrimmed arguments %q", value, arguments)
+		}
+
+		// Prefixes, device paths and variables are rewritten, so only the
+		// other launch strings must start with their executable.
+		if nul := strings.IndexByte(value, 0); nul >= 0 {
+			value = value[:nul]
+		}
+		lowerValue := strings.ToLower(value)
+		if strings.Contains(value, "%") || strings.HasPrefix(value, `\`) || strings.HasPrefix(lowerValue, "system32") || len(value) > 4096 {
+			return
+		}
+		if !derivedFromInput(strings.Replace(value, `"`, "", 2), executable) {
+			t.Fatalf("parsePath(%q) returned executable %q, which is not a prefix of it", value, executable)
+		}
+	})
+}
+
+func FuzzParseCommandLine(f *testing.F) {
+	for _, seed := range parsePathSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, command string) {
+		// This resolves executables on the local disk, so only the shape of
+		// the result is checked.
+		imagePath, arguments, err := ParseCommandLine(command)
+		if err != nil {
+			if imagePath != "" || arguments != "" {
+				t.Fatalf("ParseCommandLine(%q) failed with results %q, %q", command, imagePath, arguments)
+			}
+			return
+		}
+		if imagePath == "" || strings.ContainsRune(imagePath, 0) {
+			t.Fatalf("ParseCommandLine(%q) returned executable %q", command, imagePath)
+		}
+	})
+}
@@ -0,0 +1,154 @@
+package autoruns
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+// The columns of the CSV output of Sysinternals autorunsc (-c -s -h).
+var autorunscColumns = []string{
+	"Time",
+	"Entry Location",
+	"Entry",
+	"Enabled",
+	"Category",
+	"Profile",
+	"Description",
+	"Signer",
+	"Company",
+	"Image Path",
+	"Version",
+	"Launch String",
+	"MD5",
+	"SHA-1",
+	"PESHA-1",
+	"PESHA-256",
+	"SHA-256",
+	"IMP",
+}
+
+// autorunscCategories maps record types to the closest autorunsc category.
+var autorunscCategories = map[string]string{
+	"run_key":              "Logon",
+	"startup":              "Logon",
+	"rdp_initial_program":  "Logon",
+	"active_setup":         "Logon",
+	"powershell_profile":   "Logon",
+	"startup_delay":        "Logon",
+	"launch_agents":        "Logon",
+	"launch_agents_user":   "Logon",
+	"service":              "Services",
+	"launch_daemons":       "Services",
+	"systemd_service":      "Services",
+	"systemd_generator":    "Boot Execute",
+	"driver":               "Drivers",
+	"kext":                 "Drivers",
+	"system_extension":     "Drivers",
+	"print_processor":      "Print Monitors",
+	"winlogon_notify":      "Winlogon",
+	"winlogon_system":      "Winlogon",
+	"winlogon_vmapplet":    "Winlogon",
+	"autologon":            "Winlogon",
+	"gp_extension":         "Winlogon",
+	"gp_script":            "Logon",
+	"network_provider":     "Network Providers",
+	"boot_verification":    "Boot Execute",
+	"setup_cmdline":        "Boot Execute",
+	"setup_execute":        "Boot Execute",
+	"namespace_extension":  "Explorer",
+	"shell_copyhook":       "Explorer",
+	"shell_handler":        "Explorer",
+	"new_shortcut_handler": "Explorer",
+	"autoplay_handler":     "Explorer",
+	"aedebug":              "Image Hijacks",
+	"file_association":     "Image Hijacks",
+	"protocol_handler":     "Image Hijacks",
+	"app_paths":            "Image Hijacks",
+	"cor_profiler":         "AppInit",
+	"known_dll":            "KnownDLLs",
+	"lsa_extension":        "LSA Providers",
+	"media_codec":          "Codecs",
+	"logonui_background":   "Winlogon",
+	"alternate_shell":      "Boot Execute",
+	"scheduled_task":       "Tasks",
+	"bits_job":             "Tasks",
+	"cron":                 "Tasks",
+	"periodic":             "Tasks",
+	"at_job":               "Tasks",
+	"ld_preload":           "AppInit",
+	"pam_module":           "LSA Providers",
+	"motd_script":          "Logon",
+	"systemd_timer":        "Tasks",
+	"tmpfiles":             "Boot Execute",
+}
+
+// autorunscRoots maps the registry roots we report to the abbreviations
+// used by autorunsc.
+var autorunscRoots = []struct {
+	root         string
+	abbreviation string
+}{
+	{"LOCAL_MACHINE\\", "HKLM\\"},
+	{"CURRENT_USER\\", "HKCU\\"},
+	{"CLASSES_ROOT\\", "HKCR\\"},
+	{"USERS\\", "HKU\\"},
+}
+
+// WriteAutorunscCSV writes records to w in the CSV format of Sysinternals
+// autorunsc, so that they can be fed to tools built around its output.
+// Types are mapped to the closest autorunsc category, and columns we have
+// no data for, such as Description, Company and Version, are left empty.
+func WriteAutorunscCSV(w io.Writer, records []*Autorun) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(autorunscColumns); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		location := record.Location
+		for _, root := range autorunscRoots {
+			if strings.HasPrefix(location, root.root) {
+				location = root.abbreviation + strings.TrimPrefix(location, root.root)
+				break
+			}
+		}
+
+		profile := "System-wide"
+		if record.User != "" {
+			profile = record.User
+		}
+
+		var signer string
+		if record.Signed {
+			signer = "(Verified)"
+		}
+
+		row := []string{
+			"",
+			location,
+			record.Entry,
+			"enabled",
+			autorunscCategories[record.Type],
+			profile,
+			"",
+			signer,
+			"",
+			record.ImagePath,
+			"",
+			record.LaunchString,
+			record.MD5,
+			record.SHA1,
+			"",
+			"",
+			record.SHA256,
+			"",
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,328 @@
+//+build windows
+
+package autoruns
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modole32 = windows.NewLazySystemDLL("ole32.dll")
+
+	procCoCreateInstance = modole32.NewProc("CoCreateInstance")
+)
+
+var (
+	clsidBackgroundCopyManager = windows.GUID{Data1: 0x4991d34b, Data2: 0x80a1, Data3: 0x4291, Data4: [8]byte{0x83, 0xb6, 0x33, 0x28, 0x36, 0x6b, 0x90, 0x97}}
+	iidBackgroundCopyManager   = windows.GUID{Data1: 0x5ce34c0d, Data2: 0x0dc9, Data3: 0x4c1f, Data4: [8]byte{0x89, 0x7c, 0xda, 0xa1, 0xb7, 0x8c, 0xee, 0x7c}}
+	iidBackgroundCopyJob2      = windows.GUID{Data1: 0x54b50739, Data2: 0x686f, Data3: 0x45eb, Data4: [8]byte{0x9d, 0xff, 0xd6, 0xa9, 0xa0, 0xfa, 0xa9, 0xaf}}
+)
+
+// The methods of the BITS interfaces we call, by their index in the table
+// of methods of the interface.
+const (
+	methodQueryInterface = 0
+	methodRelease        = 2
+
+	// IBackgroundCopyManager
+	methodEnumJobs = 5
+	// IEnumBackgroundCopyJobs and IEnumBackgroundCopyFiles
+	methodNext = 3
+	// IBackgroundCopyJob
+	methodEnumFiles      = 5
+	methodGetID          = 10
+	methodGetTimes       = 13
+	methodGetState       = 14
+	methodGetOwner       = 16
+	methodGetDisplayName = 18
+	// IBackgroundCopyJob2
+	methodGetNotifyCmdLine = 36
+	// IBackgroundCopyFile
+	methodGetRemoteName = 3
+	methodGetLocalName  = 4
+)
+
+const (
+	bgJobEnumAllUsers = 0x1
+
+	bgJobStateTransferred  = 6
+	bgJobStateAcknowledged = 7
+	bgJobStateCancelled    = 8
+)
+
+// How long a job can stay pending before it is flagged as Suspicious: BITS
+// gives up on jobs after 90 days by default, and legitimate downloads are
+// done much sooner.
+const bitsJobPendingThreshold = 30 * 24 * time.Hour
+
+// The hosts, by suffix, commonly used to stage payloads.
+var bitsStagingHosts = []string{
+	"pastebin.com",
+	"raw.githubusercontent.com",
+	"gist.githubusercontent.com",
+	"transfer.sh",
+	"ngrok.io",
+	"ngrok-free.app",
+	"discordapp.com",
+	"cdn.discordapp.com",
+}
+
+// The service accounts whose jobs are machine-wide.
+var serviceAccountSIDs = map[string]bool{
+	"S-1-5-18": true,
+	"S-1-5-19": true,
+	"S-1-5-20": true,
+}
+
+// comObject is a COM interface pointer, which points to the table of
+// methods of the interface.
+type comObject struct {
+	vtable *[64]uintptr
+}
+
+// call invokes a method of the interface, returning its HRESULT as an
+// error if it failed.
+func (o *comObject) call(method int, args ...uintptr) error {
+	var a [5]uintptr
+	copy(a[:], args)
+	r, _, _ := syscall.Syscall6(o.vtable[method], uintptr(len(args)+1), uintptr(unsafe.Pointer(o)), a[0], a[1], a[2], a[3], a[4])
+	if int32(r) < 0 {
+		return windows.Errno(r)
+	}
+
+	return nil
+}
+
+func (o *comObject) release() {
+	o.call(methodRelease)
+}
+
+// getString calls a method returning a string allocated by COM.
+func (o *comObject) getString(method int) string {
+	var value *uint16
+	if err := o.call(method, uintptr(unsafe.Pointer(&value))); err != nil || value == nil {
+		return ""
+	}
+	defer windows.CoTaskMemFree(unsafe.Pointer(value))
+
+	return windows.UTF16PtrToString(value)
+}
+
+// next returns the next element of an enumerator, or nil at its end.
+func (o *comObject) next() *comObject {
+	var element *comObject
+	var fetched uint32
+	if err := o.call(methodNext, 1, uintptr(unsafe.Pointer(&element)), uintptr(unsafe.Pointer(&fetched))); err != nil || fetched == 0 {
+		return nil
+	}
+
+	return element
+}
+
+// bitsJob is what we read of a BITS job.
+type bitsJob struct {
+	id          string
+	name        string
+	owner       string
+	state       uint32
+	created     time.Time
+	modified    time.Time
+	remoteNames []string
+	localNames  []string
+	program     string
+	parameters  string
+}
+
+// readBitsJob reads the properties of a job.
+func readBitsJob(job *comObject) bitsJob {
+	var result bitsJob
+
+	var id windows.GUID
+	if job.call(methodGetID, uintptr(unsafe.Pointer(&id))) == nil {
+		result.id = id.String()
+	}
+	result.name = job.getString(methodGetDisplayName)
+	result.owner = job.getString(methodGetOwner)
+	job.call(methodGetState, uintptr(unsafe.Pointer(&result.state)))
+
+	var times struct {
+		creation, modification, transferCompletion windows.Filetime
+	}
+	if job.call(methodGetTimes, uintptr(unsafe.Pointer(&times))) == nil {
+		result.created = time.Unix(0, times.creation.Nanoseconds())
+		result.modified = time.Unix(0, times.modification.Nanoseconds())
+	}
+
+	var files *comObject
+	if job.call(methodEnumFiles, uintptr(unsafe.Pointer(&files))) == nil {
+		for file := files.next(); file != nil; file = files.next() {
+			result.remoteNames = append(result.remoteNames, file.getString(methodGetRemoteName))
+			result.localNames = append(result.localNames, file.getString(methodGetLocalName))
+			file.release()
+		}
+		files.release()
+	}
+
+	// The notification command was added with IBackgroundCopyJob2.
+	var job2 *comObject
+	if job.call(methodQueryInterface, uintptr(unsafe.Pointer(&iidBackgroundCopyJob2)), uintptr(unsafe.Pointer(&job2))) == nil {
+		var program, parameters *uint16
+		if job2.call(methodGetNotifyCmdLine, uintptr(unsafe.Pointer(&program)), uintptr(unsafe.Pointer(&parameters))) == nil {
+			if program != nil {
+				result.program = windows.UTF16PtrToString(program)
+				windows.CoTaskMemFree(unsafe.Pointer(program))
+			}
+			if parameters != nil {
+				result.parameters = windows.UTF16PtrToString(parameters)
+				windows.CoTaskMemFree(unsafe.Pointer(parameters))
+			}
+		}
+		job2.release()
+	}
+
+	return result
+}
+
+// suspiciousBitsURL checks whether a job downloads from where payloads are
+// typically staged: a bare IP address, plain HTTP on an unusual port, or a
+// paste or file sharing service.
+func suspiciousBitsURL(remoteName string) bool {
+	parsed, err := url.Parse(remoteName)
+	if err != nil || parsed.Host == "" {
+		return false
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	if net.ParseIP(host) != nil {
+		return true
+	}
+	if port := parsed.Port(); port != "" && port != "80" && port != "443" {
+		return true
+	}
+	for _, stagingHost := range bitsStagingHosts {
+		if host == stagingHost || strings.HasSuffix(host, "."+stagingHost) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// listBitsJobs returns the jobs of all users, or only those of the current
+// user with UserScopeOnly or without the rights to see the others.
+func listBitsJobs(opts Options) (jobs []bitsJob, err error) {
+	// COM calls are bound to the thread COM was initialized on.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	// S_FALSE means COM was already initialized on the thread.
+	if err := windows.CoInitializeEx(0, windows.COINIT_MULTITHREADED); err == nil || err == windows.Errno(1) {
+		defer windows.CoUninitialize()
+	}
+
+	var manager *comObject
+	r, _, _ := procCoCreateInstance.Call(
+		uintptr(unsafe.Pointer(&clsidBackgroundCopyManager)),
+		0,
+		windows.CLSCTX_LOCAL_SERVER,
+		uintptr(unsafe.Pointer(&iidBackgroundCopyManager)),
+		uintptr(unsafe.Pointer(&manager)),
+	)
+	if int32(r) < 0 {
+		return nil, windows.Errno(r)
+	}
+	defer manager.release()
+
+	// Listing the jobs of other users requires administrative rights.
+	var enum *comObject
+	var flags uintptr = bgJobEnumAllUsers
+	if opts.UserScopeOnly {
+		flags = 0
+	}
+	err = manager.call(methodEnumJobs, flags, uintptr(unsafe.Pointer(&enum)))
+	if err != nil && flags != 0 {
+		err = manager.call(methodEnumJobs, 0, uintptr(unsafe.Pointer(&enum)))
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer enum.release()
+
+	for job := enum.next(); job != nil; job = enum.next() {
+		jobs = append(jobs, readBitsJob(job))
+		job.release()
+	}
+
+	return jobs, nil
+}
+
+// This function enumerates the jobs of the Background Intelligent Transfer
+// Service, which keep downloading files across reboots and can run a
+// command once they are done. Jobs with such a notification command are
+// reported with it, and flagged as NonDefault, and the others with the
+// file they download. Jobs downloading from where payloads are typically
+// staged, or pending for longer than a legitimate download would, are
+// flagged as Suspicious. Jobs can only be listed on the local system.
+func windowsGetBitsJobs(opts Options) (records []*Autorun) {
+	if scansOtherSystem(opts) {
+		return
+	}
+
+	jobs, err := listBitsJobs(opts)
+	if err != nil {
+		opts.Warn("BITS", err)
+		return
+	}
+
+	for _, job := range jobs {
+		var newAutorun *Autorun
+		if job.program != "" {
+			// We pass the command to a function to return an Autorun.
+			newAutorun = stringToAutorun(opts, "bits_job", "BITS", job.program, false, job.name)
+			newAutorun.Arguments = job.parameters
+			newAutorun.LaunchString = strings.TrimSpace(fmt.Sprintf("%s %s", job.program, job.parameters))
+			newAutorun.Trigger = "job_complete"
+			newAutorun.NonDefault = true
+		} else {
+			var localName string
+			if len(job.localNames) > 0 {
+				localName = job.localNames[0]
+			}
+			newAutorun = stringToAutorun(opts, "bits_job", "BITS", localName, false, job.name)
+			newAutorun.LaunchString = strings.Join(job.remoteNames, ", ")
+		}
+		newAutorun.RawName = job.id
+		newAutorun.Source = Source{Kind: "bits_job", Job: job.id}
+		newAutorun.LastModified = job.modified
+		if !serviceAccountSIDs[job.owner] {
+			newAutorun.Scope = "user"
+		}
+		if opts.ResolveUsers {
+			if sid, err := windows.StringToSid(job.owner); err == nil {
+				newAutorun.User = sidUserName(sid)
+			}
+		}
+
+		pending := job.state != bgJobStateTransferred && job.state != bgJobStateAcknowledged && job.state != bgJobStateCancelled
+		newAutorun.Suspicious = pending && !job.created.IsZero() && time.Since(job.created) > bitsJobPendingThreshold
+		for _, remoteName := range job.remoteNames {
+			if suspiciousBitsURL(remoteName) {
+				newAutorun.Suspicious = true
+			}
+		}
+
+		// Add the new autorun to the records.
+		records = append(records, newAutorun)
+	}
+
+	return
+}
@@ -0,0 +1,104 @@
+//+build windows
+
+package autoruns
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// nativeCommandToAutorun returns an Autorun for a native application run by
+// the Session Manager. These are given as a name relative to System32,
+// with the .exe extension being optional, followed by their arguments.
+func nativeCommandToAutorun(opts Options, entryType string, entryLocation string, command string, entry string) *Autorun {
+	if opts.QuickScan {
+		return stringToAutorun(opts, entryType, entryLocation, command, false, entry)
+	}
+
+	fields := strings.Fields(command)
+	image := fields[0]
+	if filepath.Ext(image) == "" {
+		image += ".exe"
+	}
+
+	newAutorun := stringToAutorun(opts, entryType, entryLocation, systemDLLPath(image), false, entry)
+	newAutorun.Arguments = strings.Join(fields[1:], " ")
+	newAutorun.LaunchString = command
+
+	return newAutorun
+}
+
+// This function reads the programs run during the boot and setup phases
+// through BootVerificationProgram, Setup\CmdLine and the SetupExecute
+// native applications. These are normally absent or empty.
+func windowsGetBootPrograms(opts Options) (records []*Autorun) {
+	var reg registry.Key = registry.LOCAL_MACHINE
+
+	values := []struct {
+		entryType string
+		keyName   string
+		valueName string
+	}{
+		{"boot_verification", "System\\CurrentControlSet\\Control\\BootVerificationProgram", "ImagePath"},
+		{"setup_cmdline", "System\\Setup", "CmdLine"},
+	}
+
+	for _, value := range values {
+		// Open registry key, which is normally absent.
+		key, err := openOptionalKey(opts, reg, value.keyName)
+		if err != nil {
+			continue
+		}
+
+		command, _, err := key.GetStringValue(value.valueName)
+		key.Close()
+		if err != nil || command == "" {
+			continue
+		}
+
+		imageLocation := fmt.Sprintf("%s\\%s", registryToString(reg), value.keyName)
+
+		// We pass the value string to a function to return an Autorun.
+		newAutorun := stringToAutorun(opts, value.entryType, imageLocation, command, true, value.valueName)
+		newAutorun.RawName = value.valueName
+
+		// Add the new autorun to the records.
+		records = append(records, newAutorun)
+	}
+
+	var sessionManagerKey string = "System\\CurrentControlSet\\Control\\Session Manager"
+
+	// Open registry key.
+	key, err := openKey(opts, reg, sessionManagerKey)
+	if err != nil {
+		return
+	}
+
+	// SetupExecute holds one command per string.
+	commands, _, err := key.GetStringsValue("SetupExecute")
+	key.Close()
+	if err != nil {
+		return
+	}
+
+	imageLocation := fmt.Sprintf("%s\\%s", registryToString(reg), sessionManagerKey)
+	for _, command := range commands {
+		command = strings.TrimSpace(command)
+		if command == "" {
+			continue
+		}
+
+		// We pass the command to a function to return an Autorun.
+		newAutorun := nativeCommandToAutorun(opts, "setup_execute", imageLocation, command, "SetupExecute")
+		newAutorun.RawName = "SetupExecute"
+		newAutorun.NonDefault = true
+
+		// Add the new autorun to the records.
+		records = append(records, newAutorun)
+	}
+
+	return
+}
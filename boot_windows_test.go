@@ -0,0 +1,31 @@
+//+build windows
+
+package autoruns
+
+import "testing"
+
+func TestWindowsGetBootPrograms(t *testing.T) {
+	sessionManagerKey := `LOCAL_MACHINE\System\CurrentControlSet\Control\Session Manager`
+
+	// BootVerificationProgram and Setup are normally absent, which is not
+	// an error.
+	opts := Options{state: newScanState(), fs: newFakeFileSystem(nil)}
+	opts.registry = fakeRegistry{sessionManagerKey: {"BootExecute": []string{"autocheck autochk *"}}}
+	if records := windowsGetBootPrograms(opts); len(records) != 0 {
+		t.Errorf("got %d records, want none", len(records))
+	}
+	if warnings := opts.state.warnings.list(); len(warnings) != 0 {
+		t.Errorf("got warnings: %v", warnings)
+	}
+
+	opts = Options{state: newScanState(), fs: newFakeFileSystem(nil)}
+	opts.registry = fakeRegistry{
+		sessionManagerKey: {},
+		`LOCAL_MACHINE\System\CurrentControlSet\Control\BootVerificationProgram`: {"ImagePath": `C:\Tools\verify.exe`},
+		`LOCAL_MACHINE\System\Setup`: {"CmdLine": ""},
+	}
+	records := windowsGetBootPrograms(opts)
+	if len(records) != 1 || records[0].Type != "boot_verification" || records[0].RawName != "ImagePath" {
+		t.Errorf("got %v, want the BootVerificationProgram record", records)
+	}
+}
@@ -0,0 +1,35 @@
+//+build windows
+
+package autoruns
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// clsidServer returns the server registered for a CLSID, preferring the
+// in-process DLL over a local server executable, along with the location it
+// was read from. CLASSES_ROOT merges the per-user and machine registrations
+// the same way COM does.
+func clsidServer(opts Options, clsid string) (server string, location string, err error) {
+	var reg registry.Key = registry.CLASSES_ROOT
+
+	for _, serverKey := range []string{"InprocServer32", "LocalServer32"} {
+		keyPath := fmt.Sprintf("CLSID\\%s\\%s", clsid, serverKey)
+		key, err := registryFor(opts).OpenKey(reg, keyPath)
+		if err != nil {
+			continue
+		}
+
+		// The server is the default value of the key.
+		server, _, err := key.GetStringValue("")
+		key.Close()
+		if err == nil && server != "" {
+			return server, fmt.Sprintf("%s\\%s", registryToString(reg), keyPath), nil
+		}
+	}
+
+	return "", "", errors.New("no server registered for " + clsid)
+}
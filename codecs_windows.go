@@ -0,0 +1,89 @@
+//+build windows
+
+package autoruns
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// The codecs and drivers Windows registers in Drivers32 by default, by
+// file name.
+var defaultMediaCodecs = map[string]bool{
+	"imaadp32.acm": true,
+	"l3codeca.acm": true,
+	"msadp32.acm":  true,
+	"msg711.acm":   true,
+	"msgsm32.acm":  true,
+	"iyuv_32.dll":  true,
+	"msrle32.dll":  true,
+	"msvidc32.dll": true,
+	"msyuv.dll":    true,
+	"tsbyuv.dll":   true,
+	"midimap.dll":  true,
+	"msacm32.drv":  true,
+	"wdmaud.drv":   true,
+}
+
+// This function enumerates the audio and video codecs and drivers listed
+// in Drivers32, such as the msacm.* and vidc.* values, which are loaded
+// into every process playing or recording media. Each value names a DLL,
+// resolved relative to System32, or to SysWOW64 for the 32-bit view.
+func windowsGetMediaCodecs(opts Options) (records []*Autorun) {
+	var reg registry.Key = registry.LOCAL_MACHINE
+	drivers32Keys := []struct {
+		keyName   string
+		systemDir string
+	}{
+		{"Software\\Microsoft\\Windows NT\\CurrentVersion\\Drivers32", "System32"},
+		{"Software\\Wow6432Node\\Microsoft\\Windows NT\\CurrentVersion\\Drivers32", "SysWOW64"},
+	}
+
+	for _, drivers32 := range drivers32Keys {
+		// Open registry key.
+		key, err := openKey(opts, reg, drivers32.keyName)
+		if err != nil {
+			continue
+		}
+
+		// Enumerate values.
+		names, err := key.ReadValueNames(0)
+		if err != nil {
+			key.Close()
+			continue
+		}
+
+		imageLocation := fmt.Sprintf("%s\\%s", registryToString(reg), drivers32.keyName)
+		for _, name := range names {
+			dll, _, err := key.GetStringValue(name)
+			dll = strings.TrimSpace(dll)
+			if err != nil || dll == "" {
+				continue
+			}
+
+			imagePath := dll
+			if expanded, err := registry.ExpandString(imagePath); err == nil {
+				imagePath = expanded
+			}
+			if !filepath.IsAbs(imagePath) {
+				imagePath = filepath.Join(os.Getenv("SystemRoot"), drivers32.systemDir, imagePath)
+			}
+
+			// We pass the resolved DLL path to a function to return an Autorun.
+			newAutorun := stringToAutorun(opts, "media_codec", imageLocation, imagePath, false, name)
+			newAutorun.RawName = name
+			newAutorun.LaunchString = dll
+			newAutorun.NonDefault = !defaultMediaCodecs[strings.ToLower(filepath.Base(dll))]
+
+			// Add the new autorun to the records.
+			records = append(records, newAutorun)
+		}
+		key.Close()
+	}
+
+	return
+}
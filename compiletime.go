@@ -0,0 +1,66 @@
+package autoruns
+
+import (
+	"bytes"
+	"debug/pe"
+	"encoding/binary"
+	"time"
+)
+
+// peHeaderSampleSize is how much of the start of an image is kept to read
+// its PE headers from, which virtually always lie within it.
+const peHeaderSampleSize = 4096
+
+// The TimeDateStamp Delphi used to write into every binary it linked.
+const delphiTimeDateStamp = 0x2a425e19
+
+// peHeaderSampler is an io.Writer keeping the first peHeaderSampleSize
+// bytes written to it, so that the headers of an image can be read while
+// it is hashed.
+type peHeaderSampler struct {
+	header []byte
+}
+
+func (s *peHeaderSampler) Write(p []byte) (int, error) {
+	if remaining := peHeaderSampleSize - len(s.header); remaining > 0 {
+		if len(p) < remaining {
+			remaining = len(p)
+		}
+		s.header = append(s.header, p[:remaining]...)
+	}
+
+	return len(p), nil
+}
+
+// compileTime returns when the sampled image was linked according to the
+// TimeDateStamp of its file header. Stamps which are clearly not a date are
+// left zero: those which were zeroed or set to a placeholder, and the
+// hashes reproducible builds write instead, which mostly fall into the
+// future or before PE files existed.
+func (s *peHeaderSampler) compileTime() time.Time {
+	header := s.header
+	if len(header) < 0x40 || header[0] != 'M' || header[1] != 'Z' {
+		return time.Time{}
+	}
+
+	peOffset := int(binary.LittleEndian.Uint32(header[0x3c:]))
+	if peOffset < 0 || peOffset+4 > len(header) || !bytes.Equal(header[peOffset:peOffset+4], []byte("PE\x00\x00")) {
+		return time.Time{}
+	}
+
+	var fileHeader pe.FileHeader
+	if err := binary.Read(bytes.NewReader(header[peOffset+4:]), binary.LittleEndian, &fileHeader); err != nil {
+		return time.Time{}
+	}
+
+	stamp := fileHeader.TimeDateStamp
+	if stamp == 0 || stamp == 0xffffffff || stamp == delphiTimeDateStamp {
+		return time.Time{}
+	}
+	compiled := time.Unix(int64(stamp), 0).UTC()
+	if compiled.Year() < 1992 || compiled.After(time.Now().Add(24*time.Hour)) {
+		return time.Time{}
+	}
+
+	return compiled
+}
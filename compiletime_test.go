@@ -0,0 +1,89 @@
+package autoruns
+
+import (
+	"bytes"
+	"debug/pe"
+	"encoding/binary"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// peImage returns the headers of a PE image linked at the given
+// TimeDateStamp, followed by some padding.
+func peImage(stamp uint32) []byte {
+	var image bytes.Buffer
+	dosHeader := make([]byte, 0x80)
+	copy(dosHeader, "MZ")
+	binary.LittleEndian.PutUint32(dosHeader[0x3c:], 0x80)
+	image.Write(dosHeader)
+	image.WriteString("PE\x00\x00")
+	binary.Write(&image, binary.LittleEndian, pe.FileHeader{
+		Machine:          pe.IMAGE_FILE_MACHINE_AMD64,
+		NumberOfSections: 1,
+		TimeDateStamp:    stamp,
+	})
+	image.Write(make([]byte, 8192))
+
+	return image.Bytes()
+}
+
+// sampleCompileTime writes data to a peHeaderSampler in small chunks, the
+// way hashFile would with a small buffer, and returns the compile time.
+func sampleCompileTime(data []byte) time.Time {
+	sampler := &peHeaderSampler{}
+	for len(data) > 0 {
+		n := 100
+		if n > len(data) {
+			n = len(data)
+		}
+		sampler.Write(data[:n])
+		data = data[n:]
+	}
+
+	return sampler.compileTime()
+}
+
+func TestCompileTime(t *testing.T) {
+	// 2020-02-03 04:05:06 UTC.
+	const stamp = 1580702706
+	if got, want := sampleCompileTime(peImage(stamp)), time.Unix(stamp, 0).UTC(); !got.Equal(want) {
+		t.Errorf("compileTime() = %v, want %v", got, want)
+	}
+
+	for _, test := range []struct {
+		name string
+		data []byte
+	}{
+		{"zero stamp", peImage(0)},
+		{"placeholder stamp", peImage(0xffffffff)},
+		{"Delphi stamp", peImage(delphiTimeDateStamp)},
+		{"reproducible build hash before 1992", peImage(0x1a2b3c4d)},
+		{"reproducible build hash in the future", peImage(0xf1e2d3c4)},
+		{"ELF file", append([]byte("\x7fELF"), make([]byte, 200)...)},
+		{"truncated header", peImage(stamp)[:0x90]},
+		{"empty file", nil},
+	} {
+		if got := sampleCompileTime(test.data); !got.IsZero() {
+			t.Errorf("%s: compileTime() = %v, want zero", test.name, got)
+		}
+	}
+}
+
+func TestAnalyzeImageCompileTime(t *testing.T) {
+	const stamp = 1580702706
+	imagePath := filepath.Join(t.TempDir(), "image.exe")
+	if err := ioutil.WriteFile(imagePath, peImage(stamp), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	autorun := &Autorun{ImagePath: imagePath}
+	analyzeImage(Options{HashBufferSize: 64}, autorun)
+	if want := time.Unix(stamp, 0).UTC(); !autorun.CompileTime.Equal(want) {
+		t.Errorf("CompileTime = %v, want %v", autorun.CompileTime, want)
+	}
+	if autorun.SHA256 == "" {
+		t.Error("image was not hashed")
+	}
+}
@@ -0,0 +1,79 @@
+//+build windows
+
+package autoruns
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// The prefixes of the environment variables configuring a profiler for the
+// .NET Framework and for .NET Core respectively.
+var corProfilerPrefixes = []string{
+	"COR",
+	"CORECLR",
+}
+
+// This function checks the machine and user environments for a .NET
+// profiler, which is loaded into every .NET process started. The profiler
+// is the DLL given by <prefix>_PROFILER_PATH or, without it, the server of
+// the CLSID in <prefix>_PROFILER. These variables are normally absent, so
+// any profiler is reported, and flagged as Suspicious if it is not signed.
+func windowsGetCORProfiler(opts Options) (records []*Autorun) {
+	roots := []registryRoot{
+		{reg: registry.LOCAL_MACHINE},
+	}
+	roots = append(roots, userRegistryRoots(opts)...)
+
+	for _, root := range roots {
+		keyName := root.prefix + "Environment"
+		if root.reg == registry.LOCAL_MACHINE {
+			keyName = "System\\CurrentControlSet\\Control\\Session Manager\\Environment"
+		}
+
+		// Open registry key.
+		key, err := openKey(opts, root.reg, keyName)
+		if err != nil {
+			continue
+		}
+
+		imageLocation := fmt.Sprintf("%s\\%s", registryToString(root.reg), keyName)
+
+		for _, prefix := range corProfilerPrefixes {
+			clsid, _, err := key.GetStringValue(prefix + "_PROFILER")
+			if err != nil || clsid == "" {
+				continue
+			}
+
+			// Find the DLL of the profiler.
+			var profilerPath string
+			for _, suffix := range []string{"_PROFILER_PATH", "_PROFILER_PATH_64", "_PROFILER_PATH_32"} {
+				if path, _, err := key.GetStringValue(prefix + suffix); err == nil && path != "" {
+					profilerPath = path
+					break
+				}
+			}
+			if profilerPath == "" {
+				profilerPath, _, _ = clsidServer(opts, clsid)
+			}
+
+			// We pass the profiler to a function to return an Autorun.
+			newAutorun := stringToAutorun(opts, "cor_profiler", imageLocation, profilerPath, profilerPath != "", prefix+"_PROFILER")
+			newAutorun.RawName = prefix + "_PROFILER"
+			newAutorun.LaunchString = clsid
+			newAutorun.NonDefault = true
+			newAutorun.User = root.user
+			if !opts.QuickScan && newAutorun.ImagePath != "" {
+				signed, _ := verifySignature(newAutorun.ImagePath)
+				newAutorun.Suspicious = !signed
+			}
+
+			// Add the new autorun to the records.
+			records = append(records, newAutorun)
+		}
+		key.Close()
+	}
+
+	return
+}
@@ -0,0 +1,136 @@
+package autoruns
+
+import (
+	"bufio"
+	"bytes"
+	"path/filepath"
+	"strings"
+)
+
+// cronJob is a line of a crontab.
+type cronJob struct {
+	schedule string
+	user     string
+	command  string
+}
+
+// parseCrontab parses the jobs of a crontab. System crontabs, such as
+// /etc/crontab, name the user to run each job as between the schedule and
+// the command. Environment assignments and comments are skipped.
+func parseCrontab(data []byte, systemTab bool) (jobs []cronJob) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		// An environment variable such as PATH=/usr/bin.
+		if strings.Contains(fields[0], "=") {
+			continue
+		}
+
+		// The schedule is either a nickname such as @reboot or five fields.
+		scheduleFields := 5
+		if strings.HasPrefix(fields[0], "@") {
+			scheduleFields = 1
+		}
+		commandField := scheduleFields
+		if systemTab {
+			commandField++
+		}
+		if len(fields) <= commandField {
+			continue
+		}
+
+		job := cronJob{
+			schedule: strings.Join(fields[:scheduleFields], " "),
+			command:  cutFields(line, commandField),
+		}
+		if systemTab {
+			job.user = fields[scheduleFields]
+		}
+		jobs = append(jobs, job)
+	}
+
+	return
+}
+
+// cutFields removes the first n whitespace separated fields of a line,
+// preserving the spacing of the rest.
+func cutFields(line string, n int) string {
+	for i := 0; i < n; i++ {
+		line = strings.TrimLeft(line, " \t")
+		end := strings.IndexAny(line, " \t")
+		if end < 0 {
+			return ""
+		}
+		line = line[end:]
+	}
+
+	return strings.TrimSpace(line)
+}
+
+// shellCommandToAutorun returns an Autorun for a command run through the
+// shell, e.g. by cron. The executable is the first word of the command, or
+// what the quotes it starts with enclose, and is looked up in PATH unless
+// it is a path. The arguments are kept as they are, quoting included.
+// Executables which cannot be resolved, such as relative paths or shell
+// builtins, are left empty, and the record is marked as unparsed.
+func shellCommandToAutorun(opts Options, entryType string, entryLocation string, command string, entry string) *Autorun {
+	newAutorun := &Autorun{
+		Type:         entryType,
+		Location:     entryLocation,
+		Entry:        entry,
+		LaunchString: command,
+	}
+
+	command = strings.TrimSpace(command)
+	if command == "" {
+		newAutorun.unparsed = true
+		return newAutorun
+	}
+
+	var executable, arguments string
+	if quote := command[0]; quote == '"' || quote == '\'' {
+		end := strings.IndexByte(command[1:], quote)
+		if end < 0 {
+			newAutorun.unparsed = true
+			return newAutorun
+		}
+		executable = command[1 : end+1]
+		arguments = strings.TrimSpace(command[end+2:])
+	} else {
+		executable = strings.Fields(command)[0]
+		arguments = cutFields(command, 1)
+	}
+	newAutorun.Arguments = arguments
+
+	// A quick scan does not look anything up, and reports the executable
+	// as given.
+	if !opts.QuickScan {
+		switch {
+		case filepath.IsAbs(executable):
+		case strings.Contains(executable, "/"):
+			// Relative to a working directory we do not know.
+			executable = ""
+		default:
+			path, err := fileSystemFor(opts).LookPath(executable)
+			if err != nil {
+				path = ""
+			}
+			executable = path
+		}
+	}
+	if executable == "" {
+		opts.debugf("%s: could not resolve the executable of %q", opts.category, command)
+		newAutorun.unparsed = true
+		return newAutorun
+	}
+
+	newAutorun.ImagePath = executable
+	newAutorun.ImageName = filepath.Base(executable)
+
+	return newAutorun
+}
@@ -0,0 +1,136 @@
+//+build darwin
+
+package autoruns
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// The folders of the per-user crontabs and of the at spool.
+var (
+	cronTabsFolder = "/usr/lib/cron/tabs"
+	atJobsFolder   = "/usr/lib/cron/jobs"
+)
+
+// cronJobsToAutoruns reads a crontab and returns an Autorun for each job.
+// If user is set, the crontab belongs to that user, otherwise it is a
+// system crontab naming the user of each job.
+func cronJobsToAutoruns(opts Options, tabPath string, user string) (records []*Autorun) {
+	data, err := readFile(fileSystemFor(opts), tabPath)
+	if os.IsNotExist(err) {
+		return
+	} else if err != nil {
+		opts.Warn(tabPath, err)
+		return
+	}
+
+	for _, job := range parseCrontab(data, user == "") {
+		newAutorun := shellCommandToAutorun(opts, "cron", tabPath, job.command, job.command)
+		newAutorun.RawName = filepath.Base(tabPath)
+		newAutorun.Trigger = job.schedule
+		if user != "" {
+			newAutorun.Scope = "user"
+		}
+		if opts.ResolveUsers {
+			newAutorun.User = user
+			if user == "" {
+				newAutorun.User = job.user
+			}
+		}
+
+		records = append(records, newAutorun)
+	}
+
+	return
+}
+
+// This function enumerates the jobs of the system crontab and of the
+// crontabs of every user.
+func darwinGetCron(opts Options) (records []*Autorun) {
+	records = cronJobsToAutoruns(opts, "/etc/crontab", "")
+
+	// The per-user crontabs are named after their user.
+	tabs, err := fileSystemFor(opts).ReadDir(cronTabsFolder)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			opts.Warn(cronTabsFolder, err)
+		}
+		return
+	}
+	for _, tab := range tabs {
+		if tab.IsDir() {
+			continue
+		}
+		records = append(records, cronJobsToAutoruns(opts, filepath.Join(cronTabsFolder, tab.Name()), tab.Name())...)
+	}
+
+	return
+}
+
+// This function enumerates the scripts run by periodic, each of which
+// runs daily, weekly or monthly.
+func darwinGetPeriodic(opts Options) (records []*Autorun) {
+	for _, interval := range []string{"daily", "weekly", "monthly"} {
+		folder := filepath.Join("/etc/periodic", interval)
+		scripts, err := fileSystemFor(opts).ReadDir(folder)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				opts.Warn(folder, err)
+			}
+			continue
+		}
+
+		for _, script := range scripts {
+			if script.IsDir() {
+				continue
+			}
+
+			scriptPath := filepath.Join(folder, script.Name())
+			records = append(records, &Autorun{
+				Type:         "periodic",
+				Location:     folder,
+				ImagePath:    scriptPath,
+				ImageName:    script.Name(),
+				Entry:        script.Name(),
+				RawName:      script.Name(),
+				LaunchString: scriptPath,
+				Trigger:      interval,
+			})
+		}
+	}
+
+	return
+}
+
+// This function enumerates the jobs waiting in the spool of at. The jobs
+// are shell scripts, which are reported themselves.
+func darwinGetAtJobs(opts Options) (records []*Autorun) {
+	jobs, err := fileSystemFor(opts).ReadDir(atJobsFolder)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			opts.Warn(atJobsFolder, err)
+		}
+		return
+	}
+
+	for _, job := range jobs {
+		// The spool also holds the .SEQ counter.
+		if job.IsDir() || job.Name()[0] == '.' {
+			continue
+		}
+
+		jobPath := filepath.Join(atJobsFolder, job.Name())
+		records = append(records, &Autorun{
+			Type:         "at_job",
+			Location:     atJobsFolder,
+			ImagePath:    jobPath,
+			ImageName:    job.Name(),
+			Entry:        job.Name(),
+			RawName:      job.Name(),
+			LaunchString: jobPath,
+		})
+	}
+
+	return
+}
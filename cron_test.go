@@ -0,0 +1,88 @@
+package autoruns
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCrontab(t *testing.T) {
+	data := []byte(`# m h dom mon dow user command
+SHELL=/bin/sh
+PATH=/usr/bin:/bin
+*/5 * * * * root  /usr/local/bin/backup --all   --quiet
+@reboot www-data /opt/app/start.sh
+
+1 2 3 4
+`)
+
+	want := []cronJob{
+		{schedule: "*/5 * * * *", user: "root", command: "/usr/local/bin/backup --all   --quiet"},
+		{schedule: "@reboot", user: "www-data", command: "/opt/app/start.sh"},
+	}
+	if got := parseCrontab(data, true); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseCrontab(system) = %+v, want %+v", got, want)
+	}
+
+	want = []cronJob{{schedule: "@hourly", command: "backup.sh -v"}}
+	if got := parseCrontab([]byte("@hourly backup.sh -v\n"), false); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseCrontab(user) = %+v, want %+v", got, want)
+	}
+}
+
+func TestShellCommandToAutorunLooksUpPath(t *testing.T) {
+	fsys := newFakeFileSystem(map[string]string{"/usr/share/doc/backup": "not executable"})
+	fsys.add("/usr/bin/backup", "#!/bin/sh\n", 0755)
+	fsys.path = []string{"/bin", "/usr/share/doc", "/usr/bin"}
+	opts := Options{fs: fsys}
+
+	autorun := shellCommandToAutorun(opts, "cron", "/etc/crontab", "backup --all", "backup")
+	if autorun.ImagePath != "/usr/bin/backup" || autorun.ImageName != "backup" || autorun.Arguments != "--all" {
+		t.Errorf("got ImagePath %q, ImageName %q, Arguments %q", autorun.ImagePath, autorun.ImageName, autorun.Arguments)
+	}
+	if autorun.LaunchString != "backup --all" {
+		t.Errorf("LaunchString = %q, want the command", autorun.LaunchString)
+	}
+
+	// Paths are used as they are.
+	autorun = shellCommandToAutorun(opts, "cron", "/etc/crontab", "/opt/backup", "backup")
+	if autorun.ImagePath != "/opt/backup" {
+		t.Errorf("ImagePath = %q, want /opt/backup", autorun.ImagePath)
+	}
+}
+
+func TestShellCommandToAutorunQuoting(t *testing.T) {
+	fsys := newFakeFileSystem(nil)
+	fsys.add("/opt/my app/run", "#!/bin/sh\n", 0755)
+	opts := Options{fs: fsys}
+
+	autorun := shellCommandToAutorun(opts, "cron", "/etc/crontab", `/usr/bin/backup --name "two  words" 'a b' > /dev/null`, "backup")
+	if autorun.ImagePath != "/usr/bin/backup" || autorun.Arguments != `--name "two  words" 'a b' > /dev/null` {
+		t.Errorf("got ImagePath %q, Arguments %q", autorun.ImagePath, autorun.Arguments)
+	}
+
+	autorun = shellCommandToAutorun(opts, "cron", "/etc/crontab", `"/opt/my app/run" -x`, "run")
+	if autorun.ImagePath != "/opt/my app/run" || autorun.ImageName != "run" || autorun.Arguments != "-x" {
+		t.Errorf("got ImagePath %q, ImageName %q, Arguments %q", autorun.ImagePath, autorun.ImageName, autorun.Arguments)
+	}
+}
+
+func TestShellCommandToAutorunUnresolved(t *testing.T) {
+	opts := Options{fs: newFakeFileSystem(nil)}
+
+	for _, command := range []string{
+		"missing-tool --flag",
+		"./relative.sh",
+		"bin/relative.sh",
+		"cd /tmp && ./x",
+		`"/opt/unclosed -x`,
+		"   ",
+	} {
+		autorun := shellCommandToAutorun(opts, "cron", "/etc/crontab", command, "job")
+		if !autorun.unparsed || autorun.ImagePath != "" || autorun.ImageName != "" {
+			t.Errorf("%q: got ImagePath %q, ImageName %q, unparsed %v", command, autorun.ImagePath, autorun.ImageName, autorun.unparsed)
+		}
+		if autorun.LaunchString != command {
+			t.Errorf("%q: LaunchString = %q", command, autorun.LaunchString)
+		}
+	}
+}
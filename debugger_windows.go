@@ -0,0 +1,60 @@
+//+build windows
+
+package autoruns
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// The post-mortem debuggers configured by Windows itself or by Visual Studio.
+var defaultPostMortemDebuggers = []string{
+	"drwtsn32",
+	"vsjitdebugger",
+}
+
+// This function reads the post-mortem debugger, which is launched whenever
+// a process crashes with an unhandled exception.
+func windowsGetAeDebug(opts Options) (records []*Autorun) {
+	var reg registry.Key = registry.LOCAL_MACHINE
+
+	keyNames := []string{
+		"Software\\Microsoft\\Windows NT\\CurrentVersion\\AeDebug",
+		"Software\\Wow6432Node\\Microsoft\\Windows NT\\CurrentVersion\\AeDebug",
+	}
+
+	for _, keyName := range keyNames {
+		// Open registry key.
+		key, err := openKey(opts, reg, keyName)
+		if err != nil {
+			continue
+		}
+
+		// Check if there is a Debugger value.
+		debugger, _, err := key.GetStringValue("Debugger")
+		key.Close()
+		if err != nil || debugger == "" {
+			continue
+		}
+
+		imageLocation := fmt.Sprintf("%s\\%s", registryToString(reg), keyName)
+
+		// We pass the value string to a function to return an Autorun.
+		newAutorun := stringToAutorun(opts, "aedebug", imageLocation, debugger, true, "Debugger")
+		newAutorun.RawName = "Debugger"
+		newAutorun.NonDefault = true
+		for _, name := range defaultPostMortemDebuggers {
+			if strings.Contains(strings.ToLower(debugger), name) {
+				newAutorun.NonDefault = false
+				break
+			}
+		}
+
+		// Add the new autorun to the records.
+		records = append(records, newAutorun)
+	}
+
+	return
+}
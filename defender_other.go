@@ -0,0 +1,8 @@
+//+build !windows
+
+package autoruns
+
+// Defender exclusions only exist on Windows.
+func markDefenderExclusions(opts Options, records []*Autorun) {}
+
+func markDefenderTamper(records []*Autorun) {}
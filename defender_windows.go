@@ -0,0 +1,213 @@
+//+build windows
+
+package autoruns
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// Defender exclusions are configured locally or through group policy.
+var defenderExclusionKeys = []string{
+	"Software\\Microsoft\\Windows Defender\\Exclusions",
+	"Software\\Policies\\Microsoft\\Windows Defender\\Exclusions",
+}
+
+// defenderExclusion is a single entry of one of the exclusion lists.
+type defenderExclusion struct {
+	kind     string // Paths, Extensions or Processes.
+	value    string
+	location string
+}
+
+// readDefenderExclusions reads all exclusion lists. The exclusions are
+// stored as value names.
+func readDefenderExclusions(opts Options) (exclusions []defenderExclusion) {
+	var reg registry.Key = registry.LOCAL_MACHINE
+
+	for _, exclusionsKey := range defenderExclusionKeys {
+		for _, kind := range []string{"Paths", "Extensions", "Processes"} {
+			keyName := fmt.Sprintf("%s\\%s", exclusionsKey, kind)
+			key, err := openKey(opts, reg, keyName)
+			if err != nil {
+				continue
+			}
+
+			names, err := key.ReadValueNames(0)
+			key.Close()
+			if err != nil {
+				continue
+			}
+
+			for _, name := range names {
+				exclusions = append(exclusions, defenderExclusion{
+					kind:     kind,
+					value:    name,
+					location: fmt.Sprintf("%s\\%s", registryToString(reg), keyName),
+				})
+			}
+		}
+	}
+
+	return
+}
+
+// matches checks whether the exclusion applies to the given image.
+func (e defenderExclusion) matches(imagePath string) bool {
+	value := e.value
+	if expanded, err := registry.ExpandString(value); err == nil {
+		value = expanded
+	}
+	value = strings.ToLower(value)
+	imagePath = strings.ToLower(imagePath)
+
+	switch e.kind {
+	case "Extensions":
+		return strings.TrimPrefix(filepath.Ext(imagePath), ".") == strings.TrimPrefix(value, ".")
+	case "Processes":
+		// Processes are excluded either by name or by full path.
+		if !strings.ContainsAny(value, "\\/") {
+			return filepath.Base(imagePath) == value
+		}
+		return imagePath == filepath.Clean(value)
+	default:
+		if strings.ContainsAny(value, "*?") {
+			matched, _ := filepath.Match(value, imagePath)
+			return matched
+		}
+		value = strings.TrimSuffix(filepath.Clean(value), "\\")
+		return imagePath == value || strings.HasPrefix(imagePath, value+"\\")
+	}
+}
+
+// This function reports the Defender exclusions. They are not persistence
+// themselves, but attackers exclude the location of their payloads.
+func windowsGetDefenderExclusions(opts Options) (records []*Autorun) {
+	for _, exclusion := range readDefenderExclusions(opts) {
+		records = append(records, &Autorun{
+			Type:         "defender_exclusion",
+			Location:     exclusion.location,
+			Entry:        exclusion.value,
+			RawName:      exclusion.value,
+			LaunchString: exclusion.value,
+		})
+	}
+
+	return
+}
+
+// markDefenderExclusions sets ExcludedFromDefender on the records whose
+// image is covered by an exclusion.
+func markDefenderExclusions(opts Options, records []*Autorun) {
+	// The exclusions have already been read, and their warnings reported,
+	// by their own category.
+	opts.state = nil
+
+	exclusions := readDefenderExclusions(opts)
+	if len(exclusions) == 0 {
+		return
+	}
+
+	for _, record := range records {
+		if record.Type == "defender_exclusion" || record.ImagePath == "" {
+			continue
+		}
+		for _, exclusion := range exclusions {
+			if exclusion.matches(record.ImagePath) {
+				record.ExcludedFromDefender = true
+				break
+			}
+		}
+	}
+}
+
+// The policy values which turn off Defender, or its real-time protection,
+// relative to the Defender policy key.
+var defenderTamperValues = []struct {
+	keyName   string
+	valueName string
+}{
+	{"", "DisableAntiSpyware"},
+	{"", "DisableAntiVirus"},
+	{"Real-Time Protection", "DisableRealtimeMonitoring"},
+	{"Real-Time Protection", "DisableBehaviorMonitoring"},
+	{"Real-Time Protection", "DisableOnAccessProtection"},
+}
+
+// This function reports the Defender policies which turn off its
+// protection. They are not persistence themselves, but almost always come
+// along with its installation, so their records are counted apart in the
+// Summary. The LastModified of the records tells when the policy was set.
+func windowsGetDefenderTamper(opts Options) (records []*Autorun) {
+	var reg registry.Key = registry.LOCAL_MACHINE
+	var policyKey string = "Software\\Policies\\Microsoft\\Windows Defender"
+
+	for _, tamper := range defenderTamperValues {
+		keyName := policyKey
+		if tamper.keyName != "" {
+			keyName = fmt.Sprintf("%s\\%s", policyKey, tamper.keyName)
+		}
+
+		// Open registry key.
+		key, err := openKey(opts, reg, keyName)
+		if err != nil {
+			continue
+		}
+
+		// Only the policies which are set are reported.
+		value, _, err := key.GetIntegerValue(tamper.valueName)
+		key.Close()
+		if err != nil || value == 0 {
+			continue
+		}
+
+		records = append(records, &Autorun{
+			Type:         "defender_tamper",
+			Location:     fmt.Sprintf("%s\\%s", registryToString(reg), keyName),
+			Entry:        tamper.valueName,
+			RawName:      tamper.valueName,
+			LaunchString: fmt.Sprintf("%s=%d", tamper.valueName, value),
+			Suspicious:   true,
+		})
+	}
+
+	return
+}
+
+// defenderTamperWindow is how close to a Defender tamper policy a record
+// has to have been modified to be considered installed along with it.
+const defenderTamperWindow = 24 * time.Hour
+
+// markDefenderTamper sets nearDefenderTamper on the records modified within
+// defenderTamperWindow of a Defender tamper policy.
+func markDefenderTamper(records []*Autorun) {
+	var tamperedAt []time.Time
+	for _, record := range records {
+		if record.Type == "defender_tamper" && !record.LastModified.IsZero() {
+			tamperedAt = append(tamperedAt, record.LastModified)
+		}
+	}
+	if len(tamperedAt) == 0 {
+		return
+	}
+
+	for _, record := range records {
+		if contextTypes[record.Type] || record.LastModified.IsZero() {
+			continue
+		}
+		for _, at := range tamperedAt {
+			distance := record.LastModified.Sub(at)
+			if distance < 0 {
+				distance = -distance
+			}
+			if distance <= defenderTamperWindow {
+				record.nearDefenderTamper = true
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package autoruns
+
+import "math"
+
+// entropySampleSize is how much of an image its entropy is computed over.
+// Packed and encrypted payloads are high in entropy from their first bytes
+// on, so the start of a very large file tells as much as the whole of it.
+const entropySampleSize = 8 << 20
+
+// entropyCounter is an io.Writer counting the bytes written to it, up to
+// limit bytes, to compute their Shannon entropy.
+type entropyCounter struct {
+	limit  int64
+	total  int64
+	counts [256]int64
+}
+
+func (e *entropyCounter) Write(p []byte) (int, error) {
+	sample := p
+	if remaining := e.limit - e.total; int64(len(sample)) > remaining {
+		sample = sample[:remaining]
+	}
+	for _, b := range sample {
+		e.counts[b]++
+	}
+	e.total += int64(len(sample))
+
+	return len(p), nil
+}
+
+// value returns the entropy of the bytes counted, in bits per byte, from 0
+// for a single repeated byte to 8 for uniformly random data.
+func (e *entropyCounter) value() float64 {
+	if e.total == 0 {
+		return 0
+	}
+
+	var entropy float64
+	for _, count := range e.counts {
+		if count == 0 {
+			continue
+		}
+		p := float64(count) / float64(e.total)
+		entropy -= p * math.Log2(p)
+	}
+
+	return entropy
+}
@@ -0,0 +1,90 @@
+package autoruns
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+func TestEntropyCounter(t *testing.T) {
+	random := make([]byte, 1<<16)
+	rand.New(rand.NewSource(1)).Read(random)
+	// Every byte value once, uniformly distributed.
+	uniform := make([]byte, 256)
+	for i := range uniform {
+		uniform[i] = byte(i)
+	}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		min, max float64
+	}{
+		{"empty", nil, 0, 0},
+		{"repeated byte", bytes.Repeat([]byte{'A'}, 4096), 0, 0},
+		{"two bytes", bytes.Repeat([]byte("AB"), 2048), 1, 1},
+		{"text", bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog "), 100), 3, 4.5},
+		{"uniform", uniform, 8, 8},
+		{"random", random, 7.99, 8},
+	}
+	for _, test := range tests {
+		counter := &entropyCounter{limit: entropySampleSize}
+		// The data is written in chunks, as hashFile does.
+		for data := test.data; len(data) > 0; data = data[1+len(data)/2:] {
+			counter.Write(data[:1+len(data)/2])
+		}
+		if got := counter.value(); got < test.min-1e-9 || got > test.max+1e-9 || math.IsNaN(got) {
+			t.Errorf("%s: entropy %v, want between %v and %v", test.name, got, test.min, test.max)
+		}
+	}
+}
+
+func TestEntropyCounterLimit(t *testing.T) {
+	// Only the sample at the start counts.
+	counter := &entropyCounter{limit: 1024}
+	if n, _ := counter.Write(bytes.Repeat([]byte{0}, 1024)); n != 1024 {
+		t.Errorf("Write returned %d", n)
+	}
+	uniform := make([]byte, 4096)
+	for i := range uniform {
+		uniform[i] = byte(i)
+	}
+	if n, _ := counter.Write(uniform); n != len(uniform) {
+		t.Errorf("Write past the limit returned %d, want %d", n, len(uniform))
+	}
+	if got := counter.value(); got != 0 {
+		t.Errorf("entropy %v, want 0 for the sample", got)
+	}
+}
+
+func TestAnalyzeImageEntropy(t *testing.T) {
+	bin := string(filepath.Separator) + "bin"
+	random := make([]byte, 1<<16)
+	rand.New(rand.NewSource(1)).Read(random)
+	fsys := newFakeFileSystem(map[string]string{
+		filepath.Join(bin, "plain"):  string(bytes.Repeat([]byte{'A'}, 4096)),
+		filepath.Join(bin, "packed"): string(random),
+	})
+
+	plain := &Autorun{ImagePath: filepath.Join(bin, "plain")}
+	packed := &Autorun{ImagePath: filepath.Join(bin, "packed")}
+	missing := &Autorun{ImagePath: filepath.Join(bin, "missing")}
+	for _, autorun := range []*Autorun{plain, packed, missing} {
+		analyzeImage(Options{fs: fsys, ComputeEntropy: true}, autorun)
+	}
+	if plain.Entropy != 0 || packed.Entropy < 7.99 {
+		t.Errorf("got entropy %v for plain and %v for packed", plain.Entropy, packed.Entropy)
+	}
+	if missing.Entropy != 0 {
+		t.Errorf("got entropy %v for a missing file", missing.Entropy)
+	}
+
+	// Without ComputeEntropy, it is left unset.
+	packed = &Autorun{ImagePath: filepath.Join(bin, "packed")}
+	analyzeImage(Options{fs: fsys}, packed)
+	if packed.Entropy != 0 {
+		t.Errorf("got entropy %v without ComputeEntropy", packed.Entropy)
+	}
+}
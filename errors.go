@@ -0,0 +1,87 @@
+package autoruns
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ScanError describes a location a scanner could not read. The underlying
+// error can be inspected with errors.Is and errors.As, e.g. to tell missing
+// keys apart from denied access.
+type ScanError struct {
+	Category string
+	Location string
+	Err      error
+}
+
+func (e *ScanError) Error() string {
+	return fmt.Sprintf("%s: %s: %v", e.Category, e.Location, e.Err)
+}
+
+func (e *ScanError) Unwrap() error {
+	return e.Err
+}
+
+// warningList collects the warnings of a scan.
+type warningList struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+func (w *warningList) add(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.errs = append(w.errs, err)
+}
+
+func (w *warningList) list() []error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return append([]error(nil), w.errs...)
+}
+
+// ErrFileTimeout is reported for an image whose analysis took longer than
+// Options.FileTimeout.
+var ErrFileTimeout = errors.New("autoruns: timed out analyzing file")
+
+// ErrTruncated is reported for a location whose enumeration was cut short
+// by Options.MaxEntriesPerCategory or Options.MaxDepth.
+var ErrTruncated = errors.New("autoruns: enumeration truncated")
+
+// entriesExhausted checks whether a scanner has found as many records as
+// MaxEntriesPerCategory allows, in which case it reports location, where
+// it stops, as truncated.
+func (o Options) entriesExhausted(location string, records []*Autorun) bool {
+	if o.MaxEntriesPerCategory <= 0 || len(records) < o.MaxEntriesPerCategory {
+		return false
+	}
+
+	o.Warn(location, ErrTruncated)
+	return true
+}
+
+// tooDeep checks whether a folder depth levels below where a scanner
+// started is deeper than MaxDepth allows, in which case it reports the
+// folder as truncated.
+func (o Options) tooDeep(location string, depth int) bool {
+	if o.MaxDepth <= 0 || depth <= o.MaxDepth {
+		return false
+	}
+
+	o.Warn(location, ErrTruncated)
+	return true
+}
+
+// Warn records that the scanner could not read location. The error is
+// returned in Result.Warnings as a *ScanError of the scanner's category.
+// Scanners continue with the remaining locations after a warning.
+func (o Options) Warn(location string, err error) {
+	if o.state == nil {
+		return
+	}
+
+	o.state.warnings.add(&ScanError{Category: o.category, Location: location, Err: err})
+}
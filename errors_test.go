@@ -0,0 +1,45 @@
+package autoruns
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestWarnReportsScanErrors(t *testing.T) {
+	fsys := newFakeFileSystem(nil)
+	s := scanner{name: "test", fn: func(opts Options) []*Autorun {
+		if _, err := readFile(fileSystemFor(opts), "missing"); err != nil {
+			opts.Warn("missing", err)
+		}
+		return nil
+	}}
+
+	opts := Options{fs: fsys, QuickScan: true, state: newScanState()}
+	runScanner(s, opts)
+	warnings := opts.state.warnings.list()
+	if len(warnings) != 1 {
+		t.Fatalf("got warnings %v, want one", warnings)
+	}
+
+	var scanErr *ScanError
+	if !errors.As(warnings[0], &scanErr) {
+		t.Fatalf("warning %v is not a *ScanError", warnings[0])
+	}
+	if scanErr.Category != "test" || scanErr.Location != "missing" {
+		t.Errorf("got Category %q, Location %q", scanErr.Category, scanErr.Location)
+	}
+	if !errors.Is(warnings[0], os.ErrNotExist) {
+		t.Errorf("warning %v does not unwrap to os.ErrNotExist", warnings[0])
+	}
+	var pathErr *os.PathError
+	if !errors.As(warnings[0], &pathErr) || errors.Unwrap(warnings[0]) != error(pathErr) {
+		t.Errorf("warning %v does not unwrap to the error of the scanner", warnings[0])
+	}
+	if got, want := scanErr.Error(), "test: missing: "+pathErr.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+
+	// Without the state of a scan, warnings are dropped.
+	Options{}.Warn("location", os.ErrNotExist)
+}
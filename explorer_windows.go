@@ -0,0 +1,225 @@
+//+build windows
+
+package autoruns
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// shellExtensionSet holds the CLSIDs of the shell extensions reported so far
+// in a scan.
+type shellExtensionSet struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+// claimShellExtension checks whether a shell extension has not been reported
+// yet in this scan, by any of the scanners of shell extensions, and marks it
+// as reported.
+func claimShellExtension(opts Options, clsid string) bool {
+	set := opts.memo("shell_extensions", func() interface{} {
+		return &shellExtensionSet{seen: make(map[string]bool)}
+	}).(*shellExtensionSet)
+
+	set.mu.Lock()
+	defer set.mu.Unlock()
+
+	clsid = strings.ToLower(clsid)
+	if set.seen[clsid] {
+		return false
+	}
+	set.seen[clsid] = true
+
+	return true
+}
+
+// This function enumerates shell namespace extensions and icon overlay
+// handlers, whose DLLs are loaded into Explorer.
+func windowsGetNamespaceExtensions(opts Options) (records []*Autorun) {
+	regs := []registry.Key{
+		registry.LOCAL_MACHINE,
+		registry.CURRENT_USER,
+	}
+
+	// Overlay handlers are named subkeys holding the CLSID as their default
+	// value, while namespaces are subkeys named after the CLSID.
+	overlayKey := "Software\\Microsoft\\Windows\\CurrentVersion\\Explorer\\ShellIconOverlayIdentifiers"
+	namespaceKeys := []string{
+		"Software\\Microsoft\\Windows\\CurrentVersion\\Explorer\\MyComputer\\NameSpace",
+		"Software\\Microsoft\\Windows\\CurrentVersion\\Explorer\\Desktop\\NameSpace",
+	}
+
+	// A CLSID showing up under multiple namespaces is only reported once.
+	addCLSID := func(reg registry.Key, keyName string, clsid string) {
+		server, _, err := clsidServer(opts, clsid)
+		if err != nil || !claimShellExtension(opts, clsid) {
+			return
+		}
+
+		imageLocation := fmt.Sprintf("%s\\%s", registryToString(reg), keyName)
+
+		// We pass the server to a function to return an Autorun.
+		newAutorun := stringToAutorun(opts, "namespace_extension", imageLocation, server, true, clsid)
+		newAutorun.User = keyUser(opts, reg)
+
+		// Add the new autorun to the records.
+		records = append(records, newAutorun)
+	}
+
+	// We loop through HKLM and HKCU.
+	for _, reg := range regs {
+		// Open registry key.
+		key, err := openKey(opts, reg, overlayKey)
+		if err == nil {
+			names, _ := key.ReadSubKeyNames(0)
+			key.Close()
+
+			for _, name := range names {
+				subkeyPath := fmt.Sprintf("%s\\%s", overlayKey, name)
+				subkey, err := openKey(opts, reg, subkeyPath)
+				if err != nil {
+					continue
+				}
+				clsid, _, err := subkey.GetStringValue("")
+				subkey.Close()
+				if err != nil || clsid == "" {
+					continue
+				}
+
+				addCLSID(reg, subkeyPath, clsid)
+			}
+		}
+
+		for _, namespaceKey := range namespaceKeys {
+			// Open registry key.
+			key, err := openKey(opts, reg, namespaceKey)
+			if err != nil {
+				continue
+			}
+			names, _ := key.ReadSubKeyNames(0)
+			key.Close()
+
+			for _, clsid := range names {
+				addCLSID(reg, fmt.Sprintf("%s\\%s", namespaceKey, clsid), clsid)
+			}
+		}
+	}
+
+	return
+}
+
+// This function enumerates copy hook handlers, which are loaded into
+// Explorer whenever a folder is copied, moved, renamed or deleted, the
+// drag and drop, column and other handlers of folders and files, and the
+// handlers Explorer loads while creating shortcuts. Handlers already
+// reported as another shell extension are skipped.
+func windowsGetShellHandlers(opts Options) (records []*Autorun) {
+	addCLSID := func(entryType string, reg registry.Key, keyName string, rawName string, clsid string) {
+		server, _, err := clsidServer(opts, clsid)
+		if err != nil || !claimShellExtension(opts, clsid) {
+			return
+		}
+
+		imageLocation := fmt.Sprintf("%s\\%s", registryToString(reg), keyName)
+
+		// We pass the server to a function to return an Autorun.
+		newAutorun := stringToAutorun(opts, entryType, imageLocation, server, true, clsid)
+		newAutorun.RawName = rawName
+		newAutorun.User = keyUser(opts, reg)
+
+		// Add the new autorun to the records.
+		records = append(records, newAutorun)
+	}
+
+	// Copy hooks are named subkeys holding the CLSID as their default value.
+	var copyHookKey string = "Directory\\shellex\\CopyHookHandlers"
+	if key, err := openKey(opts, registry.CLASSES_ROOT, copyHookKey); err == nil {
+		names, _ := readSubKeyNames(opts, key, fmt.Sprintf("%s\\%s", registryToString(registry.CLASSES_ROOT), copyHookKey))
+		key.Close()
+
+		for _, name := range names {
+			subkeyPath := fmt.Sprintf("%s\\%s", copyHookKey, name)
+			subkey, err := openKey(opts, registry.CLASSES_ROOT, subkeyPath)
+			if err != nil {
+				continue
+			}
+			clsid, _, err := subkey.GetStringValue("")
+			subkey.Close()
+			if err != nil || clsid == "" {
+				continue
+			}
+
+			addCLSID("shell_copyhook", registry.CLASSES_ROOT, subkeyPath, "", clsid)
+		}
+	}
+
+	// The other handlers of the shell are named subkeys of their category
+	// holding the CLSID as their default value, or subkeys named after the
+	// CLSID. Every category of AllFilesystemObjects is covered.
+	handlerKeys := []string{
+		"Directory\\shellex\\DragDropHandlers",
+		"Drive\\shellex\\DragDropHandlers",
+		"Folder\\shellex\\DragDropHandlers",
+		"Folder\\shellex\\ColumnHandlers",
+	}
+	var allObjectsKey string = "AllFilesystemObjects\\shellex"
+	if key, err := openKey(opts, registry.CLASSES_ROOT, allObjectsKey); err == nil {
+		categories, _ := readSubKeyNames(opts, key, fmt.Sprintf("%s\\%s", registryToString(registry.CLASSES_ROOT), allObjectsKey))
+		key.Close()
+
+		for _, category := range categories {
+			handlerKeys = append(handlerKeys, fmt.Sprintf("%s\\%s", allObjectsKey, category))
+		}
+	}
+	for _, handlerKey := range handlerKeys {
+		if opts.entriesExhausted(fmt.Sprintf("%s\\%s", registryToString(registry.CLASSES_ROOT), handlerKey), records) {
+			return
+		}
+
+		key, err := openKey(opts, registry.CLASSES_ROOT, handlerKey)
+		if err != nil {
+			continue
+		}
+		names, _ := readSubKeyNames(opts, key, fmt.Sprintf("%s\\%s", registryToString(registry.CLASSES_ROOT), handlerKey))
+		key.Close()
+
+		for _, name := range names {
+			subkeyPath := fmt.Sprintf("%s\\%s", handlerKey, name)
+			subkey, err := openKey(opts, registry.CLASSES_ROOT, subkeyPath)
+			if err != nil {
+				continue
+			}
+			clsid, _, _ := subkey.GetStringValue("")
+			subkey.Close()
+			if !strings.HasPrefix(clsid, "{") {
+				clsid = name
+			}
+			if !strings.HasPrefix(clsid, "{") {
+				continue
+			}
+
+			addCLSID("shell_handler", registry.CLASSES_ROOT, subkeyPath, name, clsid)
+		}
+	}
+
+	// Shortcut handlers are values named after the CLSID.
+	var shortcutHandlersKey string = "Software\\Microsoft\\Windows\\CurrentVersion\\Explorer\\NewShortcutHandlers"
+	for _, reg := range []registry.Key{registry.LOCAL_MACHINE, registry.CURRENT_USER} {
+		key, err := openKey(opts, reg, shortcutHandlersKey)
+		if err != nil {
+			continue
+		}
+		names, _ := key.ReadValueNames(0)
+		key.Close()
+
+		for _, clsid := range names {
+			addCLSID("new_shortcut_handler", reg, shortcutHandlersKey, clsid, clsid)
+		}
+	}
+
+	return
+}
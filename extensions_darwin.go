@@ -0,0 +1,230 @@
+//+build darwin
+
+package autoruns
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"howett.net/plist"
+)
+
+// bundleInfo holds the keys of the Info.plist of a bundle we report.
+type bundleInfo struct {
+	Identifier string `plist:"CFBundleIdentifier"`
+	Executable string `plist:"CFBundleExecutable"`
+}
+
+// readBundle parses the Info.plist of a bundle, such as a kext. It returns
+// the path of the Info.plist along with its content.
+func readBundle(opts Options, bundlePath string) (string, bundleInfo, error) {
+	var info bundleInfo
+
+	infoPath := filepath.Join(bundlePath, "Contents", "Info.plist")
+	reader, err := fileSystemFor(opts).Open(infoPath)
+	if err != nil {
+		return infoPath, info, err
+	}
+	defer reader.Close()
+
+	err = plist.NewDecoder(reader).Decode(&info)
+	return infoPath, info, err
+}
+
+// bundleAutorun returns the record of a bundle whose executable is loaded
+// by the system. Bundles without an executable, such as kexts only holding
+// a personality, are reported with the Info.plist as their image.
+func bundleAutorun(opts Options, entryType string, bundlePath string) *Autorun {
+	infoPath, info, err := readBundle(opts, bundlePath)
+	if err != nil {
+		opts.Warn(infoPath, err)
+		return nil
+	}
+
+	imagePath := infoPath
+	if info.Executable != "" {
+		imagePath = filepath.Join(bundlePath, "Contents", "MacOS", info.Executable)
+	}
+
+	return &Autorun{
+		Type:         entryType,
+		Location:     infoPath,
+		ImagePath:    imagePath,
+		ImageName:    filepath.Base(imagePath),
+		Entry:        info.Identifier,
+		RawName:      filepath.Base(bundlePath),
+		LaunchString: imagePath,
+		NonDefault:   !strings.HasPrefix(info.Identifier, "com.apple."),
+	}
+}
+
+// This function enumerates the kernel extensions, the drivers of macOS.
+// Those not made by Apple are flagged as NonDefault.
+func darwinGetKexts(opts Options) (records []*Autorun) {
+	folders := []string{
+		"/Library/Extensions",
+		"/System/Library/Extensions",
+	}
+
+	for _, folder := range folders {
+		// Get list of files in folder.
+		filesList, err := fileSystemFor(opts).ReadDir(folder)
+		if os.IsNotExist(err) {
+			opts.debugf("%s: skipping %s, which does not exist", opts.category, folder)
+			continue
+		} else if err != nil {
+			opts.Warn(folder, err)
+			continue
+		}
+
+		for _, fileEntry := range filesList {
+			if filepath.Ext(fileEntry.Name()) != ".kext" {
+				continue
+			}
+
+			if newAutorun := bundleAutorun(opts, "kext", filepath.Join(folder, fileEntry.Name())); newAutorun != nil {
+				records = append(records, newAutorun)
+			}
+		}
+	}
+
+	return
+}
+
+// This function enumerates the system extensions, which replace kexts
+// with drivers and network or endpoint security extensions running in user
+// space. Every activated extension is copied to a directory of its own in
+// /Library/SystemExtensions.
+func darwinGetSystemExtensions(opts Options) (records []*Autorun) {
+	var folder string = "/Library/SystemExtensions"
+
+	// Get list of files in folder.
+	directories, err := fileSystemFor(opts).ReadDir(folder)
+	if os.IsNotExist(err) {
+		opts.debugf("%s: skipping %s, which does not exist", opts.category, folder)
+		return
+	} else if err != nil {
+		opts.Warn(folder, err)
+		return
+	}
+
+	for _, directory := range directories {
+		if !directory.IsDir() {
+			continue
+		}
+
+		extensionsPath := filepath.Join(folder, directory.Name())
+		filesList, err := fileSystemFor(opts).ReadDir(extensionsPath)
+		if err != nil {
+			opts.Warn(extensionsPath, err)
+			continue
+		}
+
+		for _, fileEntry := range filesList {
+			switch filepath.Ext(fileEntry.Name()) {
+			case ".systemextension", ".dext":
+			default:
+				continue
+			}
+
+			if newAutorun := bundleAutorun(opts, "system_extension", filepath.Join(extensionsPath, fileEntry.Name())); newAutorun != nil {
+				records = append(records, newAutorun)
+			}
+		}
+	}
+
+	return
+}
+
+// configProfile is an installed configuration profile, along with the
+// payloads we report.
+type configProfile struct {
+	Identifier  string `plist:"ProfileIdentifier"`
+	DisplayName string `plist:"ProfileDisplayName"`
+	Items       []struct {
+		PayloadType    string `plist:"PayloadType"`
+		PayloadContent struct {
+			LoginItems []struct {
+				Path string `plist:"Path"`
+			} `plist:"AutoLaunchedApplicationDictionary-managed"`
+		} `plist:"PayloadContent"`
+	} `plist:"ProfileItems"`
+}
+
+// This function enumerates the installed configuration profiles, which
+// can enforce settings and install payloads such as certificates, and
+// login items. The store lists the profiles by the user they are installed
+// for, or "_computerlevel" for the whole machine. Every profile is
+// reported with the types of its payloads, and every login item it
+// enforces is reported on its own.
+func darwinGetConfigProfiles(opts Options) (records []*Autorun) {
+	var storePath string = "/var/db/ConfigurationProfiles/Store/ConfigProfiles.binary"
+
+	// Open the plist file. There is no store until a profile is installed.
+	reader, err := fileSystemFor(opts).Open(storePath)
+	if os.IsNotExist(err) {
+		opts.debugf("%s: skipping %s, which does not exist", opts.category, storePath)
+		return
+	} else if err != nil {
+		opts.Warn(storePath, err)
+		return
+	}
+
+	// Parse the plist file.
+	var store map[string][]configProfile
+	err = plist.NewDecoder(reader).Decode(&store)
+	reader.Close()
+	if err != nil {
+		opts.Warn(storePath, err)
+		return
+	}
+
+	for owner, profiles := range store {
+		var user string
+		if opts.ResolveUsers && owner != "_computerlevel" {
+			user = owner
+		}
+		scope := "machine"
+		if owner != "_computerlevel" {
+			scope = "user"
+		}
+
+		for _, profile := range profiles {
+			var payloadTypes []string
+			for _, item := range profile.Items {
+				payloadTypes = append(payloadTypes, item.PayloadType)
+
+				for _, loginItem := range item.PayloadContent.LoginItems {
+					if loginItem.Path == "" {
+						continue
+					}
+					records = append(records, &Autorun{
+						Type:         "config_profile",
+						Location:     storePath,
+						ImagePath:    loginItem.Path,
+						ImageName:    filepath.Base(loginItem.Path),
+						Entry:        profile.Identifier,
+						RawName:      profile.DisplayName,
+						LaunchString: loginItem.Path,
+						Trigger:      "logon",
+						User:         user,
+						Scope:        scope,
+					})
+				}
+			}
+
+			records = append(records, &Autorun{
+				Type:         "config_profile",
+				Location:     storePath,
+				Entry:        profile.Identifier,
+				RawName:      profile.DisplayName,
+				LaunchString: strings.Join(payloadTypes, ", "),
+				User:         user,
+				Scope:        scope,
+			})
+		}
+	}
+
+	return
+}
@@ -0,0 +1,88 @@
+package autoruns
+
+// The codes listed in Autorun.Flags, each for a condition which makes a
+// record worth a closer look. The codes are stable: new ones may be added,
+// but existing ones keep their name and meaning.
+const (
+	// FlagHidden is set for records hidden from the tools of the system,
+	// see Autorun.Hidden.
+	FlagHidden = "HIDDEN"
+	// FlagExcludedFromDefender is set for images excluded from Defender,
+	// see Autorun.ExcludedFromDefender.
+	FlagExcludedFromDefender = "EXCLUDED_FROM_DEFENDER"
+	// FlagMasquerade is set for images named like a system binary but
+	// located elsewhere, see Autorun.Masquerade.
+	FlagMasquerade = "MASQUERADE_SYSTEM_NAME"
+	// FlagFileMissing is set for images which do not exist.
+	FlagFileMissing = "FILE_MISSING"
+	// FlagUnsigned is set for images without a valid signature. It
+	// requires Options.VerifySignatures.
+	FlagUnsigned = "UNSIGNED"
+	// FlagUnquotedPath is set on Windows for launch strings whose
+	// executable contains spaces but is not quoted, so that Windows first
+	// tries to launch what precedes the spaces, e.g. C:\Program.exe for
+	// C:\Program Files\app.exe.
+	FlagUnquotedPath = "UNQUOTED_PATH"
+	// FlagSideloadRisk is set for images importing a commonly hijacked DLL,
+	// see Autorun.SideloadRisk.
+	FlagSideloadRisk = "SIDELOAD_RISK"
+	// FlagNearDefenderTamper is set for records modified within a day of a
+	// policy weakening Defender being set.
+	FlagNearDefenderTamper = "NEAR_DEFENDER_TAMPER"
+	// FlagUnresolved is set for launch strings whose executable could not
+	// be resolved, see Autorun.Parsed.
+	FlagUnresolved = "UNRESOLVED_LAUNCH_STRING"
+	// FlagWritableDirectory is set for local images in a directory which
+	// users other than administrators can write to, so that the image can
+	// be replaced. It is not checked by quick scans.
+	FlagWritableDirectory = "WRITABLE_DIRECTORY"
+	// FlagNetworkPath is set for images loaded from a network share.
+	FlagNetworkPath = "NETWORK_PATH"
+)
+
+// recordFlags are the conditions listed in Autorun.Flags, in order.
+var recordFlags = []struct {
+	code    string
+	applies func(opts Options, autorun *Autorun) bool
+}{
+	{FlagHidden, func(opts Options, autorun *Autorun) bool {
+		return autorun.Hidden
+	}},
+	{FlagExcludedFromDefender, func(opts Options, autorun *Autorun) bool {
+		return autorun.ExcludedFromDefender
+	}},
+	{FlagMasquerade, func(opts Options, autorun *Autorun) bool {
+		return autorun.Masquerade
+	}},
+	{FlagFileMissing, func(opts Options, autorun *Autorun) bool {
+		return autorun.FileMissing
+	}},
+	{FlagUnsigned, unsignedImage},
+	{FlagUnquotedPath, func(opts Options, autorun *Autorun) bool {
+		return autorun.unquotedPath
+	}},
+	{FlagSideloadRisk, func(opts Options, autorun *Autorun) bool {
+		return autorun.SideloadRisk
+	}},
+	{FlagNearDefenderTamper, func(opts Options, autorun *Autorun) bool {
+		return autorun.nearDefenderTamper
+	}},
+	{FlagUnresolved, func(opts Options, autorun *Autorun) bool {
+		return autorun.unparsed
+	}},
+	{FlagWritableDirectory, writableImageDirectory},
+	{FlagNetworkPath, func(opts Options, autorun *Autorun) bool {
+		return autorun.MediaType == "network" || isNetworkPath(autorun.ImagePath)
+	}},
+}
+
+// setFlags sets the Flags of a record to the codes of the conditions which
+// apply to it.
+func setFlags(opts Options, autorun *Autorun) {
+	autorun.Flags = nil
+	for _, flag := range recordFlags {
+		if flag.applies(opts, autorun) {
+			autorun.Flags = append(autorun.Flags, flag.code)
+		}
+	}
+}
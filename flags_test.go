@@ -0,0 +1,65 @@
+package autoruns
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestSetFlags(t *testing.T) {
+	writableDir := t.TempDir()
+	if err := os.Chmod(writableDir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	privateDir := t.TempDir()
+	if err := os.Chmod(privateDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	image := filepath.Join(privateDir, "image")
+
+	var unsigned []string
+	if signaturesVerifiable {
+		unsigned = []string{FlagUnsigned}
+	}
+	var writable []string
+	// The DACL of a temporary directory on Windows is not set by Chmod.
+	if runtime.GOOS != "windows" {
+		writable = []string{FlagWritableDirectory}
+	}
+
+	tests := []struct {
+		name    string
+		opts    Options
+		autorun *Autorun
+		want    []string
+	}{
+		{"clean", Options{}, &Autorun{ImagePath: image}, nil},
+		{"hidden", Options{}, &Autorun{ImagePath: image, Hidden: true}, []string{FlagHidden}},
+		{"excluded", Options{}, &Autorun{ImagePath: image, ExcludedFromDefender: true}, []string{FlagExcludedFromDefender}},
+		{"masquerade", Options{}, &Autorun{ImagePath: image, Masquerade: true}, []string{FlagMasquerade}},
+		{"missing file", Options{}, &Autorun{ImagePath: image, FileMissing: true}, []string{FlagFileMissing}},
+		{"unsigned", Options{VerifySignatures: true}, &Autorun{ImagePath: image}, unsigned},
+		{"signed", Options{VerifySignatures: true}, &Autorun{ImagePath: image, Signed: true}, nil},
+		{"unquoted path", Options{}, &Autorun{ImagePath: image, unquotedPath: true}, []string{FlagUnquotedPath}},
+		{"sideload risk", Options{}, &Autorun{ImagePath: image, SideloadRisk: true}, []string{FlagSideloadRisk}},
+		{"near defender tamper", Options{}, &Autorun{ImagePath: image, nearDefenderTamper: true}, []string{FlagNearDefenderTamper}},
+		{"unresolved", Options{}, &Autorun{LaunchString: "agent --run", unparsed: true}, []string{FlagUnresolved}},
+		{"writable directory", Options{}, &Autorun{ImagePath: filepath.Join(writableDir, "image")}, writable},
+		// Quick scans do not look at the directory.
+		{"writable directory quick", Options{QuickScan: true}, &Autorun{ImagePath: filepath.Join(writableDir, "image")}, nil},
+		{"network share", Options{}, &Autorun{ImagePath: `\\server\share\agent.exe`}, []string{FlagNetworkPath}},
+		{"network drive", Options{}, &Autorun{ImagePath: `N:\agent.exe`, MediaType: "network"}, []string{FlagNetworkPath}},
+		{"several", Options{}, &Autorun{ImagePath: image, Hidden: true, FileMissing: true, unparsed: true},
+			[]string{FlagHidden, FlagFileMissing, FlagUnresolved}},
+	}
+	for _, test := range tests {
+		// Flags left from an earlier scan are replaced.
+		test.autorun.Flags = []string{"STALE"}
+		setFlags(test.opts, test.autorun)
+		if !reflect.DeepEqual(test.autorun.Flags, test.want) {
+			t.Errorf("%s: got Flags %q, want %q", test.name, test.autorun.Flags, test.want)
+		}
+	}
+}
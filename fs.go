@@ -0,0 +1,72 @@
+package autoruns
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+)
+
+// fsFile is a file opened through a fileSystem. It is implemented by
+// *os.File.
+type fsFile interface {
+	io.Reader
+	io.Seeker
+	io.Closer
+}
+
+// fileSystem looks up and reads the files scanners inspect. Scanners and
+// the parsing of launch strings only access files through the fileSystem
+// of their Options, so that it can be replaced by one which is not backed
+// by the local disk.
+type fileSystem interface {
+	Stat(name string) (os.FileInfo, error)
+	Open(name string) (fsFile, error)
+	ReadDir(dirname string) ([]os.FileInfo, error)
+	LookPath(file string) (string, error)
+}
+
+// osFileSystem is the file system of the local system.
+type osFileSystem struct{}
+
+func (osFileSystem) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (osFileSystem) Open(name string) (fsFile, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+func (osFileSystem) ReadDir(dirname string) ([]os.FileInfo, error) {
+	return ioutil.ReadDir(dirname)
+}
+
+func (osFileSystem) LookPath(file string) (string, error) {
+	return exec.LookPath(file)
+}
+
+// readFile reads a whole file from fsys.
+func readFile(fsys fileSystem, name string) ([]byte, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ioutil.ReadAll(file)
+}
+
+// fileSystemFor returns the file system a scan with the given options
+// reads from, which is the one of the local system by default.
+func fileSystemFor(opts Options) fileSystem {
+	if opts.fs == nil {
+		return osFileSystem{}
+	}
+
+	return opts.fs
+}
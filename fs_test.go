@@ -0,0 +1,198 @@
+package autoruns
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fakeFile is a file of a fakeFileSystem.
+type fakeFile struct {
+	data    string
+	mode    os.FileMode
+	modTime time.Time
+}
+
+// fakeFileSystem is an in-memory fileSystem for tests. Files are keyed by
+// their cleaned path, and directories exist as long as they contain a
+// file. Like exec.LookPath, LookPath searches the directories of path and
+// only finds executables: on Windows, files named with any extension or
+// with one of PATHEXT appended, and on other platforms, files with an
+// executable mode.
+type fakeFileSystem struct {
+	files map[string]fakeFile
+	path  []string
+}
+
+// newFakeFileSystem returns a fakeFileSystem holding files with the given
+// contents, which are regular files with mode 0644.
+func newFakeFileSystem(files map[string]string) *fakeFileSystem {
+	fsys := &fakeFileSystem{files: make(map[string]fakeFile)}
+	for name, data := range files {
+		fsys.add(name, data, 0644)
+	}
+
+	return fsys
+}
+
+// add adds a file to the file system.
+func (f *fakeFileSystem) add(name string, data string, mode os.FileMode) {
+	f.files[filepath.Clean(name)] = fakeFile{data: data, mode: mode}
+}
+
+// isDir checks whether name is a directory, which it is if it contains
+// a file.
+func (f *fakeFileSystem) isDir(name string) bool {
+	prefix := strings.TrimSuffix(filepath.Clean(name), string(filepath.Separator)) + string(filepath.Separator)
+	for path := range f.files {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (f *fakeFileSystem) Stat(name string) (os.FileInfo, error) {
+	name = filepath.Clean(name)
+	if file, ok := f.files[name]; ok {
+		return fakeFileInfo{name: filepath.Base(name), file: file}, nil
+	}
+	if f.isDir(name) {
+		return fakeFileInfo{name: filepath.Base(name), file: fakeFile{mode: os.ModeDir | 0755}}, nil
+	}
+
+	return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+}
+
+func (f *fakeFileSystem) Open(name string) (fsFile, error) {
+	file, ok := f.files[filepath.Clean(name)]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+
+	return fakeOpenFile{strings.NewReader(file.data)}, nil
+}
+
+func (f *fakeFileSystem) ReadDir(dirname string) ([]os.FileInfo, error) {
+	if !f.isDir(dirname) {
+		return nil, &os.PathError{Op: "open", Path: dirname, Err: os.ErrNotExist}
+	}
+
+	prefix := strings.TrimSuffix(filepath.Clean(dirname), string(filepath.Separator)) + string(filepath.Separator)
+	seen := make(map[string]bool)
+	var infos []os.FileInfo
+	for path := range f.files {
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+		name := strings.SplitN(path[len(prefix):], string(filepath.Separator), 2)[0]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		info, _ := f.Stat(filepath.Join(dirname, name))
+		infos = append(infos, info)
+	}
+	// Like ioutil.ReadDir, the entries are sorted by name.
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
+
+	return infos, nil
+}
+
+func (f *fakeFileSystem) LookPath(file string) (string, error) {
+	candidates := []string{file}
+	if !strings.ContainsAny(file, `/\`) {
+		candidates = nil
+		for _, dir := range f.path {
+			candidates = append(candidates, filepath.Join(dir, file))
+		}
+	}
+
+	if runtime.GOOS == "windows" {
+		var withExtensions []string
+		for _, candidate := range candidates {
+			if fakeHasExt(candidate) {
+				withExtensions = append(withExtensions, candidate)
+			}
+			for _, ext := range fakePathExt {
+				withExtensions = append(withExtensions, candidate+ext)
+			}
+		}
+		candidates = withExtensions
+	}
+
+	for _, candidate := range candidates {
+		info, err := f.Stat(candidate)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		if runtime.GOOS == "windows" || info.Mode().Perm()&0111 != 0 {
+			return candidate, nil
+		}
+	}
+
+	return "", errors.New("executable file not found: " + file)
+}
+
+// The extensions fakeFileSystem.LookPath appends on Windows.
+var fakePathExt = []string{".com", ".exe", ".bat", ".cmd"}
+
+// fakeHasExt checks whether the file name of a path has an extension.
+func fakeHasExt(path string) bool {
+	dot := strings.LastIndex(path, ".")
+	return dot >= 0 && strings.LastIndexAny(path, `:\/`) < dot
+}
+
+// fakeOpenFile is a file opened from a fakeFileSystem.
+type fakeOpenFile struct {
+	*strings.Reader
+}
+
+func (fakeOpenFile) Close() error {
+	return nil
+}
+
+// fakeFileInfo describes a file of a fakeFileSystem.
+type fakeFileInfo struct {
+	name string
+	file fakeFile
+}
+
+func (i fakeFileInfo) Name() string       { return i.name }
+func (i fakeFileInfo) Size() int64        { return int64(len(i.file.data)) }
+func (i fakeFileInfo) Mode() os.FileMode  { return i.file.mode }
+func (i fakeFileInfo) ModTime() time.Time { return i.file.modTime }
+func (i fakeFileInfo) IsDir() bool        { return i.file.mode.IsDir() }
+func (i fakeFileInfo) Sys() interface{}   { return nil }
+
+// countingFileSystem counts the files opened and the executables looked up
+// in a fileSystem. It is safe for concurrent use.
+type countingFileSystem struct {
+	fileSystem
+
+	mu      sync.Mutex
+	opens   int
+	lookups int
+}
+
+func (f *countingFileSystem) Open(name string) (fsFile, error) {
+	f.mu.Lock()
+	f.opens++
+	f.mu.Unlock()
+
+	return f.fileSystem.Open(name)
+}
+
+func (f *countingFileSystem) LookPath(file string) (string, error) {
+	f.mu.Lock()
+	f.lookups++
+	f.mu.Unlock()
+
+	return f.fileSystem.LookPath(file)
+}
@@ -0,0 +1,78 @@
+//+build windows
+
+package autoruns
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// mappedFileSystem reads the files of another system, such as a remote
+// machine or an offline image, from where they can be accessed locally.
+// Paths are still reported as they are on the other system.
+type mappedFileSystem struct {
+	// mapPath returns the local path of a file of the other system.
+	mapPath func(name string) (string, error)
+}
+
+func (f mappedFileSystem) Stat(name string) (os.FileInfo, error) {
+	mappedPath, err := f.mapPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.Stat(mappedPath)
+}
+
+func (f mappedFileSystem) Open(name string) (fsFile, error) {
+	mappedPath, err := f.mapPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(mappedPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+func (f mappedFileSystem) ReadDir(dirname string) ([]os.FileInfo, error) {
+	mappedPath, err := f.mapPath(dirname)
+	if err != nil {
+		return nil, err
+	}
+
+	return osFileSystem{}.ReadDir(mappedPath)
+}
+
+// LookPath looks up an executable in the Windows directories of the other
+// system, as its PATH is not known. Names without an extension are tried
+// with the extensions in PATHEXT.
+func (f mappedFileSystem) LookPath(file string) (string, error) {
+	candidates := []string{file}
+	if !filepath.IsAbs(file) {
+		systemRoot := os.Getenv("SystemRoot")
+		candidates = []string{
+			filepath.Join(systemRoot, "System32", file),
+			filepath.Join(systemRoot, file),
+		}
+	}
+
+	var extensions []string
+	if filepath.Ext(file) == "" {
+		extensions = filepath.SplitList(strings.ToLower(os.Getenv("PATHEXT")))
+	}
+
+	for _, candidate := range candidates {
+		for _, extension := range append([]string{""}, extensions...) {
+			if info, err := f.Stat(candidate + extension); err == nil && !info.IsDir() {
+				return candidate + extension, nil
+			}
+		}
+	}
+
+	return "", &os.PathError{Op: "lookpath", Path: file, Err: os.ErrNotExist}
+}
@@ -0,0 +1,61 @@
+package autoruns
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io"
+)
+
+// autorunGob has the fields of Autorun without its methods, so that gob
+// encodes it field by field instead of through MarshalBinary.
+type autorunGob Autorun
+
+// MarshalBinary encodes the record with gob, preserving the exact times
+// which JSON rounds. Like with JSON, a decoded record cannot be remediated
+// directly, see Options.AllowRemediation.
+func (a *Autorun) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode((*autorunGob)(a)); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a record encoded with MarshalBinary.
+func (a *Autorun) UnmarshalBinary(data []byte) error {
+	var decoded autorunGob
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&decoded); err != nil {
+		return err
+	}
+	*a = Autorun(decoded)
+
+	return nil
+}
+
+// WriteGob writes records to w as a single gob stream, which is more
+// compact than encoding every record with MarshalBinary. It can be read
+// back with ReadGob.
+func WriteGob(w io.Writer, records []*Autorun) error {
+	encoded := make([]*autorunGob, len(records))
+	for i, record := range records {
+		encoded[i] = (*autorunGob)(record)
+	}
+
+	return gob.NewEncoder(w).Encode(encoded)
+}
+
+// ReadGob reads the records written to r by WriteGob.
+func ReadGob(r io.Reader) ([]*Autorun, error) {
+	var decoded []*autorunGob
+	if err := gob.NewDecoder(r).Decode(&decoded); err != nil {
+		return nil, err
+	}
+
+	records := make([]*Autorun, len(decoded))
+	for i, record := range decoded {
+		records[i] = (*Autorun)(record)
+	}
+
+	return records, nil
+}
@@ -0,0 +1,75 @@
+package autoruns
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMarshalBinaryRoundTrip(t *testing.T) {
+	for _, record := range testRecords() {
+		data, err := record.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary: %v", err)
+		}
+		var decoded Autorun
+		if err := decoded.UnmarshalBinary(data); err != nil {
+			t.Fatalf("UnmarshalBinary: %v", err)
+		}
+		if !reflect.DeepEqual(&decoded, record) {
+			t.Errorf("decoded\n%+v\nwant\n%+v", &decoded, record)
+		}
+	}
+
+	if err := new(Autorun).UnmarshalBinary([]byte("not gob")); err == nil {
+		t.Error("UnmarshalBinary of garbage succeeded")
+	}
+}
+
+func TestMarshalBinaryPreservesTimes(t *testing.T) {
+	modified := time.Date(2024, 3, 1, 12, 30, 45, 123456789, time.FixedZone("", 90*60))
+	record := &Autorun{LastModified: modified, remediable: true}
+
+	data, err := record.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+	var decoded Autorun
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+
+	got := decoded.LastModified
+	if !got.Equal(modified) || got.Nanosecond() != 123456789 {
+		t.Errorf("LastModified = %v, want %v", got, modified)
+	}
+	if _, offset := got.Zone(); offset != 90*60 {
+		t.Errorf("LastModified has offset %d, want %d", offset, 90*60)
+	}
+	// Like JSON, the encoding does not carry whether a record can be
+	// remediated.
+	if decoded.remediable {
+		t.Error("decoded record is remediable")
+	}
+}
+
+func TestWriteGobRoundTrip(t *testing.T) {
+	records := testRecords()
+	var buf bytes.Buffer
+	if err := WriteGob(&buf, records); err != nil {
+		t.Fatalf("WriteGob: %v", err)
+	}
+
+	decoded, err := ReadGob(&buf)
+	if err != nil {
+		t.Fatalf("ReadGob: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, records) {
+		t.Errorf("read back\n%+v\nwant\n%+v", decoded, records)
+	}
+
+	if _, err := ReadGob(bytes.NewReader(nil)); err == nil {
+		t.Error("ReadGob of an empty stream succeeded")
+	}
+}
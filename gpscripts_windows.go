@@ -0,0 +1,222 @@
+//+build windows
+
+package autoruns
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// The sections of the script manifests of Group Policy, each naming the
+// event its scripts run at.
+var gpScriptSections = map[string]bool{
+	"startup":  true,
+	"shutdown": true,
+	"logon":    true,
+	"logoff":   true,
+}
+
+// gpScriptAutorun returns an Autorun for a Group Policy script. Scripts on
+// network shares, such as SYSVOL, are only resolved with
+// Options.NetworkImages.
+func gpScriptAutorun(opts Options, location string, script string, entry string) *Autorun {
+	if !isNetworkPath(script) || opts.NetworkImages {
+		return stringToAutorun(opts, "gp_script", location, script, false, entry)
+	}
+
+	return &Autorun{
+		Type:         "gp_script",
+		Location:     location,
+		ImagePath:    script,
+		ImageName:    filepath.Base(script),
+		Entry:        entry,
+		LaunchString: script,
+		MediaType:    "network",
+	}
+}
+
+// parseGPScripts reads the scripts.ini and psscripts.ini manifests in the
+// Scripts folder of a Group Policy Object. Each script is given by a
+// numbered <n>CmdLine key, with its arguments in <n>Parameters. Scripts
+// without a directory are stored in the subfolder of the Scripts folder
+// named after their section.
+func parseGPScripts(opts Options, scriptsDir string) (records []*Autorun) {
+	for _, manifest := range []string{"scripts.ini", "psscripts.ini"} {
+		manifestPath := filepath.Join(scriptsDir, manifest)
+		data, err := readFile(fileSystemFor(opts), manifestPath)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			opts.Warn(manifestPath, err)
+			continue
+		}
+
+		for _, section := range parseINI(data) {
+			event := strings.ToLower(section.name)
+			if !gpScriptSections[event] {
+				continue
+			}
+
+			for _, key := range section.keys {
+				if !strings.HasSuffix(key, "cmdline") {
+					continue
+				}
+				index := strings.TrimSuffix(key, "cmdline")
+				if _, err := strconv.Atoi(index); err != nil {
+					continue
+				}
+
+				script := section.values[key]
+				if script == "" {
+					continue
+				}
+				if !filepath.IsAbs(script) && !strings.HasPrefix(script, "\\\\") {
+					script = filepath.Join(scriptsDir, section.name, script)
+				}
+
+				// We pass the script to a function to return an Autorun.
+				newAutorun := gpScriptAutorun(opts, manifestPath, script, fmt.Sprintf("%s %s", section.name, index))
+				newAutorun.RawName = index + "CmdLine"
+				newAutorun.Arguments = section.values[index+"parameters"]
+				newAutorun.LaunchString = strings.TrimSpace(section.values[key] + " " + newAutorun.Arguments)
+				newAutorun.Trigger = event
+
+				// Add the new autorun to the records.
+				records = append(records, newAutorun)
+			}
+		}
+	}
+
+	return
+}
+
+// The events of the scripts Group Policy caches in the registry, by the
+// hive they are cached in.
+var gpScriptEvents = []struct {
+	reg    registry.Key
+	events []string
+}{
+	{registry.LOCAL_MACHINE, []string{"Startup", "Shutdown"}},
+	{registry.CURRENT_USER, []string{"Logon", "Logoff"}},
+}
+
+// windowsGetCachedGPScripts enumerates the scripts of the Group Policy
+// Objects applied to the machine and the current user, including domain
+// ones, as cached by the Group Policy client. Each event key holds a
+// numbered subkey per GPO, with the folder of the GPO on SYSVOL as
+// FileSysPath, which in turn holds a numbered subkey per script.
+func windowsGetCachedGPScripts(opts Options) (records []*Autorun) {
+	var scriptsKey string = "Software\\Microsoft\\Windows\\CurrentVersion\\Group Policy\\Scripts"
+
+	for _, scope := range gpScriptEvents {
+		for _, event := range scope.events {
+			eventKey := fmt.Sprintf("%s\\%s", scriptsKey, event)
+
+			// Open registry key.
+			key, err := openKey(opts, scope.reg, eventKey)
+			if err != nil {
+				continue
+			}
+			gpos, _ := key.ReadSubKeyNames(0)
+			key.Close()
+
+			for _, gpo := range gpos {
+				gpoKey := fmt.Sprintf("%s\\%s", eventKey, gpo)
+				subkey, err := openKey(opts, scope.reg, gpoKey)
+				if err != nil {
+					continue
+				}
+				fileSysPath, _, _ := subkey.GetStringValue("FileSysPath")
+				displayName, _, _ := subkey.GetStringValue("DisplayName")
+				scripts, _ := subkey.ReadSubKeyNames(0)
+				subkey.Close()
+				if displayName == "" {
+					displayName = gpo
+				}
+
+				for _, index := range scripts {
+					scriptKey := fmt.Sprintf("%s\\%s", gpoKey, index)
+					scriptSubkey, err := openKey(opts, scope.reg, scriptKey)
+					if err != nil {
+						continue
+					}
+					script, _, err := scriptSubkey.GetStringValue("Script")
+					parameters, _, _ := scriptSubkey.GetStringValue("Parameters")
+					scriptSubkey.Close()
+					if err != nil || script == "" {
+						continue
+					}
+
+					// Scripts without a directory are stored in the Scripts
+					// folder of the GPO.
+					scriptPath := script
+					if !filepath.IsAbs(script) && !isNetworkPath(script) && fileSysPath != "" {
+						scriptPath = filepath.Join(fileSysPath, "Scripts", event, script)
+					}
+
+					imageLocation := fmt.Sprintf("%s\\%s", registryToString(scope.reg), scriptKey)
+
+					// We pass the script to a function to return an Autorun.
+					newAutorun := gpScriptAutorun(opts, imageLocation, scriptPath, fmt.Sprintf("%s %s %s", displayName, event, index))
+					newAutorun.RawName = "Script"
+					newAutorun.Arguments = parameters
+					newAutorun.LaunchString = strings.TrimSpace(script + " " + parameters)
+					newAutorun.Trigger = strings.ToLower(event)
+					newAutorun.User = keyUser(opts, scope.reg)
+
+					// Add the new autorun to the records.
+					records = append(records, newAutorun)
+				}
+			}
+		}
+	}
+
+	return
+}
+
+// This function enumerates the startup, shutdown, logon and logoff scripts
+// of Group Policy: those of the local Group Policy, as defined by the
+// manifests on disk for both the machine and the users, those of the domain
+// policies cached from SYSVOL in the DataStore folder, and those the Group
+// Policy client caches in the registry.
+func windowsGetGPScripts(opts Options) (records []*Autorun) {
+	policyDir := filepath.Join(os.Getenv("SystemRoot"), "System32", "GroupPolicy")
+
+	for _, scope := range []string{"Machine", "User"} {
+		records = append(records, parseGPScripts(opts, filepath.Join(policyDir, scope, "Scripts"))...)
+	}
+
+	// The DataStore holds a copy of the policies of each domain, laid out
+	// as on SYSVOL: <n>\sysvol\<domain>\Policies\<GPO>.
+	dataStoreDir := filepath.Join(policyDir, "DataStore")
+	if indexes, err := fileSystemFor(opts).ReadDir(dataStoreDir); err == nil {
+		for _, index := range indexes {
+			sysvolDir := filepath.Join(dataStoreDir, index.Name(), "sysvol")
+			domains, err := fileSystemFor(opts).ReadDir(sysvolDir)
+			if err != nil {
+				continue
+			}
+
+			for _, domain := range domains {
+				policiesDir := filepath.Join(sysvolDir, domain.Name(), "Policies")
+				gpos, err := fileSystemFor(opts).ReadDir(policiesDir)
+				if err != nil {
+					continue
+				}
+
+				for _, gpo := range gpos {
+					for _, scope := range []string{"Machine", "User"} {
+						records = append(records, parseGPScripts(opts, filepath.Join(policiesDir, gpo.Name(), scope, "Scripts"))...)
+					}
+				}
+			}
+		}
+	}
+
+	return append(records, windowsGetCachedGPScripts(opts)...)
+}
@@ -0,0 +1,44 @@
+package autoruns
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+)
+
+// defaultHashBufferSize is the read buffer size used for hashing when
+// Options.HashBufferSize is not set.
+const defaultHashBufferSize = 1 << 20
+
+// hashFile computes the MD5, SHA1 and SHA256 of a file in a single pass,
+// reading it in chunks of bufferSize bytes. The contents are also written to
+// extra, e.g. to compute the entropy of the file in the same pass.
+func hashFile(fsys fileSystem, path string, bufferSize int, extra ...io.Writer) (md5Sum, sha1Sum, sha256Sum string, err error) {
+	file, err := fsys.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	if bufferSize <= 0 {
+		bufferSize = defaultHashBufferSize
+	}
+
+	md5Hash := md5.New()
+	sha1Hash := sha1.New()
+	sha256Hash := sha256.New()
+
+	// The file is wrapped so that io.CopyBuffer cannot bypass the buffer
+	// through its WriteTo method, such as the one of *os.File.
+	writer := io.MultiWriter(append([]io.Writer{md5Hash, sha1Hash, sha256Hash}, extra...)...)
+	if _, err = io.CopyBuffer(writer, struct{ io.Reader }{file}, make([]byte, bufferSize)); err != nil {
+		return
+	}
+
+	md5Sum = hex.EncodeToString(md5Hash.Sum(nil))
+	sha1Sum = hex.EncodeToString(sha1Hash.Sum(nil))
+	sha256Sum = hex.EncodeToString(sha256Hash.Sum(nil))
+	return
+}
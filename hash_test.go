@@ -0,0 +1,54 @@
+package autoruns
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHashFile(t *testing.T) {
+	fsys := newFakeFileSystem(map[string]string{"abc": "abc"})
+
+	// The sums do not depend on the size of the buffer.
+	for _, bufferSize := range []int{0, 1, 2, 1024} {
+		var extra bytes.Buffer
+		md5Sum, sha1Sum, sha256Sum, err := hashFile(fsys, "abc", bufferSize, &extra)
+		if err != nil {
+			t.Fatalf("buffer size %d: %v", bufferSize, err)
+		}
+		if md5Sum != "900150983cd24fb0d6963f7d28e17f72" ||
+			sha1Sum != "a9993e364706816aba3e25717850c26c9cd0d89d" ||
+			sha256Sum != "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad" {
+			t.Errorf("buffer size %d: got %s, %s, %s", bufferSize, md5Sum, sha1Sum, sha256Sum)
+		}
+		if extra.String() != "abc" {
+			t.Errorf("buffer size %d: extra writer got %q", bufferSize, extra.String())
+		}
+	}
+
+	if _, _, _, err := hashFile(fsys, "missing", 0); err == nil {
+		t.Error("hashing a missing file succeeded")
+	}
+}
+
+func BenchmarkHashFile(b *testing.B) {
+	const size = 64 << 20
+	path := filepath.Join(b.TempDir(), "large.bin")
+	if err := ioutil.WriteFile(path, []byte(strings.Repeat("autoruns", size/8)), 0644); err != nil {
+		b.Fatal(err)
+	}
+
+	for _, bufferSize := range []int{4 << 10, 64 << 10, 1 << 20, 4 << 20} {
+		b.Run(fmt.Sprintf("buffer=%dKiB", bufferSize>>10), func(b *testing.B) {
+			b.SetBytes(size)
+			for i := 0; i < b.N; i++ {
+				if _, _, _, err := hashFile(osFileSystem{}, path, bufferSize); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+//+build windows
+
+package autoruns
+
+import (
+	"fmt"
+	"strings"
+)
+
+// This function enumerates the commands users typed into the Run dialog,
+// from RunMRU, and the paths they typed into the address bar of Explorer,
+// from TypedPaths. They are not autoruns, but context on how autoruns may
+// have been installed, and are only reported with ExecutionHistory.
+func windowsGetExecutionHistory(opts Options) (records []*Autorun) {
+	if !opts.ExecutionHistory {
+		return
+	}
+
+	var runMRUKey string = "Software\\Microsoft\\Windows\\CurrentVersion\\Explorer\\RunMRU"
+	var typedPathsKey string = "Software\\Microsoft\\Windows\\CurrentVersion\\Explorer\\TypedPaths"
+
+	for _, root := range userRegistryRoots(opts) {
+		for _, keyName := range []string{runMRUKey, typedPathsKey} {
+			// Open registry key.
+			key, err := openKey(opts, root.reg, root.prefix+keyName)
+			if err != nil {
+				continue
+			}
+
+			// Enumerate value names.
+			names, err := key.ReadValueNames(0)
+			if err != nil {
+				key.Close()
+				continue
+			}
+
+			imageLocation := fmt.Sprintf("%s\\%s%s", registryToString(root.reg), root.prefix, keyName)
+			for _, name := range names {
+				// MRUList only holds the order of the other values.
+				if strings.EqualFold(name, "MRUList") {
+					continue
+				}
+
+				value, _, err := key.GetStringValue(name)
+				if err != nil || value == "" {
+					continue
+				}
+
+				// The Run dialog terminates the commands it records with \1.
+				command := strings.TrimSuffix(value, "\\1")
+
+				records = append(records, &Autorun{
+					Type:         "run_mru",
+					Location:     imageLocation,
+					Entry:        command,
+					RawName:      name,
+					LaunchString: command,
+					User:         root.user,
+				})
+			}
+			key.Close()
+		}
+	}
+
+	return
+}
@@ -0,0 +1,65 @@
+package autoruns
+
+import "time"
+
+// fileModTime returns when a file was last modified, once per scan no
+// matter how many records it holds.
+func fileModTime(opts Options, path string) time.Time {
+	return opts.memo("file_mtime:"+path, func() interface{} {
+		info, err := fileSystemFor(opts).Stat(path)
+		if err != nil {
+			return time.Time{}
+		}
+		return info.ModTime()
+	}).(time.Time)
+}
+
+// previousRecord returns the record of Options.PreviousRecords with the same
+// ID as autorun, if any.
+func previousRecord(opts Options, autorun *Autorun) *Autorun {
+	byID := opts.memo("previous_records", func() interface{} {
+		byID := make(map[string]*Autorun, len(opts.PreviousRecords))
+		for _, record := range opts.PreviousRecords {
+			byID[record.ID()] = record
+		}
+		return byID
+	}).(map[string]*Autorun)
+
+	return byID[autorun.ID()]
+}
+
+// unchangedRecord returns the previous record of autorun if neither where
+// it was found nor its image were modified since Options.ChangedSince, in
+// which case its analysis can be carried forward.
+func unchangedRecord(opts Options, autorun *Autorun) *Autorun {
+	if opts.ChangedSince.IsZero() {
+		return nil
+	}
+	if autorun.LastModified.IsZero() || !autorun.LastModified.Before(opts.ChangedSince) {
+		return nil
+	}
+
+	previous := previousRecord(opts, autorun)
+	if previous == nil || previous.ImagePath != autorun.ImagePath {
+		return nil
+	}
+
+	imageModTime := fileModTime(opts, autorun.ImagePath)
+	if imageModTime.IsZero() || !imageModTime.Before(opts.ChangedSince) {
+		return nil
+	}
+
+	return previous
+}
+
+// copyAnalysis copies the results of the analysis of an image from one
+// record to another.
+func copyAnalysis(dst *Autorun, src *Autorun) {
+	dst.FileMissing = src.FileMissing
+	dst.MediaType = src.MediaType
+	dst.MD5, dst.SHA1, dst.SHA256 = src.MD5, src.SHA1, src.SHA256
+	dst.Entropy = src.Entropy
+	dst.Signed, dst.Signature = src.Signed, src.Signature
+	dst.CompileTime = src.CompileTime
+	dst.SideloadRisk = src.SideloadRisk
+}
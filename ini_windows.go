@@ -0,0 +1,67 @@
+//+build windows
+
+package autoruns
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"unicode/utf16"
+)
+
+// decodeUTF16 converts text which starts with a UTF-16 little-endian byte
+// order mark to UTF-8. Other text is returned unchanged.
+func decodeUTF16(data []byte) []byte {
+	if len(data) < 2 || data[0] != 0xff || data[1] != 0xfe {
+		return data
+	}
+
+	data = data[2:]
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = uint16(data[2*i]) | uint16(data[2*i+1])<<8
+	}
+
+	return []byte(string(utf16.Decode(units)))
+}
+
+// iniSection is a section of an INI file, with its keys in the order
+// they appear in.
+type iniSection struct {
+	name   string
+	keys   []string
+	values map[string]string
+}
+
+// parseINI parses an INI file, which may be UTF-16 encoded. Key names are
+// matched case-insensitively, as Windows does, and are lowercased.
+func parseINI(data []byte) (sections []*iniSection) {
+	var section *iniSection
+
+	scanner := bufio.NewScanner(bytes.NewReader(decodeUTF16(data)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = &iniSection{name: line[1 : len(line)-1], values: make(map[string]string)}
+			sections = append(sections, section)
+			continue
+		}
+
+		separator := strings.Index(line, "=")
+		if section == nil || separator < 0 {
+			continue
+		}
+
+		key := strings.ToLower(strings.TrimSpace(line[:separator]))
+		if _, ok := section.values[key]; !ok {
+			section.keys = append(section.keys, key)
+		}
+		section.values[key] = strings.TrimSpace(line[separator+1:])
+	}
+
+	return
+}
@@ -0,0 +1,123 @@
+//+build windows
+
+package autoruns
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// The registry locations the default mappings of INI files point to. SYS:
+// is relative to LOCAL_MACHINE\Software and USR: to CURRENT_USER.
+var defaultIniMappingTargets = []string{
+	"sys:microsoft\\",
+	"usr:software\\microsoft\\",
+	"usr:control panel\\",
+	"usr:printers\\",
+	"usr:keyboard layout",
+	"usr:network\\",
+}
+
+// iniMappingTarget returns the registry location of a mapping, without
+// the prefixes which only tell how it is read, e.g. ! to also write to the
+// file or # to initialize the user key from the file.
+func iniMappingTarget(mapping string) string {
+	return strings.TrimLeft(strings.TrimSpace(mapping), "!#@")
+}
+
+// defaultIniMapping checks whether a mapping points to where Windows maps
+// INI files by default. Mappings to nothing simply stop the file from
+// being read.
+func defaultIniMapping(mapping string) bool {
+	target := strings.ToLower(iniMappingTarget(mapping))
+	if target == "" {
+		return true
+	}
+	for _, prefix := range defaultIniMappingTargets {
+		if strings.HasPrefix(target, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// This function enumerates the mappings of INI files to the registry
+// which do not point to their usual locations. Legacy reads of files such
+// as system.ini or win.ini, whose sections hold the shell or programs to
+// load, are answered from the registry location of the mapping instead.
+// A file is mapped section by section, either by a value named after the
+// section or by a subkey of the section mapping its keys, where the
+// default value maps the whole section.
+func windowsGetIniMappings(opts Options) (records []*Autorun) {
+	var reg registry.Key = registry.LOCAL_MACHINE
+	mappingKeys := []string{
+		"Software\\Microsoft\\Windows NT\\CurrentVersion\\IniFileMapping",
+		"Software\\Wow6432Node\\Microsoft\\Windows NT\\CurrentVersion\\IniFileMapping",
+	}
+
+	// addMappings reports the non-default mappings of a key, where
+	// entryPrefix names the file and section they belong to.
+	addMappings := func(keyName string, entryPrefix string) (sections []string) {
+		key, err := openKey(opts, reg, keyName)
+		if err != nil {
+			return
+		}
+		defer key.Close()
+
+		sections, _ = key.ReadSubKeyNames(0)
+		names, err := key.ReadValueNames(0)
+		if err != nil {
+			return
+		}
+
+		for _, name := range names {
+			mapping, _, err := key.GetStringValue(name)
+			if err != nil || defaultIniMapping(mapping) {
+				continue
+			}
+
+			entry := entryPrefix
+			if name != "" {
+				entry = fmt.Sprintf("%s\\%s", entryPrefix, name)
+			}
+
+			records = append(records, &Autorun{
+				Type:         "ini_mapping",
+				Location:     fmt.Sprintf("%s\\%s", registryToString(reg), keyName),
+				Entry:        entry,
+				RawName:      name,
+				LaunchString: mapping,
+				NonDefault:   true,
+			})
+		}
+
+		return
+	}
+
+	for _, mappingKey := range mappingKeys {
+		// Open registry key.
+		key, err := openKey(opts, reg, mappingKey)
+		if err != nil {
+			continue
+		}
+
+		// Every subkey maps a file.
+		files, err := key.ReadSubKeyNames(0)
+		key.Close()
+		if err != nil {
+			continue
+		}
+
+		for _, file := range files {
+			fileKey := fmt.Sprintf("%s\\%s", mappingKey, file)
+			for _, section := range addMappings(fileKey, file) {
+				addMappings(fmt.Sprintf("%s\\%s", fileKey, section), fmt.Sprintf("%s\\%s", file, section))
+			}
+		}
+	}
+
+	return
+}
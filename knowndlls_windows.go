@@ -0,0 +1,116 @@
+//+build windows
+
+package autoruns
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// This function enumerates the KnownDLLs, which are mapped at boot and
+// loaded from the system directories regardless of the search order, for
+// both 64-bit and 32-bit processes. The 32-bit ones are read from
+// KnownDlls32 and resolved relative to DllDirectory32.
+func windowsGetKnownDLLs(opts Options) (records []*Autorun) {
+	var reg registry.Key = registry.LOCAL_MACHINE
+	var sessionManagerKey string = "System\\CurrentControlSet\\Control\\Session Manager"
+
+	// The directories the DLLs are resolved relative to.
+	dllDirectory := filepath.Join(os.Getenv("SystemRoot"), "System32")
+	dllDirectory32 := filepath.Join(os.Getenv("SystemRoot"), "SysWOW64")
+	if key, err := openKey(opts, reg, sessionManagerKey+"\\KnownDLLs"); err == nil {
+		if value, _, err := key.GetStringValue("DllDirectory"); err == nil && value != "" {
+			dllDirectory = value
+		}
+		if value, _, err := key.GetStringValue("DllDirectory32"); err == nil && value != "" {
+			dllDirectory32 = value
+		}
+		key.Close()
+	}
+
+	keys := []struct {
+		keyName   string
+		directory string
+	}{
+		{sessionManagerKey + "\\KnownDLLs", dllDirectory},
+		{sessionManagerKey + "\\KnownDlls32", dllDirectory32},
+	}
+
+	for _, value := range keys {
+		// Open registry key.
+		key, err := openKey(opts, reg, value.keyName)
+		if err != nil {
+			continue
+		}
+
+		// Enumerate value names.
+		names, err := key.ReadValueNames(0)
+		if err != nil {
+			key.Close()
+			continue
+		}
+
+		imageLocation := fmt.Sprintf("%s\\%s", registryToString(reg), value.keyName)
+		directory := value.directory
+		if expanded, err := registry.ExpandString(directory); err == nil {
+			directory = expanded
+		}
+
+		for _, name := range names {
+			// The directories are not DLLs themselves.
+			if strings.HasPrefix(strings.ToLower(name), "dlldirectory") {
+				continue
+			}
+
+			dll, _, err := key.GetStringValue(name)
+			if err != nil || dll == "" {
+				continue
+			}
+
+			// We pass the resolved DLL path to a function to return an Autorun.
+			newAutorun := stringToAutorun(opts, "known_dll", imageLocation, filepath.Join(directory, dll), false, name)
+			newAutorun.RawName = name
+			newAutorun.LaunchString = dll
+
+			// Add the new autorun to the records.
+			records = append(records, newAutorun)
+		}
+		key.Close()
+	}
+
+	return
+}
+
+// This function reads the shell started instead of Explorer when booting
+// into Safe Mode with Command Prompt, which is cmd.exe by default.
+func windowsGetAlternateShell(opts Options) (records []*Autorun) {
+	var reg registry.Key = registry.LOCAL_MACHINE
+	var safeBootKey string = "System\\CurrentControlSet\\Control\\SafeBoot"
+
+	// Open registry key.
+	key, err := openKey(opts, reg, safeBootKey)
+	if err != nil {
+		return
+	}
+
+	shell, _, err := key.GetStringValue("AlternateShell")
+	key.Close()
+	if err != nil || shell == "" {
+		return
+	}
+
+	imageLocation := fmt.Sprintf("%s\\%s", registryToString(reg), safeBootKey)
+
+	// We pass the value string to a function to return an Autorun.
+	newAutorun := stringToAutorun(opts, "alternate_shell", imageLocation, shell, true, "AlternateShell")
+	newAutorun.RawName = "AlternateShell"
+	newAutorun.NonDefault = !strings.EqualFold(shell, "cmd.exe")
+
+	records = append(records, newAutorun)
+
+	return
+}
@@ -0,0 +1,88 @@
+package autoruns
+
+import (
+	"os"
+	"sync"
+)
+
+// Location is a place a scanner looks at.
+type Location struct {
+	Category string `json:"category"`
+	// Root is the registry root of a registry key, e.g. "LOCAL_MACHINE",
+	// and empty for files and directories.
+	Root string `json:"root"`
+	Path string `json:"path"`
+	// Kind is either "registry_key", "directory" or "file".
+	Kind string `json:"kind"`
+}
+
+// locationRecorder collects the locations a scanner looks at.
+type locationRecorder struct {
+	mu        sync.Mutex
+	category  string
+	seen      map[Location]bool
+	locations []Location
+}
+
+func (r *locationRecorder) record(root string, path string, kind string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	location := Location{Category: r.category, Root: root, Path: path, Kind: kind}
+	if r.seen[location] {
+		return
+	}
+	r.seen[location] = true
+	r.locations = append(r.locations, location)
+}
+
+// recordingFileSystem is a fileSystem which records the files and
+// directories looked at, without touching any of them.
+type recordingFileSystem struct {
+	recorder *locationRecorder
+}
+
+func (fs recordingFileSystem) Stat(name string) (os.FileInfo, error) {
+	fs.recorder.record("", name, "file")
+	return nil, os.ErrNotExist
+}
+
+func (fs recordingFileSystem) Open(name string) (fsFile, error) {
+	fs.recorder.record("", name, "file")
+	return nil, os.ErrNotExist
+}
+
+func (fs recordingFileSystem) ReadDir(dirname string) ([]os.FileInfo, error) {
+	fs.recorder.record("", dirname, "directory")
+	return nil, os.ErrNotExist
+}
+
+func (fs recordingFileSystem) LookPath(file string) (string, error) {
+	return "", os.ErrNotExist
+}
+
+// Locations returns the registry keys, directories and files a scan with
+// the given options would look at first, without reading any of them. The
+// scanners run against an empty registry and file system, so locations
+// they would only find within those, such as the subkeys of a key or the
+// home directories of users, are not listed.
+func Locations(opts Options) []Location {
+	recorder := &locationRecorder{seen: make(map[Location]bool)}
+
+	opts.ctx = nil
+	opts.state = nil
+	opts.fs = recordingFileSystem{recorder}
+	opts = withRecordingRegistry(opts, recorder)
+
+	for _, s := range registeredScanners() {
+		if opts.UserScopeOnly && s.machineOnly {
+			continue
+		}
+
+		recorder.category = s.name
+		opts.category = s.name
+		s.fn(opts)
+	}
+
+	return recorder.locations
+}
@@ -0,0 +1,16 @@
+package autoruns
+
+// Logger receives debug messages about what a scan looks at, such as the
+// keys and files it opens and the entries it skips along with why.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+}
+
+// debugf logs a debug message to the Logger of the options, if any.
+func (o Options) debugf(format string, args ...interface{}) {
+	if o.Logger == nil {
+		return
+	}
+
+	o.Logger.Debugf(format, args...)
+}
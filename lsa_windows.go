@@ -0,0 +1,81 @@
+//+build windows
+
+package autoruns
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// The LSA extensions Windows registers by default, by file name.
+var defaultLsaExtensions = map[string]bool{
+	"dpapisrv.dll":  true,
+	"efslsaext.dll": true,
+	"lsaadt.dll":    true,
+	"cloudap.dll":   true,
+	"negoexts.dll":  true,
+	"kerberos.dll":  true,
+	"msv1_0.dll":    true,
+}
+
+// This function enumerates the LSA extensions, which are loaded into the
+// protected LSASS process. Each subkey of LsaExtensionConfig lists them in
+// an Extensions value, and Lsa\OSConfig can list more in LsaExtensions.
+// DLLs given by name are resolved relative to System32.
+func windowsGetLsaExtensions(opts Options) (records []*Autorun) {
+	var reg registry.Key = registry.LOCAL_MACHINE
+	var extensionConfigKey string = "System\\CurrentControlSet\\Control\\LsaExtensionConfig"
+	var osConfigKey string = "System\\CurrentControlSet\\Control\\Lsa\\OSConfig"
+
+	type extensionList struct {
+		keyName   string
+		valueName string
+	}
+	lists := []extensionList{{osConfigKey, "LsaExtensions"}}
+
+	// Open registry key.
+	if key, err := openKey(opts, reg, extensionConfigKey); err == nil {
+		names, _ := key.ReadSubKeyNames(0)
+		key.Close()
+
+		for _, name := range names {
+			lists = append(lists, extensionList{fmt.Sprintf("%s\\%s", extensionConfigKey, name), "Extensions"})
+		}
+	}
+
+	for _, list := range lists {
+		key, err := openKey(opts, reg, list.keyName)
+		if err != nil {
+			continue
+		}
+
+		// The list holds one DLL per string.
+		dlls, _, err := key.GetStringsValue(list.valueName)
+		key.Close()
+		if err != nil {
+			continue
+		}
+
+		imageLocation := fmt.Sprintf("%s\\%s", registryToString(reg), list.keyName)
+		for _, dll := range dlls {
+			dll = strings.TrimSpace(dll)
+			if dll == "" {
+				continue
+			}
+
+			// We pass the resolved DLL path to a function to return an Autorun.
+			newAutorun := stringToAutorun(opts, "lsa_extension", imageLocation, systemDLLPath(dll), false, filepath.Base(dll))
+			newAutorun.RawName = list.valueName
+			newAutorun.LaunchString = dll
+			newAutorun.NonDefault = !defaultLsaExtensions[strings.ToLower(filepath.Base(dll))]
+
+			// Add the new autorun to the records.
+			records = append(records, newAutorun)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,8 @@
+//+build !windows
+
+package autoruns
+
+// Masquerading is only checked for Windows system binaries.
+func masquerades(imagePath string) bool {
+	return false
+}
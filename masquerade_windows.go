@@ -0,0 +1,126 @@
+//+build windows
+
+package autoruns
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Well-known system binaries, which malware likes to be named after, and
+// the directories below %SystemRoot% they belong in. Binaries which also
+// exist in a 32-bit flavor are allowed in SysWOW64 as well.
+var systemBinaryDirs = map[string][]string{
+	"csrss.exe":     {"System32"},
+	"ctfmon.exe":    {"System32", "SysWOW64"},
+	"conhost.exe":   {"System32"},
+	"dllhost.exe":   {"System32", "SysWOW64"},
+	"explorer.exe":  {"", "SysWOW64"},
+	"lsass.exe":     {"System32"},
+	"lsm.exe":       {"System32"},
+	"rundll32.exe":  {"System32", "SysWOW64"},
+	"services.exe":  {"System32"},
+	"smss.exe":      {"System32"},
+	"spoolsv.exe":   {"System32"},
+	"svchost.exe":   {"System32", "SysWOW64"},
+	"taskhost.exe":  {"System32"},
+	"taskhostw.exe": {"System32"},
+	"wininit.exe":   {"System32"},
+	"winlogon.exe":  {"System32"},
+}
+
+// Characters which look like the Latin letters system binaries are named
+// with: digits and Cyrillic and Greek letters.
+var homoglyphs = strings.NewReplacer(
+	"0", "o", "1", "l",
+	"\u0430", "a", "\u0435", "e", "\u0456", "i", "\u043e", "o", "\u0440", "p",
+	"\u0441", "c", "\u0443", "y", "\u0445", "x", "\u03bf", "o",
+)
+
+// The shortest names, without extension, whose look-alikes are flagged.
+// Shorter names, such as smss, are too close to too many others.
+const minLookAlikeLength = 6
+
+// lookAlike checks whether an image name imitates a well-known system
+// binary without being named after it: by replacing letters with
+// homoglyphs, or by adding, removing, replacing or swapping a single
+// character.
+func lookAlike(name string) bool {
+	if _, ok := systemBinaryDirs[name]; ok || !strings.HasSuffix(name, ".exe") {
+		return false
+	}
+
+	if _, ok := systemBinaryDirs[homoglyphs.Replace(name)]; ok {
+		return true
+	}
+	for binary := range systemBinaryDirs {
+		base := strings.TrimSuffix(binary, ".exe")
+		if len(base) >= minLookAlikeLength && editDistance(strings.TrimSuffix(name, ".exe"), base) == 1 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// editDistance returns how many characters have to be added, removed,
+// replaced or swapped with their neighbor to turn a into b.
+func editDistance(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	// d[i][j] is the distance between the first i runes of a and the first
+	// j runes of b.
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = d[i-1][j-1] + cost
+			if d[i-1][j]+1 < d[i][j] {
+				d[i][j] = d[i-1][j] + 1
+			}
+			if d[i][j-1]+1 < d[i][j] {
+				d[i][j] = d[i][j-1] + 1
+			}
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] && d[i-2][j-2]+1 < d[i][j] {
+				d[i][j] = d[i-2][j-2] + 1
+			}
+		}
+	}
+
+	return d[len(ra)][len(rb)]
+}
+
+// masquerades checks whether an image is named after a well-known system
+// binary but is not located where that binary belongs, or is named like
+// one without being it, such as svch0st.exe or scvhost.exe.
+func masquerades(imagePath string) bool {
+	name := strings.ToLower(filepath.Base(imagePath))
+	if lookAlike(name) {
+		return true
+	}
+	dirs, ok := systemBinaryDirs[name]
+	if !ok {
+		return false
+	}
+
+	systemRoot := os.Getenv("SystemRoot")
+	imageDir := filepath.Dir(imagePath)
+	for _, dir := range dirs {
+		if strings.EqualFold(imageDir, filepath.Join(systemRoot, dir)) {
+			return false
+		}
+	}
+
+	return true
+}
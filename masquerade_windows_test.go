@@ -0,0 +1,66 @@
+//+build windows
+
+package autoruns
+
+import "testing"
+
+func TestMasquerades(t *testing.T) {
+	t.Setenv("SystemRoot", `C:\Windows`)
+
+	tests := []struct {
+		imagePath string
+		want      bool
+	}{
+		{`C:\Windows\System32\svchost.exe`, false},
+		{`c:\windows\system32\SVCHOST.EXE`, false},
+		{`C:\Windows\SysWOW64\svchost.exe`, false},
+		{`C:\Windows\explorer.exe`, false},
+		{`C:\Windows\System32\taskhost.exe`, false},
+		{`C:\Program Files\Agent\agent.exe`, false},
+		// Names which only resemble short system binaries are common.
+		{`C:\Tools\sms.exe`, false},
+		{`C:\Tools\lsm.dll`, false},
+
+		// In the wrong directory.
+		{`C:\Users\Public\svchost.exe`, true},
+		{`C:\Windows\svchost.exe`, true},
+		{`C:\Windows\SysWOW64\lsass.exe`, true},
+		{`C:\Windows\System32\Tasks\services.exe`, true},
+		// Homoglyphs.
+		{`C:\Windows\System32\svch0st.exe`, true},
+		{`C:\Users\Public\expl0rer.exe`, true},
+		{"C:\\Windows\\System32\\\u0441srss.exe", true},
+		{`C:\Windows\System32\wlnlogon.exe`, true},
+		// A character added, removed, replaced or swapped.
+		{`C:\Windows\System32\svchosts.exe`, true},
+		{`C:\Windows\System32\svhost.exe`, true},
+		{`C:\Windows\System32\scvhost.exe`, true},
+		{`C:\Windows\System32\svchast.exe`, true},
+		{`C:\ProgramData\rundl32.exe`, true},
+	}
+	for _, test := range tests {
+		if got := masquerades(test.imagePath); got != test.want {
+			t.Errorf("masquerades(%q) = %v, want %v", test.imagePath, got, test.want)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"svchost", "svchost", 0},
+		{"svchosts", "svchost", 1},
+		{"svhost", "svchost", 1},
+		{"scvhost", "svchost", 1},
+		{"svchast", "svchost", 1},
+		{"svchst.", "svchost", 2},
+		{"", "lsass", 5},
+	}
+	for _, test := range tests {
+		if got := editDistance(test.a, test.b); got != test.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+//+build !windows
+
+package autoruns
+
+// Media types are only determined on Windows.
+func mediaType(path string) string {
+	return ""
+}
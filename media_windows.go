@@ -0,0 +1,53 @@
+//+build windows
+
+package autoruns
+
+import (
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+// The media types reported for the drive types of GetDriveType.
+var driveMediaTypes = map[uint32]string{
+	windows.DRIVE_REMOVABLE: "removable",
+	windows.DRIVE_FIXED:     "fixed",
+	windows.DRIVE_REMOTE:    "network",
+	windows.DRIVE_CDROM:     "cdrom",
+	windows.DRIVE_RAMDISK:   "ramdisk",
+}
+
+// mediaType returns the type of the volume a path is on, or an empty
+// string if the path has no drive letter or share to look up.
+func mediaType(path string) string {
+	return volumeMediaType(filepath.VolumeName(path), func(root string) uint32 {
+		rootPtr, err := windows.UTF16PtrFromString(root)
+		if err != nil {
+			return windows.DRIVE_UNKNOWN
+		}
+
+		return windows.GetDriveType(rootPtr)
+	})
+}
+
+// volumeMediaType returns the media type of a volume as returned by
+// filepath.VolumeName. Shares are on the network, and the type of a drive
+// letter is looked up with driveType, given the root of the drive.
+func volumeMediaType(volume string, driveType func(root string) uint32) string {
+	if strings.HasPrefix(volume, `\\?\`) || strings.HasPrefix(volume, `\\.\`) {
+		volume = volume[4:]
+		if strings.HasPrefix(strings.ToUpper(volume), `UNC\`) {
+			return "network"
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(volume, `\\`):
+		return "network"
+	case len(volume) == 2 && volume[1] == ':':
+		return driveMediaTypes[driveType(volume+`\`)]
+	}
+
+	return ""
+}
@@ -0,0 +1,48 @@
+//+build windows
+
+package autoruns
+
+import (
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/windows"
+)
+
+func TestVolumeMediaType(t *testing.T) {
+	driveTypes := map[string]uint32{
+		`C:\`: windows.DRIVE_FIXED,
+		`E:\`: windows.DRIVE_REMOVABLE,
+		`N:\`: windows.DRIVE_REMOTE,
+		`R:\`: windows.DRIVE_CDROM,
+		`X:\`: windows.DRIVE_NO_ROOT_DIR,
+	}
+	driveType := func(root string) uint32 {
+		if _, ok := driveTypes[root]; !ok {
+			t.Errorf("drive type of %q looked up", root)
+		}
+		return driveTypes[root]
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{`C:\Windows\System32\svchost.exe`, "fixed"},
+		{`E:\autorun.exe`, "removable"},
+		{`N:\Tools\agent.exe`, "network"},
+		{`R:\setup.exe`, "cdrom"},
+		// A drive letter which is not mapped.
+		{`X:\agent.exe`, ""},
+		{`\\server\share\agent.exe`, "network"},
+		{`\\?\UNC\server\share\agent.exe`, "network"},
+		{`\\?\C:\Windows\notepad.exe`, "fixed"},
+		{`agent.exe`, ""},
+		{`\Windows\notepad.exe`, ""},
+	}
+	for _, test := range tests {
+		if got := volumeMediaType(filepath.VolumeName(test.path), driveType); got != test.want {
+			t.Errorf("media type of %q = %q, want %q", test.path, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+//+build linux
+
+package autoruns
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// The directory of the scripts which generate the message of the day.
+var motdScriptsDir string = "/etc/update-motd.d"
+
+// This function enumerates the scripts pam_motd runs as root to generate
+// the message of the day, on every login over SSH or the console. Like
+// run-parts, which runs them, we skip the files which are not executable.
+func linuxGetMotdScripts(opts Options) (records []*Autorun) {
+	scripts, err := fileSystemFor(opts).ReadDir(motdScriptsDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			opts.Warn(motdScriptsDir, err)
+		}
+		return
+	}
+
+	for _, script := range scripts {
+		if script.IsDir() || script.Mode().Perm()&0111 == 0 {
+			continue
+		}
+
+		scriptPath := filepath.Join(motdScriptsDir, script.Name())
+		records = append(records, &Autorun{
+			Type:         "motd_script",
+			Location:     motdScriptsDir,
+			ImagePath:    scriptPath,
+			ImageName:    script.Name(),
+			Entry:        script.Name(),
+			RawName:      script.Name(),
+			LaunchString: scriptPath,
+			Trigger:      "logon",
+		})
+	}
+
+	return
+}
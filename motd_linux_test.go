@@ -0,0 +1,20 @@
+//+build linux
+
+package autoruns
+
+import "testing"
+
+func TestLinuxGetMotdScripts(t *testing.T) {
+	fsys := newFakeFileSystem(nil)
+	fsys.add("/etc/update-motd.d/00-header", "#!/bin/sh\n", 0755)
+	fsys.add("/etc/update-motd.d/50-disabled", "#!/bin/sh\n", 0644)
+	fsys.add("/etc/update-motd.d/old/99-footer", "#!/bin/sh\n", 0755)
+
+	records := linuxGetMotdScripts(Options{fs: fsys})
+	if len(records) != 1 {
+		t.Fatalf("got %d records, want only the executable script: %+v", len(records), records)
+	}
+	if record := records[0]; record.ImagePath != "/etc/update-motd.d/00-header" || record.RawName != "00-header" || record.Trigger != "logon" {
+		t.Errorf("got %+v", record)
+	}
+}
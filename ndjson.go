@@ -0,0 +1,51 @@
+package autoruns
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// WriteNDJSON writes records to w as newline-delimited JSON, one object per
+// line.
+func WriteNDJSON(w io.Writer, records []*Autorun) error {
+	encoder := json.NewEncoder(w)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// StreamNDJSON writes records received from the channel to w as
+// newline-delimited JSON until the channel is closed. If w is buffered
+// (e.g. a *bufio.Writer or an http.ResponseWriter), it is flushed after
+// every record so that an interrupted upload still delivers the records
+// written so far. StreamNDJSON returns on the first error without
+// draining the channel.
+func StreamNDJSON(w io.Writer, records <-chan *Autorun) error {
+	encoder := json.NewEncoder(w)
+	for record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+		if err := flush(w); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// flush flushes w if it supports either of the common Flush signatures.
+func flush(w io.Writer) error {
+	switch f := w.(type) {
+	case interface{ Flush() error }:
+		return f.Flush()
+	case interface{ Flush() }:
+		f.Flush()
+	}
+
+	return nil
+}
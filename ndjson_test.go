@@ -0,0 +1,111 @@
+package autoruns
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testRecords returns records with most fields set, including times with
+// nanoseconds.
+func testRecords() []*Autorun {
+	modified := time.Date(2024, 3, 1, 12, 30, 45, 123456789, time.UTC)
+	return []*Autorun{
+		{
+			Type:          "run_key",
+			Location:      `LOCAL_MACHINE\Software\Microsoft\Windows\CurrentVersion\Run`,
+			Source:        Source{Kind: "registry_value", Root: "LOCAL_MACHINE", Key: `Software\Microsoft\Windows\CurrentVersion\Run`, Value: "Agent", View: "64"},
+			LastModified:  modified,
+			ImagePath:     `C:\Program Files\Agent\agent.exe`,
+			ImageName:     "agent.exe",
+			Arguments:     `--tray "quoted arg"`,
+			ArgumentsList: []string{"--tray", "quoted arg"},
+			SHA256:        "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+			Entropy:       6.25,
+			Entry:         "Agent",
+			RawName:       "Agent",
+			Parsed:        true,
+			CompileTime:   time.Date(2020, 2, 3, 4, 5, 6, 0, time.UTC),
+			Signed:        true,
+			Signature: &Signature{
+				Signer:    "Vendor",
+				NotBefore: modified.AddDate(-1, 0, 0),
+				NotAfter:  modified.AddDate(1, 0, 0),
+			},
+			Scope:            "machine",
+			Suspicion:        25,
+			SuspicionReasons: []string{"unsigned"},
+			Flags:            []string{FlagUnsigned},
+		},
+		{Type: "cron", Location: "/etc/crontab", Entry: "/usr/bin/startup", LaunchString: "/usr/bin/startup"},
+	}
+}
+
+func TestWriteNDJSONRoundTrip(t *testing.T) {
+	records := testRecords()
+	var buf bytes.Buffer
+	if err := WriteNDJSON(&buf, records); err != nil {
+		t.Fatalf("WriteNDJSON: %v", err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != len(records) {
+		t.Errorf("wrote %d lines, want one per record", lines)
+	}
+
+	var decoded []*Autorun
+	decoder := json.NewDecoder(&buf)
+	for {
+		var record Autorun
+		if err := decoder.Decode(&record); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Decode: %v", err)
+		}
+		decoded = append(decoded, &record)
+	}
+	if !reflect.DeepEqual(decoded, records) {
+		t.Errorf("read back\n%+v\nwant\n%+v", decoded, records)
+	}
+}
+
+// flushRecorder records what was written to it whenever it is flushed.
+type flushRecorder struct {
+	bytes.Buffer
+	flushed []string
+}
+
+func (f *flushRecorder) Flush() error {
+	f.flushed = append(f.flushed, f.String())
+	return nil
+}
+
+func TestStreamNDJSONFlushesEveryRecord(t *testing.T) {
+	records := testRecords()
+	channel := make(chan *Autorun, len(records))
+	for _, record := range records {
+		channel <- record
+	}
+	close(channel)
+
+	var w flushRecorder
+	if err := StreamNDJSON(&w, channel); err != nil {
+		t.Fatalf("StreamNDJSON: %v", err)
+	}
+	if len(w.flushed) != len(records) {
+		t.Fatalf("flushed %d times, want once per record", len(w.flushed))
+	}
+	for i, flushed := range w.flushed {
+		if lines := strings.Count(flushed, "\n"); lines != i+1 {
+			t.Errorf("flush %d delivered %d records, want %d", i, lines, i+1)
+		}
+	}
+
+	var expected bytes.Buffer
+	WriteNDJSON(&expected, records)
+	if w.String() != expected.String() {
+		t.Errorf("StreamNDJSON wrote\n%s\nwant the same as WriteNDJSON\n%s", w.String(), expected.String())
+	}
+}
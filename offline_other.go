@@ -0,0 +1,14 @@
+//+build !windows
+
+package autoruns
+
+import "errors"
+
+// Offline images can only be scanned on Windows.
+func connectImage(opts *Options) error {
+	if opts.ImageRoot != "" {
+		return errors.New("autoruns: offline images can only be scanned on Windows")
+	}
+
+	return nil
+}
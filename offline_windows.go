@@ -0,0 +1,151 @@
+//+build windows
+
+package autoruns
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// offlineRegistry reads the SOFTWARE and SYSTEM hives of an offline image,
+// loaded under USERS as the keys software and system of hives.
+// CurrentControlSet is a link the system creates at boot, so in the SYSTEM
+// hive of an image it is resolved to the control set it would have pointed
+// to.
+type offlineRegistry struct {
+	hives      registryReader
+	software   string
+	system     string
+	controlSet string
+}
+
+func (r offlineRegistry) OpenKey(reg registry.Key, path string) (registryKey, error) {
+	var root string
+	switch reg {
+	case registry.LOCAL_MACHINE:
+		hive, rest := path, ""
+		if i := strings.Index(path, "\\"); i >= 0 {
+			hive, rest = path[:i], path[i+1:]
+		}
+
+		switch strings.ToLower(hive) {
+		case "software":
+			root, path = r.software, rest
+		case "system":
+			root, path = r.system, r.resolveControlSet(rest)
+		default:
+			return nil, registry.ErrNotExist
+		}
+	case registry.CLASSES_ROOT:
+		root, path = r.software, "Classes\\"+path
+	default:
+		return nil, errOutOfScope
+	}
+
+	if path != "" {
+		root += "\\" + path
+	}
+	return r.hives.OpenKey(registry.USERS, root)
+}
+
+// resolveControlSet replaces a leading CurrentControlSet in a path of the
+// SYSTEM hive by the control set of the image, e.g. ControlSet002.
+func (r offlineRegistry) resolveControlSet(path string) string {
+	const currentControlSet = "currentcontrolset"
+
+	lowerPath := strings.ToLower(path)
+	if lowerPath == currentControlSet || strings.HasPrefix(lowerPath, currentControlSet+"\\") {
+		return r.controlSet + path[len(currentControlSet):]
+	}
+
+	return path
+}
+
+// currentControlSet reads which control set of the SYSTEM hive loaded
+// under USERS as system is the current one from Select\Current.
+func currentControlSet(hives registryReader, system string) (string, error) {
+	key, err := hives.OpenKey(registry.USERS, system+"\\Select")
+	if err != nil {
+		return "", err
+	}
+	defer key.Close()
+
+	current, _, err := key.GetIntegerValue("Current")
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("ControlSet%03d", current), nil
+}
+
+// mountImageHive loads a hive of the image under USERS until the scan is
+// done, and returns the name of its key.
+func mountImageHive(opts Options, name string) (string, error) {
+	hiveFile := filepath.Join(opts.ImageRoot, "Windows", "System32", "config", name)
+	mountName := "autoruns_image_" + name
+	if err := loadHive(mountName, hiveFile); err != nil {
+		return "", fmt.Errorf("autoruns: loading %s: %w", hiveFile, err)
+	}
+	opts.onDone(func() {
+		unloadHive(mountName)
+	})
+
+	return mountName, nil
+}
+
+// connectImage loads the hives of Options.ImageRoot and makes opts read
+// from them and from the files of the image. The hives are unloaded once
+// the scan is done.
+func connectImage(opts *Options) error {
+	if opts.ImageRoot == "" {
+		return nil
+	}
+	switch {
+	case isRemote(*opts):
+		return errors.New("autoruns: an offline image cannot be scanned on a remote host")
+	case opts.UserScopeOnly || opts.ScanUserHives:
+		return errors.New("autoruns: an offline image has no user scope and its user hives are not loaded")
+	case opts.AllowRemediation:
+		return errors.New("autoruns: the records of an offline image cannot be remediated")
+	}
+
+	if err := enableHivePrivileges(); err != nil {
+		return fmt.Errorf("autoruns: loading the hives of %s: %w", opts.ImageRoot, err)
+	}
+	software, err := mountImageHive(*opts, "SOFTWARE")
+	if err != nil {
+		return err
+	}
+	system, err := mountImageHive(*opts, "SYSTEM")
+	if err != nil {
+		return err
+	}
+	controlSet, err := currentControlSet(systemRegistry{}, system)
+	if err != nil {
+		return fmt.Errorf("autoruns: reading the current control set of %s: %w", opts.ImageRoot, err)
+	}
+
+	root := opts.ImageRoot
+	opts.registry = offlineRegistry{hives: systemRegistry{}, software: software, system: system, controlSet: controlSet}
+	opts.fs = mappedFileSystem{mapPath: func(name string) (string, error) {
+		return imageRootPath(root, name)
+	}}
+
+	return nil
+}
+
+// imageRootPath returns the path of a file of an offline image mounted at
+// root. Only the files on the system drive of the image can be read.
+func imageRootPath(root string, name string) (string, error) {
+	volume := filepath.VolumeName(name)
+	if !strings.EqualFold(volume, os.Getenv("SystemDrive")) {
+		return "", &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+
+	return filepath.Join(root, name[len(volume):]), nil
+}
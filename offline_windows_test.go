@@ -0,0 +1,81 @@
+//+build windows
+
+package autoruns
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+func TestOfflineRegistryControlSet(t *testing.T) {
+	t.Setenv("SystemRoot", `C:\Windows`)
+	system := `USERS\autoruns_image_SYSTEM\`
+	hives := fakeRegistry{
+		system + "Select": {"Current": uint32(2), "Default": uint32(1)},
+		system + `ControlSet001\Services\Stale`: {
+			"ImagePath": `C:\Windows\stale.exe`,
+			"Type":      uint32(0x10),
+			"Start":     uint32(2),
+		},
+		system + `ControlSet002\Services\Current`: {
+			"ImagePath": `C:\Windows\current.exe`,
+			"Type":      uint32(0x10),
+			"Start":     uint32(2),
+		},
+		`USERS\autoruns_image_SOFTWARE\Microsoft\Windows\CurrentVersion\Run`: {
+			"Agent": `C:\Windows\agent.exe`,
+		},
+	}
+
+	controlSet, err := currentControlSet(hives, "autoruns_image_SYSTEM")
+	if err != nil || controlSet != "ControlSet002" {
+		t.Fatalf("currentControlSet = %q, %v, want ControlSet002", controlSet, err)
+	}
+
+	opts := Options{state: newScanState(), fs: newFakeFileSystem(nil)}
+	opts.registry = offlineRegistry{
+		hives:      hives,
+		software:   "autoruns_image_SOFTWARE",
+		system:     "autoruns_image_SYSTEM",
+		controlSet: controlSet,
+	}
+
+	records := recordsByEntry(windowsGetServices(opts))
+	if len(records) != 1 || records["Current"] == nil {
+		t.Errorf("got services %v, want only those of ControlSet002", records)
+	}
+	if record := records["Current"]; record != nil && record.Location != `LOCAL_MACHINE\System\CurrentControlSet\Services\Current` {
+		t.Errorf("got Location %q", record.Location)
+	}
+
+	key, err := opts.registry.OpenKey(registry.LOCAL_MACHINE, `Software\Microsoft\Windows\CurrentVersion\Run`)
+	if err != nil {
+		t.Fatalf("opening a key of SOFTWARE: %v", err)
+	}
+	if value, _, err := key.GetStringValue("Agent"); err != nil || value != `C:\Windows\agent.exe` {
+		t.Errorf("GetStringValue = %q, %v", value, err)
+	}
+	key.Close()
+
+	// Only the hives of the image are loaded.
+	if _, err := opts.registry.OpenKey(registry.CURRENT_USER, "Software"); !errors.Is(err, errOutOfScope) {
+		t.Errorf("opening a key of CURRENT_USER: %v", err)
+	}
+}
+
+func TestCurrentControlSetWithoutSelect(t *testing.T) {
+	hives := fakeRegistry{
+		`USERS\autoruns_image_SYSTEM\ControlSet001\Services\Beep`: {"Start": uint32(1)},
+	}
+	if controlSet, err := currentControlSet(hives, "autoruns_image_SYSTEM"); err != registry.ErrNotExist {
+		t.Errorf("currentControlSet = %q, %v, want registry.ErrNotExist", controlSet, err)
+	}
+
+	// A Select key without Current is as unusable.
+	hives[`USERS\autoruns_image_SYSTEM\Select`] = fakeValues{"Default": uint32(1)}
+	if controlSet, err := currentControlSet(hives, "autoruns_image_SYSTEM"); err == nil {
+		t.Errorf("currentControlSet = %q without Select\\Current", controlSet)
+	}
+}
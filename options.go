@@ -0,0 +1,381 @@
+package autoruns
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Option configures the Options returned by NewOptions.
+type Option func(opts *Options) error
+
+// NewOptions returns Options configured by the given options, with the
+// defaults of all settings filled in. Unlike setting the fields of Options
+// directly, it rejects invalid values and combinations of settings which
+// contradict each other, such as analyzing images during a quick scan.
+//
+// Without any options, the result is equivalent to the zero Options: every
+// registered scanner runs and images are hashed.
+func NewOptions(options ...Option) (Options, error) {
+	opts := Options{
+		HashBufferSize:    defaultHashBufferSize,
+		MaxInFlightHashes: 1,
+		FileTimeout:       defaultFileTimeout,
+	}
+	opts.RetryAttempts, opts.RetryBackoff = opts.retryPolicy()
+
+	for _, option := range options {
+		if err := option(&opts); err != nil {
+			return Options{}, err
+		}
+	}
+
+	if opts.QuickScan {
+		switch {
+		case opts.VerifySignatures:
+			return Options{}, errors.New("autoruns: signatures cannot be verified during a quick scan")
+		case opts.CheckSideloading:
+			return Options{}, errors.New("autoruns: sideloading cannot be checked during a quick scan")
+		case len(opts.AllowedHashes) > 0:
+			return Options{}, errors.New("autoruns: allowed hashes require hashing, which a quick scan does not do")
+		case opts.ComputeEntropy:
+			return Options{}, errors.New("autoruns: entropy is computed while hashing, which a quick scan does not do")
+		}
+	}
+	if opts.UserScopeOnly && opts.ScanUserHives {
+		return Options{}, errors.New("autoruns: the hives of other users are out of the user scope")
+	}
+	if opts.RemoteHost == "" && opts.RemoteFileAccess {
+		return Options{}, errors.New("autoruns: remote file access requires a remote host")
+	}
+	if opts.RemoteHost != "" && (opts.UserScopeOnly || opts.ScanUserHives) {
+		return Options{}, errors.New("autoruns: a remote scan has no user scope and cannot load the hives of users")
+	}
+	if opts.RemoteHost != "" && opts.AllowRemediation {
+		return Options{}, errors.New("autoruns: the records of a remote scan cannot be remediated")
+	}
+
+	if opts.ImageRoot != "" && (opts.RemoteHost != "" || opts.UserScopeOnly || opts.ScanUserHives || opts.AllowRemediation) {
+		return Options{}, errors.New("autoruns: an offline image is scanned locally, has no user scope and cannot be remediated")
+	}
+
+	return opts, nil
+}
+
+// WithQuickScan only inventories the persistence locations, see
+// Options.QuickScan. It is off by default.
+func WithQuickScan() Option {
+	return func(opts *Options) error {
+		opts.QuickScan = true
+		return nil
+	}
+}
+
+// WithVerifySignatures verifies the signatures of images, see
+// Options.VerifySignatures. It is off by default.
+func WithVerifySignatures() Option {
+	return func(opts *Options) error {
+		opts.VerifySignatures = true
+		return nil
+	}
+}
+
+// WithCheckSideloading checks images for DLL sideloading, see
+// Options.CheckSideloading. It is off by default.
+func WithCheckSideloading() Option {
+	return func(opts *Options) error {
+		opts.CheckSideloading = true
+		return nil
+	}
+}
+
+// WithExecutionHistory also reports what users recently ran, see
+// Options.ExecutionHistory. It is off by default.
+func WithExecutionHistory() Option {
+	return func(opts *Options) error {
+		opts.ExecutionHistory = true
+		return nil
+	}
+}
+
+// WithScoreSuspicion scores how suspicious records are, see
+// Options.ScoreSuspicion. It is off by default.
+func WithScoreSuspicion() Option {
+	return func(opts *Options) error {
+		opts.ScoreSuspicion = true
+		return nil
+	}
+}
+
+// WithFileAssociations sets the file extensions and protocols whose open
+// command is checked, see Options.FileAssociations. It defaults to a set
+// of high-risk extensions and protocols.
+func WithFileAssociations(associations ...string) Option {
+	return func(opts *Options) error {
+		if len(associations) == 0 {
+			return errors.New("autoruns: no file associations given")
+		}
+		opts.FileAssociations = associations
+		return nil
+	}
+}
+
+// WithHashBufferSize sets the size of the buffer images are hashed with,
+// see Options.HashBufferSize. It defaults to 1 MiB.
+func WithHashBufferSize(size int) Option {
+	return func(opts *Options) error {
+		if size <= 0 {
+			return errors.New("autoruns: the hash buffer size must be positive")
+		}
+		opts.HashBufferSize = size
+		return nil
+	}
+}
+
+// WithMaxInFlightHashes sets how many images are hashed at the same time,
+// see Options.MaxInFlightHashes. It defaults to 1.
+func WithMaxInFlightHashes(n int) Option {
+	return func(opts *Options) error {
+		if n <= 0 {
+			return errors.New("autoruns: the number of images hashed at the same time must be positive")
+		}
+		opts.MaxInFlightHashes = n
+		return nil
+	}
+}
+
+// WithFileTimeout sets how long the analysis of a single image may take,
+// see Options.FileTimeout. It defaults to 10s; a negative value disables
+// the timeout.
+func WithFileTimeout(timeout time.Duration) Option {
+	return func(opts *Options) error {
+		if timeout == 0 {
+			return errors.New("autoruns: the file timeout must not be zero")
+		}
+		opts.FileTimeout = timeout
+		return nil
+	}
+}
+
+// WithMaxEntriesPerCategory caps the number of records of each category,
+// see Options.MaxEntriesPerCategory. There is no cap by default.
+func WithMaxEntriesPerCategory(n int) Option {
+	return func(opts *Options) error {
+		if n <= 0 {
+			return errors.New("autoruns: the maximum number of entries must be positive")
+		}
+		opts.MaxEntriesPerCategory = n
+		return nil
+	}
+}
+
+// WithMaxDepth caps how deep scanners descend into nested folders, see
+// Options.MaxDepth. There is no cap by default.
+func WithMaxDepth(depth int) Option {
+	return func(opts *Options) error {
+		if depth <= 0 {
+			return errors.New("autoruns: the maximum depth must be positive")
+		}
+		opts.MaxDepth = depth
+		return nil
+	}
+}
+
+// WithRetries sets how many times opening a registry key is retried after
+// a transient failure and the delay before the first retry, see
+// Options.RetryAttempts. They default to 2 and 50ms; zero attempts disable
+// retries and a zero backoff keeps the default.
+func WithRetries(attempts int, backoff time.Duration) Option {
+	return func(opts *Options) error {
+		if attempts < 0 || backoff < 0 {
+			return errors.New("autoruns: retries must not be negative")
+		}
+		opts.RetryAttempts = attempts
+		if attempts == 0 {
+			opts.RetryAttempts = -1
+		}
+		if backoff > 0 {
+			opts.RetryBackoff = backoff
+		}
+		return nil
+	}
+}
+
+// WithChangedSince makes the scan incremental, carrying the analyses of
+// the records unchanged since the given time forward from previous, see
+// Options.ChangedSince.
+func WithChangedSince(since time.Time, previous []*Autorun) Option {
+	return func(opts *Options) error {
+		if since.IsZero() {
+			return errors.New("autoruns: the time of the previous scan is zero")
+		}
+		opts.ChangedSince = since
+		opts.PreviousRecords = previous
+		return nil
+	}
+}
+
+// WithNetworkImages analyzes images on network shares, see
+// Options.NetworkImages. It is off by default.
+func WithNetworkImages() Option {
+	return func(opts *Options) error {
+		opts.NetworkImages = true
+		return nil
+	}
+}
+
+// WithComputeEntropy computes the entropy of images while hashing them, see
+// Options.ComputeEntropy. It is off by default.
+func WithComputeEntropy() Option {
+	return func(opts *Options) error {
+		opts.ComputeEntropy = true
+		return nil
+	}
+}
+
+// WithRecurseStartupFolders also reports the files in subfolders of the
+// Startup folders, see Options.RecurseStartupFolders. It is off by default.
+func WithRecurseStartupFolders() Option {
+	return func(opts *Options) error {
+		opts.RecurseStartupFolders = true
+		return nil
+	}
+}
+
+// WithSplitArguments sets the ArgumentsList of every record, see
+// Options.SplitArguments. It is off by default.
+func WithSplitArguments() Option {
+	return func(opts *Options) error {
+		opts.SplitArguments = true
+		return nil
+	}
+}
+
+// WithUnsorted returns the records in the order they are found, see
+// Options.Unsorted. Records are sorted by default.
+func WithUnsorted() Option {
+	return func(opts *Options) error {
+		opts.Unsorted = true
+		return nil
+	}
+}
+
+// WithRecordTimings measures how long each category takes, see
+// Options.RecordTimings. It is off by default.
+func WithRecordTimings() Option {
+	return func(opts *Options) error {
+		opts.RecordTimings = true
+		return nil
+	}
+}
+
+// WithResolveUsers sets the account per-user records belong to, see
+// Options.ResolveUsers. It is off by default.
+func WithResolveUsers() Option {
+	return func(opts *Options) error {
+		opts.ResolveUsers = true
+		return nil
+	}
+}
+
+// WithScanUserHives also scans the locations of other users, see
+// Options.ScanUserHives. It is off by default.
+func WithScanUserHives() Option {
+	return func(opts *Options) error {
+		opts.ScanUserHives = true
+		return nil
+	}
+}
+
+// WithUserScopeOnly restricts the scan to the locations of the current
+// user, see Options.UserScopeOnly. It is off by default.
+func WithUserScopeOnly() Option {
+	return func(opts *Options) error {
+		opts.UserScopeOnly = true
+		return nil
+	}
+}
+
+// WithUserScopeUnlessElevated sets Options.UserScopeOnly if the process
+// does not run with administrative rights, see IsElevated, so that an
+// unprivileged scan only covers what it can read.
+func WithUserScopeUnlessElevated() Option {
+	return func(opts *Options) error {
+		if !IsElevated() {
+			opts.UserScopeOnly = true
+		}
+		return nil
+	}
+}
+
+// WithAllowedHashes leaves out records whose image has one of the given
+// SHA256 hashes, see Options.AllowedHashes. Nothing is left out by default.
+func WithAllowedHashes(hashes ...string) Option {
+	return func(opts *Options) error {
+		if opts.AllowedHashes == nil {
+			opts.AllowedHashes = make(map[string]bool)
+		}
+		for _, hash := range hashes {
+			if len(hash) != 64 || strings.Trim(strings.ToLower(hash), "0123456789abcdef") != "" {
+				return errors.New("autoruns: invalid SHA256 hash " + hash)
+			}
+			opts.AllowedHashes[strings.ToLower(hash)] = true
+		}
+		return nil
+	}
+}
+
+// WithImageRootForRegistry scans the offline Windows installation mounted
+// at root, reading the registry from its hives, see Options.ImageRoot.
+func WithImageRootForRegistry(root string) Option {
+	return func(opts *Options) error {
+		if root == "" {
+			return errors.New("autoruns: empty image root")
+		}
+		opts.ImageRoot = root
+		return nil
+	}
+}
+
+// WithRemoteHost scans another Windows machine through its remote
+// registry, see Options.RemoteHost.
+func WithRemoteHost(host string) Option {
+	return func(opts *Options) error {
+		if strings.TrimPrefix(host, "\\\\") == "" {
+			return errors.New("autoruns: the remote host is empty")
+		}
+		opts.RemoteHost = host
+		return nil
+	}
+}
+
+// WithRemoteFileAccess analyzes the images of a remote scan over the
+// administrative shares, see Options.RemoteFileAccess. It is off by
+// default.
+func WithRemoteFileAccess() Option {
+	return func(opts *Options) error {
+		opts.RemoteFileAccess = true
+		return nil
+	}
+}
+
+// WithAllowRemediation allows the records of the scan to be disabled or
+// removed, see Options.AllowRemediation. It is off by default.
+func WithAllowRemediation() Option {
+	return func(opts *Options) error {
+		opts.AllowRemediation = true
+		return nil
+	}
+}
+
+// WithLogger sets the logger receiving debug messages, see
+// Options.Logger. Nothing is logged by default.
+func WithLogger(logger Logger) Option {
+	return func(opts *Options) error {
+		if logger == nil {
+			return errors.New("autoruns: the logger is nil")
+		}
+		opts.Logger = logger
+		return nil
+	}
+}
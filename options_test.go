@@ -0,0 +1,91 @@
+package autoruns
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewOptionsRejectsContradictions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+	}{
+		{"remediating a remote scan", []Option{WithRemoteHost("host"), WithAllowRemediation()}},
+		{"remediating an offline image", []Option{WithImageRootForRegistry("/mnt/image"), WithAllowRemediation()}},
+		{"verifying signatures during a quick scan", []Option{WithQuickScan(), WithVerifySignatures()}},
+		{"remote file access without a remote host", []Option{WithRemoteFileAccess()}},
+		{"checking sideloading during a quick scan", []Option{WithQuickScan(), WithCheckSideloading()}},
+		{"computing entropy during a quick scan", []Option{WithQuickScan(), WithComputeEntropy()}},
+		{"loading the hives of users in the user scope", []Option{WithUserScopeOnly(), WithScanUserHives()}},
+		{"a remote scan in the user scope", []Option{WithRemoteHost("host"), WithUserScopeOnly()}},
+		{"a zero hash buffer", []Option{WithHashBufferSize(0)}},
+		{"no images hashed at the same time", []Option{WithMaxInFlightHashes(0)}},
+		{"a zero file timeout", []Option{WithFileTimeout(0)}},
+		{"a negative maximum of entries", []Option{WithMaxEntriesPerCategory(-1)}},
+		{"a zero maximum depth", []Option{WithMaxDepth(0)}},
+		{"negative retries", []Option{WithRetries(-1, 0)}},
+		{"allowing hashes during a quick scan", []Option{WithQuickScan(), WithAllowedHashes(strings.Repeat("a", 64))}},
+	}
+
+	for _, test := range tests {
+		if _, err := NewOptions(test.options...); err == nil {
+			t.Errorf("%s: NewOptions did not return an error", test.name)
+		}
+	}
+}
+
+func TestNewOptionsDefaults(t *testing.T) {
+	opts, err := NewOptions()
+	if err != nil {
+		t.Fatalf("NewOptions: %v", err)
+	}
+	if opts.QuickScan || opts.UserScopeOnly || opts.MaxEntriesPerCategory != 0 || opts.MaxDepth != 0 {
+		t.Errorf("NewOptions() limits the scan: %+v", opts)
+	}
+	if opts.HashBufferSize != 1<<20 || opts.MaxInFlightHashes != 1 || opts.FileTimeout != 10*time.Second {
+		t.Errorf("got HashBufferSize %d, MaxInFlightHashes %d, FileTimeout %v", opts.HashBufferSize, opts.MaxInFlightHashes, opts.FileTimeout)
+	}
+	if opts.RetryAttempts != 2 || opts.RetryBackoff != 50*time.Millisecond {
+		t.Errorf("got RetryAttempts %d, RetryBackoff %v", opts.RetryAttempts, opts.RetryBackoff)
+	}
+
+	opts, err = NewOptions(WithRetries(0, 0), WithFileTimeout(-1), WithMaxInFlightHashes(4))
+	if err != nil {
+		t.Fatalf("NewOptions: %v", err)
+	}
+	// Zero retries disable them, and keep the default backoff.
+	if opts.RetryAttempts != -1 || opts.RetryBackoff != 50*time.Millisecond {
+		t.Errorf("got RetryAttempts %d, RetryBackoff %v", opts.RetryAttempts, opts.RetryBackoff)
+	}
+	if opts.FileTimeout != -1 || opts.MaxInFlightHashes != 4 {
+		t.Errorf("got FileTimeout %v, MaxInFlightHashes %d", opts.FileTimeout, opts.MaxInFlightHashes)
+	}
+}
+
+func TestNewOptionsAcceptsRemoteScan(t *testing.T) {
+	opts, err := NewOptions(WithRemoteHost("host"), WithRemoteFileAccess())
+	if err != nil {
+		t.Fatalf("NewOptions: %v", err)
+	}
+	if opts.RemoteHost != "host" || !opts.RemoteFileAccess || opts.AllowRemediation {
+		t.Errorf("NewOptions returned %+v", opts)
+	}
+}
+
+func TestWithAllowedHashes(t *testing.T) {
+	hash := "BA7816BF8F01CFEA414140DE5DAE2223B00361A396177A9CB410FF61F20015AD"
+	opts, err := NewOptions(WithAllowedHashes(hash))
+	if err != nil {
+		t.Fatalf("NewOptions: %v", err)
+	}
+	if len(opts.AllowedHashes) != 1 || !opts.AllowedHashes[strings.ToLower(hash)] {
+		t.Errorf("AllowedHashes = %v, want the lowercase hash", opts.AllowedHashes)
+	}
+
+	for _, invalid := range []string{"", "abc", strings.Repeat("g", 64), hash + "00"} {
+		if _, err := NewOptions(WithAllowedHashes(invalid)); err == nil {
+			t.Errorf("WithAllowedHashes(%q) did not return an error", invalid)
+		}
+	}
+}
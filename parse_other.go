@@ -0,0 +1,14 @@
+//+build !windows
+
+package autoruns
+
+import (
+	"errors"
+)
+
+// ParseCommandLine splits a Windows command line into the executable it
+// launches and its arguments. The executable is resolved against the local
+// file system, so this is only supported on Windows.
+func ParseCommandLine(command string) (imagePath string, arguments string, err error) {
+	return "", "", errors.New("autoruns: ParseCommandLine is only supported on Windows")
+}
@@ -0,0 +1,11 @@
+//+build !windows
+
+package autoruns
+
+import "testing"
+
+func TestParseCommandLineUnsupported(t *testing.T) {
+	if _, _, err := ParseCommandLine("/usr/bin/true"); err == nil {
+		t.Error("ParseCommandLine succeeded on a platform without Windows command lines")
+	}
+}
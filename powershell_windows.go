@@ -0,0 +1,91 @@
+//+build windows
+
+package autoruns
+
+import (
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// The names of the profile scripts PowerShell runs for all hosts and for
+// the console host.
+var powershellProfileNames = []string{
+	"profile.ps1",
+	"Microsoft.PowerShell_profile.ps1",
+}
+
+// This function enumerates the profile scripts PowerShell runs whenever it
+// starts, both for all users and in the Documents folder of every user, for
+// Windows PowerShell and PowerShell 7. The scripts themselves are reported,
+// as there is no executable to resolve.
+func windowsGetPowerShellProfiles(opts Options) (records []*Autorun) {
+	type profileFolder struct {
+		path    string
+		user    string
+		perUser bool
+	}
+
+	var folders []profileFolder
+	if !opts.UserScopeOnly {
+		folders = append(folders,
+			profileFolder{path: filepath.Join(os.Getenv("SystemRoot"), "System32", "WindowsPowerShell", "v1.0")},
+			profileFolder{path: filepath.Join(os.Getenv("ProgramFiles"), "PowerShell", "7")},
+		)
+	}
+
+	// The home directories of all users are next to the one of ours.
+	usersPath := filepath.Dir(os.Getenv("USERPROFILE"))
+	if opts.UserScopeOnly {
+		for _, folder := range []string{"WindowsPowerShell", "PowerShell"} {
+			folders = append(folders, profileFolder{
+				path:    filepath.Join(os.Getenv("USERPROFILE"), "Documents", folder),
+				user:    keyUser(opts, registry.CURRENT_USER),
+				perUser: true,
+			})
+		}
+	} else if homes, err := fileSystemFor(opts).ReadDir(usersPath); err == nil {
+		for _, home := range homes {
+			if !home.IsDir() {
+				continue
+			}
+
+			var user string
+			if opts.ResolveUsers {
+				user = home.Name()
+			}
+			for _, folder := range []string{"WindowsPowerShell", "PowerShell"} {
+				folders = append(folders, profileFolder{
+					path:    filepath.Join(usersPath, home.Name(), "Documents", folder),
+					user:    user,
+					perUser: true,
+				})
+			}
+		}
+	} else {
+		opts.Warn(usersPath, err)
+	}
+
+	for _, folder := range folders {
+		for _, name := range powershellProfileNames {
+			profilePath := filepath.Join(folder.path, name)
+			if _, err := fileSystemFor(opts).Stat(profilePath); err != nil {
+				continue
+			}
+
+			// Instantiate new autorun record.
+			newAutorun := stringToAutorun(opts, "powershell_profile", folder.path, profilePath, false, name)
+			newAutorun.RawName = name
+			newAutorun.User = folder.user
+			if folder.perUser {
+				newAutorun.Scope = "user"
+			}
+
+			// Add new record to list.
+			records = append(records, newAutorun)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,307 @@
+//+build linux
+
+package autoruns
+
+import (
+	"bufio"
+	"bytes"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// The file listing the libraries the dynamic linker loads into every
+// process.
+var ldPreloadFile string = "/etc/ld.so.preload"
+
+// The shell initialization files read by every user, which can set
+// LD_PRELOAD for the sessions they start.
+var systemShellInitFiles = []string{
+	"/etc/environment",
+	"/etc/profile",
+	"/etc/bash.bashrc",
+	"/etc/bashrc",
+	"/etc/zshenv",
+	"/etc/zshrc",
+	"/etc/zsh/zshenv",
+	"/etc/zsh/zprofile",
+	"/etc/zsh/zshrc",
+}
+
+// The directory of scripts sourced by /etc/profile.
+var profileScriptsDir string = "/etc/profile.d"
+
+// The shell initialization files in the home directory of a user.
+var userShellInitFiles = []string{
+	".profile",
+	".bash_profile",
+	".bash_login",
+	".bashrc",
+	".zshenv",
+	".zprofile",
+	".zshrc",
+}
+
+// The directories PAM looks up modules given by name in.
+var pamModuleDirs = []string{
+	"/lib/security",
+	"/lib64/security",
+	"/usr/lib/security",
+	"/usr/lib64/security",
+	"/lib/*-linux-gnu/security",
+	"/usr/lib/*-linux-gnu/security",
+}
+
+// The directory of the PAM configuration of each service.
+var pamConfigDir string = "/etc/pam.d"
+
+// Matches an assignment of LD_PRELOAD, either plain or exported.
+var ldPreloadAssignment = regexp.MustCompile(`(?:^|[\s;])LD_PRELOAD=("[^"]*"|'[^']*'|[^\s;]*)`)
+
+// parseLibraryList splits a list of libraries separated by colons or
+// whitespace, as in ld.so.preload and LD_PRELOAD. Entries referencing
+// variables, such as an existing $LD_PRELOAD, are skipped.
+func parseLibraryList(list string) (libraries []string) {
+	for _, library := range strings.FieldsFunc(list, func(r rune) bool {
+		return r == ':' || r == ' ' || r == '\t'
+	}) {
+		if strings.Contains(library, "$") {
+			continue
+		}
+		libraries = append(libraries, library)
+	}
+
+	return
+}
+
+// libraryAutorun returns an Autorun for a preloaded library.
+func libraryAutorun(entryType string, location string, rawName string, library string, launchString string) *Autorun {
+	return &Autorun{
+		Type:         entryType,
+		Location:     location,
+		ImagePath:    library,
+		ImageName:    filepath.Base(library),
+		Entry:        library,
+		RawName:      rawName,
+		LaunchString: launchString,
+	}
+}
+
+// shellPreloads returns an Autorun for each library a shell initialization
+// file assigns to LD_PRELOAD.
+func shellPreloads(opts Options, filePath string) (records []*Autorun) {
+	data, err := readFile(fileSystemFor(opts), filePath)
+	if err != nil {
+		return
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		for _, match := range ldPreloadAssignment.FindAllStringSubmatch(line, -1) {
+			for _, library := range parseLibraryList(strings.Trim(match[1], "\"'")) {
+				records = append(records, libraryAutorun("ld_preload", filePath, "LD_PRELOAD", library, line))
+			}
+		}
+	}
+
+	return
+}
+
+// This function enumerates the libraries listed in /etc/ld.so.preload and
+// those assigned to LD_PRELOAD in the shell initialization files of the
+// system and of every user.
+func linuxGetLDPreload(opts Options) (records []*Autorun) {
+	if !opts.UserScopeOnly {
+		if data, err := readFile(fileSystemFor(opts), ldPreloadFile); err == nil {
+			scanner := bufio.NewScanner(bytes.NewReader(data))
+			for scanner.Scan() {
+				line := strings.TrimSpace(scanner.Text())
+				if comment := strings.Index(line, "#"); comment >= 0 {
+					line = strings.TrimSpace(line[:comment])
+				}
+
+				for _, library := range parseLibraryList(line) {
+					records = append(records, libraryAutorun("ld_preload", ldPreloadFile, filepath.Base(ldPreloadFile), library, line))
+				}
+			}
+		}
+
+		initFiles := append([]string{}, systemShellInitFiles...)
+		if scripts, err := fileSystemFor(opts).ReadDir(profileScriptsDir); err == nil {
+			for _, script := range scripts {
+				if !script.IsDir() {
+					initFiles = append(initFiles, filepath.Join(profileScriptsDir, script.Name()))
+				}
+			}
+		}
+		for _, initFile := range initFiles {
+			records = append(records, shellPreloads(opts, initFile)...)
+		}
+	}
+
+	for _, home := range homeDirectories(opts) {
+		if opts.Context().Err() != nil {
+			return
+		}
+
+		for _, initFile := range userShellInitFiles {
+			userRecords := shellPreloads(opts, filepath.Join(home, initFile))
+			for _, record := range userRecords {
+				record.Scope = "user"
+				if opts.ResolveUsers {
+					record.User = filepath.Base(home)
+				}
+			}
+			records = append(records, userRecords...)
+		}
+	}
+
+	return
+}
+
+// globDirs returns the directories of fsys matching pattern, which can
+// have wildcards in any of its elements, like filepath.Glob.
+func globDirs(fsys fileSystem, pattern string) (dirs []string) {
+	dirs = []string{string(filepath.Separator)}
+	for _, element := range strings.Split(strings.Trim(pattern, string(filepath.Separator)), string(filepath.Separator)) {
+		var matches []string
+		for _, dir := range dirs {
+			if !strings.ContainsAny(element, "*?[") {
+				if info, err := fsys.Stat(filepath.Join(dir, element)); err == nil && info.IsDir() {
+					matches = append(matches, filepath.Join(dir, element))
+				}
+				continue
+			}
+
+			entries, err := fsys.ReadDir(dir)
+			if err != nil {
+				continue
+			}
+			for _, entry := range entries {
+				if matched, _ := filepath.Match(element, entry.Name()); matched && entry.IsDir() {
+					matches = append(matches, filepath.Join(dir, entry.Name()))
+				}
+			}
+		}
+		dirs = matches
+	}
+
+	return
+}
+
+// resolvePAMModule returns the path of a PAM module, which is either
+// absolute or the name of a file in one of the module directories. It
+// returns an empty path if the module is not found.
+func resolvePAMModule(opts Options, module string) string {
+	if filepath.IsAbs(module) {
+		return module
+	}
+
+	for _, pattern := range pamModuleDirs {
+		for _, dir := range globDirs(fileSystemFor(opts), pattern) {
+			modulePath := filepath.Join(dir, module)
+			if _, err := fileSystemFor(opts).Stat(modulePath); err == nil {
+				return modulePath
+			}
+		}
+	}
+
+	return ""
+}
+
+// This function enumerates the modules referenced by the PAM configuration
+// of each service, which are loaded into the processes authenticating
+// users. Each module is reported once per service, with the management
+// groups it is used for as Trigger.
+func linuxGetPAMModules(opts Options) (records []*Autorun) {
+	files, err := fileSystemFor(opts).ReadDir(pamConfigDir)
+	if err != nil {
+		return
+	}
+
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+
+		configPath := filepath.Join(pamConfigDir, file.Name())
+		data, err := readFile(fileSystemFor(opts), configPath)
+		if err != nil {
+			opts.Warn(configPath, err)
+			continue
+		}
+
+		modules := make(map[string]*Autorun)
+		groups := make(map[string][]string)
+		seenGroups := make(map[string]bool)
+		var order []string
+
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "@") {
+				continue
+			}
+
+			// Lines are "type control module-path arguments", where the
+			// control can be a bracketed list of actions containing spaces.
+			group := strings.TrimPrefix(strings.Fields(line)[0], "-")
+			rest := cutFields(line, 1)
+			if rest == "" {
+				continue
+			}
+			if strings.HasPrefix(rest, "[") {
+				closing := strings.Index(rest, "]")
+				if closing < 0 {
+					continue
+				}
+				rest = rest[closing+1:]
+			} else if control := strings.Fields(rest)[0]; control == "include" || control == "substack" {
+				// The configuration of another service is included, and
+				// is reported on its own.
+				continue
+			} else {
+				rest = cutFields(rest, 1)
+			}
+			fields := strings.Fields(rest)
+			if len(fields) == 0 {
+				continue
+			}
+			module := fields[0]
+
+			if _, ok := modules[module]; !ok {
+				modules[module] = &Autorun{
+					Type:         "pam_module",
+					Location:     configPath,
+					Arguments:    strings.Join(fields[1:], " "),
+					Entry:        module,
+					RawName:      module,
+					LaunchString: line,
+				}
+				// Modules which are not found are left unresolved rather
+				// than reported as missing files.
+				if modulePath := resolvePAMModule(opts, module); modulePath != "" {
+					modules[module].ImagePath = modulePath
+					modules[module].ImageName = filepath.Base(modulePath)
+				}
+				order = append(order, module)
+			}
+			if !seenGroups[module+" "+group] {
+				seenGroups[module+" "+group] = true
+				groups[module] = append(groups[module], group)
+			}
+		}
+
+		for _, module := range order {
+			modules[module].Trigger = strings.Join(groups[module], ", ")
+			records = append(records, modules[module])
+		}
+	}
+
+	return
+}
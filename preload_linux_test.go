@@ -0,0 +1,65 @@
+//+build linux
+
+package autoruns
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGlobDirs(t *testing.T) {
+	fsys := newFakeFileSystem(map[string]string{
+		"/lib/x86_64-linux-gnu/security/pam_unix.so": "",
+		"/lib/i386-linux-gnu/security/pam_unix.so":   "",
+		"/lib/x86_64-linux-gnu.conf":                 "",
+		"/lib/aarch64-linux-gnu/libc.so.6":           "",
+	})
+
+	got := globDirs(fsys, "/lib/*-linux-gnu/security")
+	want := []string{"/lib/i386-linux-gnu/security", "/lib/x86_64-linux-gnu/security"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestLinuxGetPAMModules(t *testing.T) {
+	fsys := newFakeFileSystem(map[string]string{
+		"/etc/pam.d/sshd": "auth required pam_unix.so nullok\n" +
+			"account include common-account\n" +
+			"session [success=ok default=bad] pam_foo.so debug\n" +
+			"-session optional /opt/pam/evil.so\n" +
+			"session required pam_unix.so\n",
+		"/lib/x86_64-linux-gnu/security/pam_unix.so": "",
+	})
+
+	records := recordsByEntry(linuxGetPAMModules(Options{fs: fsys}))
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want 3: %v", len(records), records)
+	}
+
+	tests := []struct {
+		module    string
+		imagePath string
+		imageName string
+		arguments string
+		trigger   string
+	}{
+		{"pam_unix.so", "/lib/x86_64-linux-gnu/security/pam_unix.so", "pam_unix.so", "nullok", "auth, session"},
+		// A module found in none of the directories is left unresolved.
+		{"pam_foo.so", "", "", "debug", "session"},
+		{"/opt/pam/evil.so", "/opt/pam/evil.so", "evil.so", "", "session"},
+	}
+	for _, test := range tests {
+		record := records[test.module]
+		if record == nil {
+			t.Errorf("%s: not found", test.module)
+			continue
+		}
+		if record.ImagePath != test.imagePath || record.ImageName != test.imageName {
+			t.Errorf("%s: got ImagePath %q, ImageName %q", test.module, record.ImagePath, record.ImageName)
+		}
+		if record.Arguments != test.arguments || record.Trigger != test.trigger {
+			t.Errorf("%s: got Arguments %q, Trigger %q", test.module, record.Arguments, record.Trigger)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+//+build windows
+
+package autoruns
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// The spooler keeps the print processors of each environment in its own
+// architecture directory under spool\prtprocs.
+var printEnvironmentDirs = map[string]string{
+	"Windows x64":    "x64",
+	"Windows NT x86": "W32X86",
+	"Windows IA64":   "IA64",
+	"Windows ARM64":  "ARM64",
+}
+
+// printProcessorPath resolves the Driver value of a print processor to the
+// DLL the spooler loads for the given environment.
+func printProcessorPath(environment string, driver string) string {
+	if filepath.IsAbs(driver) {
+		return driver
+	}
+
+	processorsDir := filepath.Join(os.Getenv("SystemRoot"), "System32", "spool", "prtprocs")
+	if dir, ok := printEnvironmentDirs[environment]; ok {
+		processorsDir = filepath.Join(processorsDir, dir)
+	}
+
+	return filepath.Join(processorsDir, driver)
+}
+
+// This function enumerates print processors loaded by the spooler service.
+func windowsGetPrintProcessors(opts Options) (records []*Autorun) {
+	var reg registry.Key = registry.LOCAL_MACHINE
+	var environmentsKey string = "System\\CurrentControlSet\\Control\\Print\\Environments"
+
+	// Open the registry key.
+	key, err := openKey(opts, reg, environmentsKey)
+	if err != nil {
+		return
+	}
+
+	// Enumerate the environments (e.g. "Windows x64").
+	environments, err := key.ReadSubKeyNames(0)
+	key.Close()
+	if err != nil {
+		return
+	}
+
+	for _, environment := range environments {
+		processorsKey := fmt.Sprintf("%s\\%s\\Print Processors", environmentsKey, environment)
+		key, err := openKey(opts, reg, processorsKey)
+		if err != nil {
+			continue
+		}
+
+		// Enumerate the print processors of this environment.
+		names, err := key.ReadSubKeyNames(0)
+		key.Close()
+		if err != nil {
+			continue
+		}
+
+		for _, name := range names {
+			subkeyPath := fmt.Sprintf("%s\\%s", processorsKey, name)
+			subkey, err := openKey(opts, reg, subkeyPath)
+			if err != nil {
+				continue
+			}
+
+			// The Driver value names the DLL implementing the processor.
+			driver, _, err := subkey.GetStringValue("Driver")
+			subkey.Close()
+			if err != nil || driver == "" {
+				continue
+			}
+
+			imageLocation := fmt.Sprintf("%s\\%s", registryToString(reg), subkeyPath)
+
+			// We pass the resolved DLL path to a function to return an Autorun.
+			newAutorun := stringToAutorun(opts, "print_processor", imageLocation, printProcessorPath(environment, driver), false, name)
+			newAutorun.RawName = "Driver"
+			newAutorun.LaunchString = driver
+
+			// Add the new autorun to the records.
+			records = append(records, newAutorun)
+		}
+	}
+
+	return
+}
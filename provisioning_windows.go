@@ -0,0 +1,92 @@
+//+build windows
+
+package autoruns
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// How many levels of subkeys of the provisioning commands are looked at.
+const provisioningCommandsDepth = 4
+
+// provisioningTrigger returns when the commands of a provisioning context
+// run: those of the primary context run as the first user when they log on,
+// those of the device context as SYSTEM while the device is set up.
+func provisioningTrigger(keyName string) string {
+	lowerName := strings.ToLower(keyName)
+	switch {
+	case strings.Contains(lowerName, "\\primarycontext"):
+		return "logon"
+	case strings.Contains(lowerName, "\\devicecontext"):
+		return "boot"
+	}
+
+	return ""
+}
+
+// This function enumerates the commands of provisioning packages, which
+// are typically applied through MDM. Every command is a subkey somewhere
+// below Provisioning\Commands holding its CommandLine, and the file it
+// runs in CommandFile or CommandFilePath.
+func windowsGetProvisioningCommands(opts Options) (records []*Autorun) {
+	var reg registry.Key = registry.LOCAL_MACHINE
+	var commandsKey string = "Software\\Microsoft\\Provisioning\\Commands"
+
+	type commandKey struct {
+		keyName string
+		depth   int
+	}
+	keys := []commandKey{{commandsKey, 0}}
+	for len(keys) > 0 {
+		current := keys[0]
+		keys = keys[1:]
+
+		// Open registry key.
+		key, err := openKey(opts, reg, current.keyName)
+		if err != nil {
+			continue
+		}
+
+		names, _ := key.ReadSubKeyNames(0)
+		commandLine, _, _ := key.GetStringValue("CommandLine")
+		var commandFile string
+		for _, valueName := range []string{"CommandFile", "CommandFilePath"} {
+			if value, _, err := key.GetStringValue(valueName); err == nil && value != "" {
+				commandFile = value
+				break
+			}
+		}
+		key.Close()
+
+		if current.depth < provisioningCommandsDepth {
+			for _, name := range names {
+				keys = append(keys, commandKey{fmt.Sprintf("%s\\%s", current.keyName, name), current.depth + 1})
+			}
+		}
+
+		// Only the keys of commands hold a command line or file.
+		rawName := "CommandLine"
+		if commandLine == "" {
+			commandLine, rawName = commandFile, "CommandFile"
+		}
+		if strings.TrimSpace(commandLine) == "" {
+			continue
+		}
+
+		imageLocation := fmt.Sprintf("%s\\%s", registryToString(reg), current.keyName)
+		entry := current.keyName[strings.LastIndex(current.keyName, "\\")+1:]
+
+		// We pass the value string to a function to return an Autorun.
+		newAutorun := stringToAutorun(opts, "provisioning_command", imageLocation, commandLine, true, entry)
+		newAutorun.RawName = rawName
+		newAutorun.Trigger = provisioningTrigger(current.keyName)
+
+		// Add the new autorun to the records.
+		records = append(records, newAutorun)
+	}
+
+	return
+}
@@ -0,0 +1,159 @@
+//+build windows
+
+package autoruns
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// The WinRM plugins Windows registers by default, by lowercased name.
+var defaultWSManPlugins = map[string]bool{
+	"event forwarding plugin":                  true,
+	"microsoft.powershell":                     true,
+	"microsoft.powershell.workflow":            true,
+	"microsoft.powershell32":                   true,
+	"microsoft.windows.servermanagerworkflows": true,
+	"sel plugin":                               true,
+	"wmi provider":                             true,
+}
+
+// The SIDs, as abbreviated in SDDL, which grant access to an endpoint to
+// practically anyone.
+var broadSDDLTrustees = map[string]bool{
+	"WD": true, // Everyone
+	"AN": true, // Anonymous
+	"AU": true, // Authenticated Users
+	"BU": true, // Users
+	"DU": true, // Domain Users
+	"NU": true, // Network
+}
+
+// wsmanPlugin is the part of the configuration of a WinRM plugin we are
+// interested in.
+type wsmanPlugin struct {
+	Name      string `xml:"Name,attr"`
+	Filename  string `xml:"Filename,attr"`
+	RunAsUser string `xml:"RunAsUser,attr"`
+	Params    []struct {
+		Name  string `xml:"Name,attr"`
+		Value string `xml:"Value,attr"`
+	} `xml:"InitializationParameters>Param"`
+	Resources []struct {
+		Security []struct {
+			Sddl string `xml:"Sddl,attr"`
+		} `xml:"Security"`
+	} `xml:"Resources>Resource"`
+}
+
+// param returns the value of an initialization parameter of the plugin.
+func (p wsmanPlugin) param(name string) string {
+	for _, param := range p.Params {
+		if strings.EqualFold(param.Name, name) {
+			return param.Value
+		}
+	}
+
+	return ""
+}
+
+// broadAccess checks whether the security descriptor of any resource of
+// the plugin allows access to a broad group of users.
+func (p wsmanPlugin) broadAccess() bool {
+	for _, resource := range p.Resources {
+		for _, security := range resource.Security {
+			dacl := security.Sddl
+			if i := strings.Index(dacl, "S:"); i >= 0 {
+				dacl = dacl[:i]
+			}
+
+			// Access allowed ACEs look like (A;;GA;;;BA), ending with the
+			// trustee.
+			for _, ace := range strings.Split(dacl, "(")[1:] {
+				fields := strings.Split(strings.TrimSuffix(ace, ")"), ";")
+				if len(fields) == 6 && fields[0] == "A" && broadSDDLTrustees[strings.ToUpper(fields[5])] {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// This function enumerates the WinRM plugins, which include the PowerShell
+// session configurations remote sessions connect to. Their configuration
+// is stored as XML in the ConfigXML value of the plugin. Sessions of a
+// configuration with a StartupScript run it first: it is reported as the
+// image, and the plugin DLL otherwise. Plugins Windows does not register,
+// or which run a script or as another user, are flagged as NonDefault, and
+// those open to broad groups of users, such as Everyone, as Suspicious.
+func windowsGetPSSessionConfigurations(opts Options) (records []*Autorun) {
+	var reg registry.Key = registry.LOCAL_MACHINE
+	var pluginsKey string = "Software\\Microsoft\\Windows\\CurrentVersion\\WSMAN\\Plugin"
+
+	// Open registry key.
+	key, err := openKey(opts, reg, pluginsKey)
+	if err != nil {
+		return
+	}
+
+	// Enumerate subkeys.
+	names, err := key.ReadSubKeyNames(0)
+	key.Close()
+	if err != nil {
+		return
+	}
+
+	for _, name := range names {
+		subkeyPath := fmt.Sprintf("%s\\%s", pluginsKey, name)
+		subkey, err := openKey(opts, reg, subkeyPath)
+		if err != nil {
+			continue
+		}
+		configXML, _, err := subkey.GetStringValue("ConfigXML")
+		subkey.Close()
+		if err != nil {
+			continue
+		}
+
+		imageLocation := fmt.Sprintf("%s\\%s", registryToString(reg), subkeyPath)
+
+		var plugin wsmanPlugin
+		if err := xml.Unmarshal([]byte(configXML), &plugin); err != nil {
+			opts.Warn(imageLocation, err)
+			continue
+		}
+
+		// The startup script runs in the session, the DLL hosts it.
+		startupScript := plugin.param("startupscript")
+		launchString := plugin.Filename
+		if startupScript != "" {
+			launchString = startupScript
+		}
+		if launchString == "" {
+			continue
+		}
+
+		// We pass the resolved path to a function to return an Autorun.
+		newAutorun := stringToAutorun(opts, "pssession_config", imageLocation, systemDLLPath(launchString), false, name)
+		newAutorun.RawName = "ConfigXML"
+		newAutorun.LaunchString = launchString
+		newAutorun.Trigger = "remote_session"
+		// The default configurations run neither a script nor as another
+		// user.
+		newAutorun.NonDefault = !defaultWSManPlugins[strings.ToLower(name)] || startupScript != "" || plugin.RunAsUser != ""
+		newAutorun.Suspicious = plugin.broadAccess()
+		if opts.ResolveUsers {
+			newAutorun.User = plugin.RunAsUser
+		}
+
+		// Add the new autorun to the records.
+		records = append(records, newAutorun)
+	}
+
+	return
+}
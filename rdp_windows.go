@@ -0,0 +1,133 @@
+//+build windows
+
+package autoruns
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// This function reads the program Remote Desktop sessions are forced to
+// start instead of the user's desktop. It can be configured for the RDP
+// listener, and through machine and user policies. It is empty by default,
+// so any value is reported.
+func windowsGetRDPInitialProgram(opts Options) (records []*Autorun) {
+	var policyKey string = "Software\\Policies\\Microsoft\\Windows NT\\Terminal Services"
+
+	roots := []registryRoot{
+		{reg: registry.LOCAL_MACHINE},
+	}
+	roots = append(roots, userRegistryRoots(opts)...)
+
+	type initialProgramKey struct {
+		root    registryRoot
+		keyName string
+	}
+	keys := []initialProgramKey{
+		{registryRoot{reg: registry.LOCAL_MACHINE}, "System\\CurrentControlSet\\Control\\Terminal Server\\WinStations\\RDP-Tcp"},
+	}
+	for _, root := range roots {
+		keys = append(keys, initialProgramKey{root, root.prefix + policyKey})
+	}
+
+	for _, value := range keys {
+		// Open registry key.
+		key, err := openKey(opts, value.root.reg, value.keyName)
+		if err != nil {
+			continue
+		}
+
+		// Check if there is an initial program.
+		initialProgram, _, err := key.GetStringValue("InitialProgram")
+		workDirectory, _, _ := key.GetStringValue("WorkDirectory")
+		key.Close()
+		if err != nil || initialProgram == "" {
+			continue
+		}
+
+		imageLocation := fmt.Sprintf("%s\\%s", registryToString(value.root.reg), value.keyName)
+
+		// We pass the value string to a function to return an Autorun.
+		newAutorun := stringToAutorun(opts, "rdp_initial_program", imageLocation, initialProgram, true, "InitialProgram")
+		newAutorun.RawName = "InitialProgram"
+		newAutorun.WorkingDirectory = workDirectory
+		newAutorun.User = value.root.user
+
+		// Add the new autorun to the records.
+		records = append(records, newAutorun)
+	}
+
+	return
+}
+
+// This function enumerates the Remote Desktop add-ins, which load a DLL
+// into the RDP client of the user, or a program into the sessions of the
+// server. Each add-in is a subkey naming its module in Name, or in DllName
+// for older clients. Modules outside the Windows directory are not shipped
+// with Windows, and are flagged as NonDefault.
+func windowsGetRDPAddIns(opts Options) (records []*Autorun) {
+	var clientAddInsKey string = "Software\\Microsoft\\Terminal Server Client\\Default\\AddIns"
+	var serverAddInsKey string = "System\\CurrentControlSet\\Control\\Terminal Server\\AddIns"
+
+	type addInsKey struct {
+		root    registryRoot
+		keyName string
+	}
+	keys := []addInsKey{
+		{registryRoot{reg: registry.LOCAL_MACHINE}, serverAddInsKey},
+		{registryRoot{reg: registry.LOCAL_MACHINE}, clientAddInsKey},
+	}
+	for _, root := range userRegistryRoots(opts) {
+		keys = append(keys, addInsKey{root, root.prefix + clientAddInsKey})
+	}
+
+	for _, addIns := range keys {
+		// Open registry key.
+		key, err := openKey(opts, addIns.root.reg, addIns.keyName)
+		if err != nil {
+			continue
+		}
+
+		// Enumerate subkeys.
+		names, err := key.ReadSubKeyNames(0)
+		key.Close()
+		if err != nil {
+			continue
+		}
+
+		for _, name := range names {
+			subkeyPath := fmt.Sprintf("%s\\%s", addIns.keyName, name)
+			subkey, err := openKey(opts, addIns.root.reg, subkeyPath)
+			if err != nil {
+				continue
+			}
+
+			// Check which value holds the module.
+			var module, valueName string
+			for _, candidate := range []string{"Name", "DllName"} {
+				if value, _, err := subkey.GetStringValue(candidate); err == nil && value != "" {
+					module, valueName = value, candidate
+					break
+				}
+			}
+			subkey.Close()
+			if module == "" {
+				continue
+			}
+
+			imageLocation := fmt.Sprintf("%s\\%s", registryToString(addIns.root.reg), subkeyPath)
+
+			// We pass the value string to a function to return an Autorun.
+			newAutorun := stringToAutorun(opts, "rdp_addin", imageLocation, module, true, name)
+			newAutorun.RawName = valueName
+			newAutorun.User = addIns.root.user
+			newAutorun.NonDefault = !opts.QuickScan && !inSystemDirectory(newAutorun.ImagePath)
+
+			// Add the new autorun to the records.
+			records = append(records, newAutorun)
+		}
+	}
+
+	return
+}
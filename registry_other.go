@@ -0,0 +1,11 @@
+//+build !windows
+
+package autoruns
+
+// platformOptions holds the parts of Options which only exist on Windows.
+type platformOptions struct{}
+
+// There is no registry to record on this platform.
+func withRecordingRegistry(opts Options, recorder *locationRecorder) Options {
+	return opts
+}
@@ -0,0 +1,128 @@
+//+build windows
+
+package autoruns
+
+import (
+	"errors"
+	"io"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// errOutOfScope is returned for keys outside of the scope of
+// Options.UserScopeOnly.
+var errOutOfScope = errors.New("out of scope")
+
+// registryKey is an open registry key. It is implemented by registry.Key.
+type registryKey interface {
+	ReadSubKeyNames(n int) ([]string, error)
+	ReadValueNames(n int) ([]string, error)
+	GetStringValue(name string) (string, uint32, error)
+	GetStringsValue(name string) ([]string, uint32, error)
+	GetIntegerValue(name string) (uint64, uint32, error)
+	GetBinaryValue(name string) ([]byte, uint32, error)
+	Stat() (*registry.KeyInfo, error)
+	Close() error
+}
+
+// registryReader opens registry keys for reading. Scanners only access the
+// registry through the reader of their Options, so that it can be replaced
+// by one which is not backed by the live registry.
+type registryReader interface {
+	OpenKey(reg registry.Key, path string) (registryKey, error)
+}
+
+// The views of the registry are chosen explicitly: keys are opened in the
+// 64-bit view, where Wow6432Node holds the 32-bit one, so that a 32-bit
+// build is not silently redirected to the 32-bit view of the keys it opens.
+// A value found under the same name in both views is then reported once
+// for each, with the View of its Source telling them apart.
+const (
+	registryRead  = registry.READ | registry.WOW64_64KEY
+	registryWrite = registry.SET_VALUE | registry.WOW64_64KEY
+)
+
+// systemRegistry reads the registry of the local system.
+type systemRegistry struct{}
+
+func (systemRegistry) OpenKey(reg registry.Key, path string) (registryKey, error) {
+	key, err := registry.OpenKey(reg, path, registryRead)
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// userRegistry restricts a registryReader to the hive of the current user
+// and to CLASSES_ROOT, whose per-user part can always be read.
+type userRegistry struct {
+	registryReader
+}
+
+func (r userRegistry) OpenKey(reg registry.Key, path string) (registryKey, error) {
+	if reg != registry.CURRENT_USER && reg != registry.CLASSES_ROOT {
+		return nil, errOutOfScope
+	}
+
+	return r.registryReader.OpenKey(reg, path)
+}
+
+// recordingRegistry is a registryReader which records the keys opened,
+// without opening any of them.
+type recordingRegistry struct {
+	recorder *locationRecorder
+}
+
+func (r recordingRegistry) OpenKey(reg registry.Key, path string) (registryKey, error) {
+	r.recorder.record(registryToString(reg), path, "registry_key")
+	return nil, registry.ErrNotExist
+}
+
+// withRecordingRegistry makes opts read from a registry which records the
+// keys opened to recorder.
+func withRecordingRegistry(opts Options, recorder *locationRecorder) Options {
+	opts.registry = recordingRegistry{recorder}
+	return opts
+}
+
+// platformOptions holds the parts of Options which only exist on Windows.
+type platformOptions struct {
+	registry registryReader
+}
+
+// registryFor returns the registry a scan with the given options reads
+// from, which is the one of the local system by default.
+func registryFor(opts Options) registryReader {
+	var reader registryReader = systemRegistry{}
+	if opts.registry != nil {
+		reader = opts.registry
+	}
+	if opts.UserScopeOnly {
+		reader = userRegistry{reader}
+	}
+
+	return reader
+}
+
+// readSubKeyNames reads the names of the subkeys of key, found at location,
+// up to MaxEntriesPerCategory of them. A key with more is reported as
+// truncated.
+func readSubKeyNames(opts Options, key registryKey, location string) ([]string, error) {
+	limit := opts.MaxEntriesPerCategory
+	if limit <= 0 {
+		return key.ReadSubKeyNames(0)
+	}
+
+	// Fewer names than asked for are returned along with io.EOF.
+	names, err := key.ReadSubKeyNames(limit + 1)
+	if err == io.EOF {
+		err = nil
+	}
+	if len(names) > limit {
+		opts.Warn(location, ErrTruncated)
+		names = names[:limit]
+	}
+
+	return names, err
+}
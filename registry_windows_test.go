@@ -0,0 +1,446 @@
+//+build windows
+
+package autoruns
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// fakeValues are the values of a key of a fakeRegistry by name: strings,
+// string lists, integers and byte slices.
+type fakeValues map[string]interface{}
+
+// fakeRegistry is an in-memory registryReader for tests. Keys are given by
+// their full path, e.g. LOCAL_MACHINE\Software\Microsoft, as
+// registryToString names their root, and exist as long as they or one of
+// their subkeys is listed. Like in the real registry, names are case
+// insensitive.
+type fakeRegistry map[string]fakeValues
+
+func (r fakeRegistry) OpenKey(reg registry.Key, path string) (registryKey, error) {
+	fullPath := registryToString(reg) + "\\" + path
+	key := &fakeKey{registry: r, path: fullPath}
+	for name, values := range r {
+		if strings.EqualFold(name, fullPath) {
+			key.values = values
+			return key, nil
+		}
+	}
+	if len(key.subKeys()) > 0 {
+		return key, nil
+	}
+
+	return nil, registry.ErrNotExist
+}
+
+// fakeKey is an open key of a fakeRegistry.
+type fakeKey struct {
+	registry fakeRegistry
+	path     string
+	values   fakeValues
+}
+
+// subKeys returns the sorted names of the subkeys of the key.
+func (k *fakeKey) subKeys() (names []string) {
+	prefix := strings.ToLower(k.path) + "\\"
+	seen := make(map[string]bool)
+	for name := range k.registry {
+		if !strings.HasPrefix(strings.ToLower(name), prefix) {
+			continue
+		}
+		subKey := strings.SplitN(name[len(prefix):], "\\", 2)[0]
+		if !seen[strings.ToLower(subKey)] {
+			seen[strings.ToLower(subKey)] = true
+			names = append(names, subKey)
+		}
+	}
+	sort.Strings(names)
+
+	return
+}
+
+// fakeNames returns at most n of names, or all with n <= 0, along with
+// io.EOF if there are fewer, like registry.Key does.
+func fakeNames(names []string, n int) ([]string, error) {
+	if n <= 0 {
+		return names, nil
+	}
+	if len(names) > n {
+		return names[:n], nil
+	}
+
+	return names, io.EOF
+}
+
+func (k *fakeKey) ReadSubKeyNames(n int) ([]string, error) {
+	return fakeNames(k.subKeys(), n)
+}
+
+func (k *fakeKey) ReadValueNames(n int) ([]string, error) {
+	var names []string
+	for name := range k.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return fakeNames(names, n)
+}
+
+// value returns the value of the given name.
+func (k *fakeKey) value(name string) (interface{}, error) {
+	for valueName, value := range k.values {
+		if strings.EqualFold(valueName, name) {
+			return value, nil
+		}
+	}
+
+	return nil, registry.ErrNotExist
+}
+
+func (k *fakeKey) GetStringValue(name string) (string, uint32, error) {
+	value, err := k.value(name)
+	if err != nil {
+		return "", 0, err
+	}
+	if s, ok := value.(string); ok {
+		return s, registry.SZ, nil
+	}
+
+	return "", 0, registry.ErrUnexpectedType
+}
+
+func (k *fakeKey) GetStringsValue(name string) ([]string, uint32, error) {
+	value, err := k.value(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	if s, ok := value.([]string); ok {
+		return s, registry.MULTI_SZ, nil
+	}
+
+	return nil, 0, registry.ErrUnexpectedType
+}
+
+func (k *fakeKey) GetIntegerValue(name string) (uint64, uint32, error) {
+	value, err := k.value(name)
+	if err != nil {
+		return 0, 0, err
+	}
+	switch i := value.(type) {
+	case int:
+		return uint64(i), registry.DWORD, nil
+	case uint32:
+		return uint64(i), registry.DWORD, nil
+	case uint64:
+		return i, registry.QWORD, nil
+	}
+
+	return 0, 0, registry.ErrUnexpectedType
+}
+
+func (k *fakeKey) GetBinaryValue(name string) ([]byte, uint32, error) {
+	value, err := k.value(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	if b, ok := value.([]byte); ok {
+		return b, registry.BINARY, nil
+	}
+
+	return nil, 0, registry.ErrUnexpectedType
+}
+
+func (k *fakeKey) Stat() (*registry.KeyInfo, error) {
+	return &registry.KeyInfo{
+		SubKeyCount: uint32(len(k.subKeys())),
+		ValueCount:  uint32(len(k.values)),
+	}, nil
+}
+
+func (k *fakeKey) Close() error {
+	return nil
+}
+
+func TestFakeRegistry(t *testing.T) {
+	reg := fakeRegistry{
+		`LOCAL_MACHINE\Software\Vendor\App`:           {"Path": `C:\App\app.exe`, "Count": 3},
+		`LOCAL_MACHINE\Software\Vendor\Tool\Settings`: {},
+	}
+
+	key, err := reg.OpenKey(registry.LOCAL_MACHINE, `software\vendor`)
+	if err != nil {
+		t.Fatalf("OpenKey: %v", err)
+	}
+	if names, err := key.ReadSubKeyNames(0); err != nil || strings.Join(names, ",") != "App,Tool" {
+		t.Errorf("ReadSubKeyNames = %v, %v", names, err)
+	}
+	if names, err := key.ReadSubKeyNames(5); err != io.EOF || len(names) != 2 {
+		t.Errorf("ReadSubKeyNames(5) = %v, %v, want 2 names and io.EOF", names, err)
+	}
+
+	key, _ = reg.OpenKey(registry.LOCAL_MACHINE, `Software\Vendor\App`)
+	if value, _, err := key.GetStringValue("path"); err != nil || value != `C:\App\app.exe` {
+		t.Errorf("GetStringValue = %q, %v", value, err)
+	}
+	if _, _, err := key.GetStringValue("Count"); err != registry.ErrUnexpectedType {
+		t.Errorf("GetStringValue of an integer: %v", err)
+	}
+	if _, err := reg.OpenKey(registry.CURRENT_USER, `Software\Vendor\App`); err != registry.ErrNotExist {
+		t.Errorf("OpenKey of a missing key: %v", err)
+	}
+}
+
+func TestWindowsGetCurrentVersionRun(t *testing.T) {
+	opts := Options{fs: newFakeFileSystem(map[string]string{
+		`C:\Program Files\Agent\agent.exe`: "MZ",
+		`C:\Users\Public\updater.exe`:      "MZ",
+	})}
+	opts.registry = fakeRegistry{
+		`LOCAL_MACHINE\Software\Microsoft\Windows\CurrentVersion\Run`: {
+			"Agent": `"C:\Program Files\Agent\agent.exe" --tray`,
+			"Empty": "",
+		},
+		`LOCAL_MACHINE\Software\Wow6432Node\Microsoft\Windows\CurrentVersion\Run`: {
+			"Legacy": `C:\Program Files\Agent\agent.exe --legacy`,
+		},
+		`CURRENT_USER\Software\Microsoft\Windows\CurrentVersion\RunOnce`: {
+			"Updater": `C:\Users\Public\updater.exe /silent`,
+			"Counter": 1,
+		},
+	}
+
+	records := recordsByEntry(windowsGetCurrentVersionRun(opts))
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want 3: %v", len(records), records)
+	}
+
+	tests := []struct {
+		entry     string
+		location  string
+		imagePath string
+		arguments string
+	}{
+		{"Agent", `LOCAL_MACHINE\Software\Microsoft\Windows\CurrentVersion\Run`, `C:\Program Files\Agent\agent.exe`, "--tray"},
+		{"Legacy", `LOCAL_MACHINE\Software\Wow6432Node\Microsoft\Windows\CurrentVersion\Run`, `C:\Program Files\Agent\agent.exe`, "--legacy"},
+		{"Updater", `CURRENT_USER\Software\Microsoft\Windows\CurrentVersion\RunOnce`, `C:\Users\Public\updater.exe`, "/silent"},
+	}
+	for _, test := range tests {
+		record := records[test.entry]
+		if record == nil {
+			t.Errorf("%s: not found", test.entry)
+			continue
+		}
+		if record.Type != "run_key" || record.RawName != test.entry || record.Location != test.location {
+			t.Errorf("%s: got Type %q, RawName %q, Location %q", test.entry, record.Type, record.RawName, record.Location)
+		}
+		if record.ImagePath != test.imagePath || record.Arguments != test.arguments {
+			t.Errorf("%s: got ImagePath %q, Arguments %q", test.entry, record.ImagePath, record.Arguments)
+		}
+	}
+}
+
+func TestRunKeysTruncated(t *testing.T) {
+	opts := Options{state: newScanState(), fs: newFakeFileSystem(nil), QuickScan: true, MaxEntriesPerCategory: 3}
+	values := fakeValues{}
+	for _, name := range []string{"A", "B", "C", "D", "E"} {
+		values[name] = `C:\Program Files\` + name + `\agent.exe`
+	}
+	opts.registry = fakeRegistry{`LOCAL_MACHINE\Software\Microsoft\Windows\CurrentVersion\Run`: values}
+
+	records := runScanner(scanner{name: "run_keys", fn: windowsGetCurrentVersionRun}, opts)
+	if len(records) != 3 {
+		t.Errorf("got %d records, want the 3 of the cap", len(records))
+	}
+
+	var truncated []*ScanError
+	for _, warning := range opts.state.warnings.list() {
+		var scanErr *ScanError
+		if errors.Is(warning, ErrTruncated) && errors.As(warning, &scanErr) {
+			truncated = append(truncated, scanErr)
+		}
+	}
+	if len(truncated) != 1 {
+		t.Fatalf("got %d truncations, want 1: %v", len(truncated), opts.state.warnings.list())
+	}
+	if truncated[0].Category != "run_keys" || truncated[0].Location != `LOCAL_MACHINE\Software\Microsoft\Windows\CurrentVersion\Run` {
+		t.Errorf("got Category %q, Location %q", truncated[0].Category, truncated[0].Location)
+	}
+
+	// At the cap, nothing is truncated.
+	opts = Options{state: newScanState(), fs: newFakeFileSystem(nil), QuickScan: true, MaxEntriesPerCategory: 5}
+	opts.registry = fakeRegistry{`LOCAL_MACHINE\Software\Microsoft\Windows\CurrentVersion\Run`: values}
+	if records := runScanner(scanner{name: "run_keys", fn: windowsGetCurrentVersionRun}, opts); len(records) != 5 {
+		t.Errorf("got %d records at the cap, want 5", len(records))
+	}
+	for _, warning := range opts.state.warnings.list() {
+		if errors.Is(warning, ErrTruncated) {
+			t.Errorf("truncated at the cap: %v", warning)
+		}
+	}
+}
+
+func TestUserScopeOnlyRegistry(t *testing.T) {
+	opts := Options{UserScopeOnly: true}
+	opts.registry = fakeRegistry{
+		`LOCAL_MACHINE\Software\Microsoft\Windows\CurrentVersion\Run`: {"Machine": `C:\machine.exe`},
+		`CURRENT_USER\Software\Microsoft\Windows\CurrentVersion\Run`:  {"User": `C:\user.exe`},
+	}
+
+	records := windowsGetCurrentVersionRun(opts)
+	if len(records) != 1 || records[0].Entry != "User" {
+		t.Errorf("got %v, want only the record of the current user", records)
+	}
+}
+
+func TestRegistryViewsNeitherDuplicatedNorDropped(t *testing.T) {
+	opts := Options{QuickScan: true}
+	opts.registry = fakeRegistry{
+		`LOCAL_MACHINE\Software\Microsoft\Windows\CurrentVersion\Run`: {
+			"Agent":  `C:\Program Files\Agent\agent.exe`,
+			"Native": `C:\Tools\native.exe`,
+		},
+		`LOCAL_MACHINE\Software\Wow6432Node\Microsoft\Windows\CurrentVersion\Run`: {
+			"Agent": `C:\Program Files (x86)\Agent\agent.exe`,
+		},
+	}
+
+	views := make(map[string][]string)
+	for _, record := range windowsGetCurrentVersionRun(opts) {
+		setSource(opts, record)
+		views[record.Entry] = append(views[record.Entry], record.Source.View)
+	}
+
+	// The value found in both views is reported once for each of them.
+	if got := strings.Join(views["Agent"], ","); got != "64,32" {
+		t.Errorf("views of Agent = %s, want 64,32", got)
+	}
+	if got := strings.Join(views["Native"], ","); got != "64" {
+		t.Errorf("views of Native = %s, want 64", got)
+	}
+	if len(views) != 2 {
+		t.Errorf("got entries %v, want Agent and Native", views)
+	}
+}
+
+func TestOpenKeyWarnings(t *testing.T) {
+	opts := Options{state: newScanState()}
+	opts.registry = fakeRegistry{}
+
+	runScanner(scanner{name: "services", fn: windowsGetServices}, opts)
+	warnings := opts.state.warnings.list()
+	if len(warnings) == 0 {
+		t.Fatal("a missing key is not reported")
+	}
+
+	var scanErr *ScanError
+	if !errors.As(warnings[0], &scanErr) {
+		t.Fatalf("warning %v is not a *ScanError", warnings[0])
+	}
+	if scanErr.Category != "services" || scanErr.Location != `LOCAL_MACHINE\System\CurrentControlSet\Services` {
+		t.Errorf("got Category %q, Location %q", scanErr.Category, scanErr.Location)
+	}
+	if errors.Unwrap(warnings[0]) != registry.ErrNotExist || !errors.Is(warnings[0], registry.ErrNotExist) {
+		t.Errorf("warning %v does not unwrap to registry.ErrNotExist", warnings[0])
+	}
+
+	// A key which does not exist is not an error of the machine.
+	summary := summarize(opts, nil, warnings)
+	if summary.Errors != 0 || summary.NotFound != len(warnings) {
+		t.Errorf("Errors, NotFound = %d, %d, want 0, %d", summary.Errors, summary.NotFound, len(warnings))
+	}
+}
+
+// flakyRegistry fails to open keys with err the first failures times.
+type flakyRegistry struct {
+	registryReader
+	err      error
+	failures int
+	opens    int
+}
+
+func (r *flakyRegistry) OpenKey(reg registry.Key, path string) (registryKey, error) {
+	r.opens++
+	if r.opens <= r.failures {
+		return nil, r.err
+	}
+
+	return r.registryReader.OpenKey(reg, path)
+}
+
+func TestOpenKeyRetriesTransientFailures(t *testing.T) {
+	run := fakeRegistry{
+		`LOCAL_MACHINE\Software\Microsoft\Windows\CurrentVersion\Run`: {"Agent": `C:\agent.exe`},
+	}
+	runKey := `Software\Microsoft\Windows\CurrentVersion\Run`
+
+	tests := []struct {
+		name     string
+		err      error
+		failures int
+		attempts int
+		opened   bool
+		opens    int
+	}{
+		{"sharing violation", windows.ERROR_SHARING_VIOLATION, 2, 0, true, 3},
+		{"busy", windows.ERROR_BUSY, 1, 0, true, 2},
+		{"out of attempts", windows.ERROR_SHARING_VIOLATION, 5, 2, false, 3},
+		{"retries disabled", windows.ERROR_SHARING_VIOLATION, 1, -1, false, 1},
+		// Missing keys and denied access fail fast.
+		{"missing", registry.ErrNotExist, 1, 0, false, 1},
+		{"denied", windows.ERROR_ACCESS_DENIED, 1, 0, false, 1},
+	}
+	for _, test := range tests {
+		flaky := &flakyRegistry{registryReader: run, err: test.err, failures: test.failures}
+		opts := Options{RetryAttempts: test.attempts, RetryBackoff: time.Millisecond}
+		opts.registry = flaky
+
+		key, err := openKey(opts, registry.LOCAL_MACHINE, runKey)
+		if opened := err == nil; opened != test.opened {
+			t.Errorf("%s: openKey failed with %v", test.name, err)
+		} else if opened {
+			key.Close()
+		}
+		if flaky.opens != test.opens {
+			t.Errorf("%s: opened the key %d times, want %d", test.name, flaky.opens, test.opens)
+		}
+	}
+
+	// The scanner reads the entry once the key could be opened.
+	opts := Options{QuickScan: true, RetryBackoff: time.Millisecond}
+	opts.registry = &flakyRegistry{registryReader: run, err: windows.ERROR_LOCK_VIOLATION, failures: 1}
+	if records := windowsGetCurrentVersionRun(opts); len(records) != 1 || records[0].Entry != "Agent" {
+		t.Errorf("got %v, want the entry of the key opened on retry", records)
+	}
+}
+
+func TestWindowsCategoryDetails(t *testing.T) {
+	want := map[string]CategoryInfo{
+		"run_keys": {Name: "run_keys", Scope: "user"},
+		"services": {Name: "services", Scope: "machine"},
+		// Defender hides its exclusions and the task scheduler the tasks
+		// of others from unprivileged users.
+		"defender_exclusions": {Name: "defender_exclusions", Scope: "machine", RequiresAdmin: true},
+		"tasks":               {Name: "tasks", Scope: "machine", RequiresAdmin: true},
+	}
+
+	found := 0
+	for _, info := range CategoryDetails() {
+		if expected, ok := want[info.Name]; ok {
+			found++
+			if info != expected {
+				t.Errorf("got %+v, want %+v", info, expected)
+			}
+		}
+	}
+	if found != len(want) {
+		t.Errorf("found %d of the categories %v", found, want)
+	}
+}
@@ -0,0 +1,88 @@
+package autoruns
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrRemediationNotAllowed is returned when disabling or removing a record
+// which does not come from a scan with Options.AllowRemediation.
+var ErrRemediationNotAllowed = errors.New("autoruns: remediation requires Options.AllowRemediation")
+
+// ErrRemediationUnsupported is returned when a record cannot be disabled or
+// removed, depending on its type and where it was found.
+var ErrRemediationUnsupported = errors.New("autoruns: remediation is not supported")
+
+// Remediation describes the change which disables or removes an autorun.
+type Remediation struct {
+	// Action is what is done to Target, e.g. "delete_registry_value" or
+	// "delete_file".
+	Action string `json:"action"`
+	// Target is the registry value or key, file, task or service acted
+	// on.
+	Target string `json:"target"`
+	// Value is the data written by actions which set a value.
+	Value string `json:"value"`
+
+	apply func() error
+}
+
+func (r Remediation) String() string {
+	if r.Value != "" {
+		return fmt.Sprintf("%s %s = %s", r.Action, r.Target, r.Value)
+	}
+	return fmt.Sprintf("%s %s", r.Action, r.Target)
+}
+
+// PlanDisable returns the change Disable would make, without making it.
+func (a *Autorun) PlanDisable() (Remediation, error) {
+	return planRemediation(a, true)
+}
+
+// PlanRemove returns the change Remove would make, without making it.
+func (a *Autorun) PlanRemove() (Remediation, error) {
+	return planRemediation(a, false)
+}
+
+// Disable stops the autorun from being launched while keeping it in place,
+// the same way the system does, e.g. by marking a Run value as disabled
+// in StartupApproved or by setting a service to be disabled. The record
+// must come from a scan with Options.AllowRemediation.
+func (a *Autorun) Disable() error {
+	return a.remediate(true)
+}
+
+// Remove deletes the value, file, task or service holding the autorun. It
+// cannot be undone. The record must come from a scan with
+// Options.AllowRemediation.
+func (a *Autorun) Remove() error {
+	return a.remediate(false)
+}
+
+func (a *Autorun) remediate(disable bool) error {
+	if !a.remediable {
+		return ErrRemediationNotAllowed
+	}
+
+	remediation, err := planRemediation(a, disable)
+	if err != nil {
+		return err
+	}
+
+	if err := remediation.apply(); err != nil {
+		return fmt.Errorf("autoruns: %s: %w", remediation, err)
+	}
+
+	return nil
+}
+
+// unsupportedRemediation returns the error for a record which cannot be
+// remediated.
+func unsupportedRemediation(a *Autorun, disable bool) error {
+	action := "removed"
+	if disable {
+		action = "disabled"
+	}
+
+	return fmt.Errorf("%w: %s records cannot be %s", ErrRemediationUnsupported, a.Type, action)
+}
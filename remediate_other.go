@@ -0,0 +1,33 @@
+//+build !windows
+
+package autoruns
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// planRemediation returns the change which disables or removes a record.
+// Only the records held by a file of their own can be removed, and none can
+// be disabled.
+func planRemediation(a *Autorun, disable bool) (Remediation, error) {
+	var target string
+	switch a.Type {
+	case "launch_daemons", "launch_agents", "launch_agents_user":
+		target = a.Location
+	case "periodic", "systemd_generator", "motd_script":
+		target = filepath.Join(a.Location, a.RawName)
+	}
+
+	if disable || target == "" {
+		return Remediation{}, unsupportedRemediation(a, disable)
+	}
+
+	return Remediation{
+		Action: "delete_file",
+		Target: target,
+		apply: func() error {
+			return os.Remove(target)
+		},
+	}, nil
+}
@@ -0,0 +1,190 @@
+//+build windows
+
+package autoruns
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// The Run keys Explorer keeps a StartupApproved key of, with the
+// StartupApproved key they map to, relative to the root of the hive.
+var startupApprovedRunKeys = []struct {
+	runKey     string
+	approveKey string
+}{
+	{"Software\\Microsoft\\Windows\\CurrentVersion\\Run", "Software\\Microsoft\\Windows\\CurrentVersion\\Explorer\\StartupApproved\\Run"},
+	{"Software\\Wow6432Node\\Microsoft\\Windows\\CurrentVersion\\Run", "Software\\Microsoft\\Windows\\CurrentVersion\\Explorer\\StartupApproved\\Run32"},
+}
+
+// The StartupApproved key of the Startup folders.
+var startupApprovedFolderKey string = "Software\\Microsoft\\Windows\\CurrentVersion\\Explorer\\StartupApproved\\StartupFolder"
+
+// The StartupApproved data Explorer writes for a disabled entry: 03
+// followed by the time it was disabled, which can be left empty.
+var startupApprovedDisabled = []byte{0x03, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+
+// The Start value of a disabled service.
+const serviceStartDisabled = 4
+
+// setValueRemediation returns a Remediation writing a value.
+func setValueRemediation(reg registry.Key, keyPath string, name string, apply func(key registry.Key) error, value string) Remediation {
+	return Remediation{
+		Action: "set_registry_value",
+		Target: fmt.Sprintf("%s\\%s\\%s", registryToString(reg), keyPath, name),
+		Value:  value,
+		apply: func() error {
+			key, _, err := registry.CreateKey(reg, keyPath, registryWrite)
+			if err != nil {
+				return err
+			}
+			defer key.Close()
+
+			return apply(key)
+		},
+	}
+}
+
+// startupApprovedRemediation returns a Remediation marking an entry as
+// disabled in a StartupApproved key, as the Startup tab of Task Manager
+// does.
+func startupApprovedRemediation(reg registry.Key, keyPath string, name string) Remediation {
+	return setValueRemediation(reg, keyPath, name, func(key registry.Key) error {
+		return key.SetBinaryValue(name, startupApprovedDisabled)
+	}, hex.EncodeToString(startupApprovedDisabled))
+}
+
+// deleteValueRemediation returns a Remediation deleting a value.
+func deleteValueRemediation(reg registry.Key, keyPath string, name string) Remediation {
+	return Remediation{
+		Action: "delete_registry_value",
+		Target: fmt.Sprintf("%s\\%s\\%s", registryToString(reg), keyPath, name),
+		apply: func() error {
+			key, err := registry.OpenKey(reg, keyPath, registryWrite)
+			if err != nil {
+				return err
+			}
+			defer key.Close()
+
+			return key.DeleteValue(name)
+		},
+	}
+}
+
+// schtasksRemediation returns a Remediation changing a scheduled task with
+// schtasks.
+func schtasksRemediation(action string, taskName string, args ...string) Remediation {
+	return Remediation{
+		Action: action,
+		Target: taskName,
+		apply: func() error {
+			output, err := exec.Command("schtasks.exe", args...).CombinedOutput()
+			if err != nil {
+				return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output)))
+			}
+			return nil
+		},
+	}
+}
+
+// planRemediation returns the change which disables or removes a record.
+func planRemediation(a *Autorun, disable bool) (Remediation, error) {
+	switch a.Type {
+	case "run_key":
+		reg, keyPath, ok := parseRegistryLocation(a.Location)
+		if !ok {
+			break
+		}
+		if !disable {
+			return deleteValueRemediation(reg, keyPath, a.RawName), nil
+		}
+
+		// Only the Run keys, not RunOnce or the policy keys, can be
+		// disabled through StartupApproved. The path of the key can be
+		// prefixed with the hive of another user.
+		for _, runKey := range startupApprovedRunKeys {
+			if strings.HasSuffix(strings.ToLower(keyPath), strings.ToLower(runKey.runKey)) {
+				prefix := keyPath[:len(keyPath)-len(runKey.runKey)]
+				return startupApprovedRemediation(reg, prefix+runKey.approveKey, a.RawName), nil
+			}
+		}
+
+	case "startup":
+		if !disable {
+			filePath := filepath.Join(a.Location, a.RawName)
+			return Remediation{
+				Action: "delete_file",
+				Target: filePath,
+				apply: func() error {
+					return os.Remove(filePath)
+				},
+			}, nil
+		}
+
+		// Only the files at the top of the folder are approved, the common
+		// Startup folder in LOCAL_MACHINE and the one of the current user
+		// in CURRENT_USER.
+		if strings.Contains(a.RawName, "\\") {
+			break
+		}
+		folders := map[string]registry.Key{
+			os.Getenv("ProgramData"): registry.LOCAL_MACHINE,
+			os.Getenv("AppData"):     registry.CURRENT_USER,
+		}
+		for folder, reg := range folders {
+			if folder != "" && strings.HasPrefix(strings.ToLower(a.Location), strings.ToLower(folder)+"\\") {
+				return startupApprovedRemediation(reg, startupApprovedFolderKey, a.RawName), nil
+			}
+		}
+
+	case "service", "driver":
+		if !disable {
+			serviceName := a.Entry
+			return Remediation{
+				Action: "delete_service",
+				Target: serviceName,
+				apply: func() error {
+					manager, err := mgr.Connect()
+					if err != nil {
+						return err
+					}
+					defer manager.Disconnect()
+
+					service, err := manager.OpenService(serviceName)
+					if err != nil {
+						return err
+					}
+					defer service.Close()
+
+					return service.Delete()
+				},
+			}, nil
+		}
+
+		reg, keyPath, ok := parseRegistryLocation(a.Location)
+		if !ok {
+			break
+		}
+		return setValueRemediation(reg, keyPath, "Start", func(key registry.Key) error {
+			return key.SetDWordValue("Start", serviceStartDisabled)
+		}, fmt.Sprint(serviceStartDisabled)), nil
+
+	case "scheduled_task":
+		if a.Entry == "" {
+			break
+		}
+		if disable {
+			return schtasksRemediation("disable_task", a.Entry, "/Change", "/TN", a.Entry, "/Disable"), nil
+		}
+		return schtasksRemediation("delete_task", a.Entry, "/Delete", "/TN", a.Entry, "/F"), nil
+	}
+
+	return Remediation{}, unsupportedRemediation(a, disable)
+}
@@ -0,0 +1,22 @@
+//+build !windows
+
+package autoruns
+
+import "errors"
+
+// Scans of other machines are only supported on Windows.
+func isRemote(opts Options) bool {
+	return false
+}
+
+func connectRemote(opts *Options) error {
+	if opts.RemoteHost != "" {
+		return errors.New("autoruns: remote scans are only supported on Windows")
+	}
+
+	return nil
+}
+
+func accessPath(opts Options, path string) string {
+	return path
+}
@@ -0,0 +1,115 @@
+//+build windows
+
+package autoruns
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// remoteRegistry reads the registry of another machine. Only LOCAL_MACHINE
+// and USERS can be opened remotely: CLASSES_ROOT is read from the machine
+// part of the classes, and CURRENT_USER is out of scope as there is no
+// current user on the remote machine.
+type remoteRegistry struct {
+	machine registry.Key
+	users   registry.Key
+}
+
+func (r remoteRegistry) OpenKey(reg registry.Key, path string) (registryKey, error) {
+	var root registry.Key
+	switch reg {
+	case registry.LOCAL_MACHINE:
+		root = r.machine
+	case registry.USERS:
+		root = r.users
+	case registry.CLASSES_ROOT:
+		root, path = r.machine, "Software\\Classes\\"+path
+	default:
+		return nil, errOutOfScope
+	}
+
+	key, err := registry.OpenKey(root, path, registryRead)
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// adminSharePath returns the path of a file of host through the
+// administrative share of its drive, so that C:\Windows is read from
+// \\host\C$\Windows. Paths without a drive letter cannot be read remotely.
+func adminSharePath(host string, name string) (string, error) {
+	volume := filepath.VolumeName(name)
+	switch {
+	case strings.HasPrefix(volume, "\\\\"):
+		return name, nil
+	case len(volume) == 2 && volume[1] == ':':
+		return fmt.Sprintf("\\\\%s\\%c$%s", host, volume[0], name[2:]), nil
+	}
+
+	return "", &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+}
+
+// isRemote checks whether a scan reads another machine.
+func isRemote(opts Options) bool {
+	return opts.RemoteHost != ""
+}
+
+// connectRemote connects to the registry of Options.RemoteHost and makes
+// opts read from it and from its administrative shares. The keys are
+// closed once the scan is done.
+func connectRemote(opts *Options) error {
+	if !isRemote(*opts) {
+		return nil
+	}
+	if opts.ScanUserHives {
+		return fmt.Errorf("autoruns: the hives of other users cannot be loaded on %s", opts.RemoteHost)
+	}
+	// Remediation changes the local system, not the remote one.
+	if opts.AllowRemediation {
+		return fmt.Errorf("autoruns: the records of %s cannot be remediated", opts.RemoteHost)
+	}
+
+	host := strings.TrimPrefix(opts.RemoteHost, "\\\\")
+	machine, err := registry.OpenRemoteKey("\\\\"+host, registry.LOCAL_MACHINE)
+	if err != nil {
+		return fmt.Errorf("autoruns: connecting to the registry of %s: %w", host, err)
+	}
+	users, err := registry.OpenRemoteKey("\\\\"+host, registry.USERS)
+	if err != nil {
+		machine.Close()
+		return fmt.Errorf("autoruns: connecting to the registry of %s: %w", host, err)
+	}
+	opts.onDone(func() {
+		users.Close()
+		machine.Close()
+	})
+
+	opts.registry = remoteRegistry{machine: machine, users: users}
+	opts.fs = mappedFileSystem{mapPath: func(name string) (string, error) {
+		return adminSharePath(host, name)
+	}}
+	if !opts.RemoteFileAccess {
+		opts.QuickScan = true
+	}
+
+	return nil
+}
+
+// accessPath returns the path an image of a remote or offline scan is
+// read from, for the analyses which do not go through the fileSystem.
+func accessPath(opts Options, path string) string {
+	if fsys, ok := opts.fs.(mappedFileSystem); ok {
+		if mappedPath, err := fsys.mapPath(path); err == nil {
+			return mappedPath
+		}
+	}
+
+	return path
+}
@@ -0,0 +1,84 @@
+package autoruns
+
+// suspicionSignals are the signals combined into Autorun.Suspicion, along
+// with the points each contributes. The score is capped at 100.
+var suspicionSignals = []struct {
+	reason  string
+	weight  int
+	applies func(opts Options, autorun *Autorun) bool
+}{
+	{"hidden", 40, func(opts Options, autorun *Autorun) bool {
+		return autorun.Hidden
+	}},
+	{"excluded_from_defender", 30, func(opts Options, autorun *Autorun) bool {
+		return autorun.ExcludedFromDefender
+	}},
+	{"masquerade", 30, func(opts Options, autorun *Autorun) bool {
+		return autorun.Masquerade
+	}},
+	{"file_missing", 25, func(opts Options, autorun *Autorun) bool {
+		return autorun.FileMissing
+	}},
+	{"unsigned", 25, unsignedImage},
+	{"suspicious", 25, func(opts Options, autorun *Autorun) bool {
+		return autorun.Suspicious
+	}},
+	{"writable_directory", 20, writableImageDirectory},
+	{"sideload_risk", 15, func(opts Options, autorun *Autorun) bool {
+		return autorun.SideloadRisk
+	}},
+	{"non_default", 15, func(opts Options, autorun *Autorun) bool {
+		return autorun.NonDefault
+	}},
+	{"outside_system_directory", 10, func(opts Options, autorun *Autorun) bool {
+		return !opts.QuickScan && autorun.ImagePath != "" && !inSystemDirectory(autorun.ImagePath)
+	}},
+	{"near_defender_tamper", 10, func(opts Options, autorun *Autorun) bool {
+		return autorun.nearDefenderTamper
+	}},
+}
+
+// unsignedImage checks whether the signature of an existing image was
+// verified and found missing or invalid. Where signatures cannot be
+// verified, no image is unsigned.
+func unsignedImage(opts Options, autorun *Autorun) bool {
+	return signaturesVerifiable && opts.VerifySignatures && !autorun.Signed && autorun.ImagePath != "" && !autorun.FileMissing
+}
+
+// writableImageDirectory checks whether an existing local image is in a
+// directory writable by everyone. Like the analysis of images, the lookup
+// skips network shares and gives up after the FileTimeout of opts.
+func writableImageDirectory(opts Options, autorun *Autorun) bool {
+	if opts.QuickScan || scansOtherSystem(opts) || autorun.ImagePath == "" || autorun.FileMissing {
+		return false
+	}
+	if autorun.MediaType == "network" || isNetworkPath(autorun.ImagePath) {
+		return false
+	}
+
+	var writable bool
+	if !withFileTimeout(opts, func() { writable = imageDirWritable(autorun.ImagePath) }) {
+		opts.debugf("%s: timed out checking the directory of %s", opts.category, autorun.ImagePath)
+		return false
+	}
+
+	return writable
+}
+
+// scoreSuspicion sets the Suspicion of a record to the sum of the weights
+// of the signals which apply to it, and lists those in SuspicionReasons.
+func scoreSuspicion(opts Options, autorun *Autorun) {
+	autorun.Suspicion = 0
+	autorun.SuspicionReasons = nil
+
+	for _, signal := range suspicionSignals {
+		if signal.applies(opts, autorun) {
+			autorun.Suspicion += signal.weight
+			autorun.SuspicionReasons = append(autorun.SuspicionReasons, signal.reason)
+		}
+	}
+
+	if autorun.Suspicion > 100 {
+		autorun.Suspicion = 100
+	}
+}
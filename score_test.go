@@ -0,0 +1,75 @@
+package autoruns
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// hasReason checks whether a scored record lists reason.
+func hasReason(autorun *Autorun, reason string) bool {
+	for _, r := range autorun.SuspicionReasons {
+		if r == reason {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestScoreSuspicionUnsigned(t *testing.T) {
+	opts := Options{VerifySignatures: true}
+	autorun := &Autorun{ImagePath: "/nonexistent/image"}
+	scoreSuspicion(opts, autorun)
+
+	if got := hasReason(autorun, "unsigned"); got != signaturesVerifiable {
+		t.Errorf("unsigned reason = %v, want %v where signatures are verifiable: %v", got, signaturesVerifiable, autorun.SuspicionReasons)
+	}
+
+	autorun = &Autorun{ImagePath: "/nonexistent/image", Signed: true}
+	scoreSuspicion(opts, autorun)
+	if hasReason(autorun, "unsigned") {
+		t.Errorf("signed image scored as unsigned: %v", autorun.SuspicionReasons)
+	}
+}
+
+func TestScoreSuspicionCapped(t *testing.T) {
+	autorun := &Autorun{
+		Hidden:               true,
+		ExcludedFromDefender: true,
+		Masquerade:           true,
+		FileMissing:          true,
+		Suspicious:           true,
+	}
+	scoreSuspicion(Options{QuickScan: true}, autorun)
+
+	if autorun.Suspicion != 100 {
+		t.Errorf("Suspicion = %d, want 100", autorun.Suspicion)
+	}
+	if len(autorun.SuspicionReasons) != 5 {
+		t.Errorf("SuspicionReasons = %v, want 5 reasons", autorun.SuspicionReasons)
+	}
+}
+
+func TestScoreSuspicionWritableDirectory(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	autorun := &Autorun{ImagePath: filepath.Join(dir, "image")}
+	if !writableImageDirectory(Options{}, autorun) && runtime.GOOS != "windows" {
+		t.Errorf("%s is not reported as writable", dir)
+	}
+
+	// Network images are never looked up.
+	for _, autorun := range []*Autorun{
+		{ImagePath: filepath.Join(dir, "image"), MediaType: "network"},
+		{ImagePath: "\\\\server\\share\\image.exe"},
+	} {
+		if writableImageDirectory(Options{}, autorun) {
+			t.Errorf("network image %s is reported as writable", autorun.ImagePath)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+//+build windows
+
+package autoruns
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// Service trigger types, as defined for SERVICE_TRIGGER in winsvc.h.
+var serviceTriggerTypes = map[uint64]string{
+	1:  "device_interface_arrival",
+	2:  "ip_address_availability",
+	3:  "domain_join",
+	4:  "firewall_port_event",
+	5:  "group_policy",
+	6:  "network_endpoint",
+	7:  "custom_system_state_change",
+	20: "custom",
+	30: "aggregate",
+}
+
+// Well-known trigger subtype GUIDs, as defined in winsvc.h.
+var serviceTriggerSubtypes = map[string]string{
+	"{4F27F2DE-14E2-430B-A549-7CD48CBC8245}": "first_ip_address_arrival",
+	"{CC4BA62A-162E-4648-847A-B6BDF993E335}": "last_ip_address_removal",
+	"{1CE20ABA-9851-4421-9430-1DDEB766E809}": "domain_join",
+	"{DDAF516E-58C2-4866-9574-C3B615D42EA1}": "domain_leave",
+	"{B7569E07-8421-4EE0-AD10-86915AFDAD09}": "firewall_port_open",
+	"{A144ED38-8E12-4DE4-9D96-E64740B1A524}": "firewall_port_close",
+	"{659FCAE6-5BDB-4DA9-B1FF-CA2A178D46E0}": "machine_policy_present",
+	"{54FB46C8-F089-464C-B1FD-59D1B62C3B50}": "user_policy_present",
+	"{BC90D167-9470-4139-A9BA-BE0BBBF5B74D}": "rpc_interface_event",
+	"{1F81D131-3FAC-4537-9E0C-7E7B0C2F4B55}": "named_pipe_event",
+}
+
+// bytesToGUID converts the binary form of a GUID as stored in the registry.
+func bytesToGUID(b []byte) (guid windows.GUID, ok bool) {
+	if len(b) < 16 {
+		return guid, false
+	}
+
+	guid.Data1 = binary.LittleEndian.Uint32(b[0:4])
+	guid.Data2 = binary.LittleEndian.Uint16(b[4:6])
+	guid.Data3 = binary.LittleEndian.Uint16(b[6:8])
+	copy(guid.Data4[:], b[8:16])
+
+	return guid, true
+}
+
+// The start modes of services and drivers, by the value of Start.
+var serviceStartModes = map[uint64]string{
+	0: "boot",
+	1: "system",
+	2: "auto",
+	3: "manual",
+	4: "disabled",
+}
+
+// serviceStartMode returns when a service or driver is started according to
+// its Start value. Automatic services with DelayedAutostart set are started
+// a while after the others, which is reported as "auto-delayed".
+func serviceStartMode(key registryKey) string {
+	start, _, err := key.GetIntegerValue("Start")
+	if err != nil {
+		return ""
+	}
+
+	mode, ok := serviceStartModes[start]
+	if !ok {
+		return ""
+	}
+	if start == 2 {
+		if delayed, _, err := key.GetIntegerValue("DelayedAutostart"); err == nil && delayed == 1 {
+			mode = "auto-delayed"
+		}
+	}
+
+	return mode
+}
+
+// serviceTrigger summarizes the triggers configured under the TriggerInfo
+// subkey of a service, e.g. "first_ip_address_arrival, domain_join".
+// It returns an empty string for services which are not trigger-started.
+func serviceTrigger(opts Options, reg registry.Key, servicePath string) string {
+	triggerInfoPath := fmt.Sprintf("%s\\TriggerInfo", servicePath)
+	key, err := registryFor(opts).OpenKey(reg, triggerInfoPath)
+	if err != nil {
+		return ""
+	}
+
+	// Each trigger is stored in a numbered subkey.
+	names, err := key.ReadSubKeyNames(0)
+	key.Close()
+	if err != nil {
+		return ""
+	}
+
+	var triggers []string
+	for _, name := range names {
+		subkey, err := registryFor(opts).OpenKey(reg, fmt.Sprintf("%s\\%s", triggerInfoPath, name))
+		if err != nil {
+			continue
+		}
+
+		triggerType, _, err := subkey.GetIntegerValue("Type")
+		if err != nil {
+			subkey.Close()
+			continue
+		}
+		action, _, _ := subkey.GetIntegerValue("Action")
+		rawGUID, _, _ := subkey.GetBinaryValue("GUID")
+		subkey.Close()
+
+		typeName, ok := serviceTriggerTypes[triggerType]
+		if !ok {
+			typeName = fmt.Sprintf("type_%d", triggerType)
+		}
+
+		// Prefer the well-known name of the subtype and fall back to the
+		// trigger type along with the raw GUID.
+		trigger := typeName
+		if guid, ok := bytesToGUID(rawGUID); ok {
+			if subtype, ok := serviceTriggerSubtypes[strings.ToUpper(guid.String())]; ok {
+				trigger = subtype
+			} else {
+				trigger = fmt.Sprintf("%s %s", typeName, guid.String())
+			}
+		}
+
+		// SERVICE_TRIGGER_ACTION_SERVICE_STOP.
+		if action == 2 {
+			trigger += " (stop)"
+		}
+
+		triggers = append(triggers, trigger)
+	}
+
+	return strings.Join(triggers, ", ")
+}
+
+// This function enumerates the network providers listed in the provider
+// order, which load into processes performing logons and network
+// operations. Providers in the order without a ProviderPath are reported
+// without an image and flagged as Suspicious, since a DLL can be planted
+// for them later.
+func windowsGetNetworkProviders(opts Options) (records []*Autorun) {
+	var reg registry.Key = registry.LOCAL_MACHINE
+	var orderKey string = "System\\CurrentControlSet\\Control\\NetworkProvider\\Order"
+
+	// Open the registry key.
+	key, err := openKey(opts, reg, orderKey)
+	if err != nil {
+		return
+	}
+
+	// The order is a comma-separated list of service names.
+	order, _, err := key.GetStringValue("ProviderOrder")
+	key.Close()
+	if err != nil {
+		return
+	}
+
+	for _, name := range strings.Split(order, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		providerKey := fmt.Sprintf("System\\CurrentControlSet\\Services\\%s\\NetworkProvider", name)
+		imageLocation := fmt.Sprintf("%s\\%s", registryToString(reg), providerKey)
+
+		// Check if there is a ProviderPath value.
+		var providerPath string
+		subkey, err := openOptionalKey(opts, reg, providerKey)
+		if err == nil {
+			providerPath, _, _ = subkey.GetStringValue("ProviderPath")
+			subkey.Close()
+		} else if !errors.Is(err, registry.ErrNotExist) {
+			continue
+		}
+		if strings.TrimSpace(providerPath) == "" {
+			records = append(records, &Autorun{
+				Type:       "network_provider",
+				Location:   imageLocation,
+				Entry:      name,
+				RawName:    "ProviderPath",
+				Suspicious: true,
+			})
+			continue
+		}
+
+		// We pass the resolved DLL path to a function to return an Autorun.
+		newAutorun := stringToAutorun(opts, "network_provider", imageLocation, systemDLLPath(providerPath), false, name)
+		newAutorun.RawName = "ProviderPath"
+		newAutorun.LaunchString = providerPath
+
+		// Add the new autorun to the records.
+		records = append(records, newAutorun)
+	}
+
+	return
+}
+
+// serviceGroupOrder holds the order drivers are loaded in within the early
+// phases: the groups, in the order of ServiceGroupOrder\List, and the tags
+// of each group, in the order of GroupOrderList.
+type serviceGroupOrder struct {
+	groups map[string]int
+	tags   map[string][]uint32
+}
+
+// readServiceGroupOrder reads the order drivers are loaded in.
+func readServiceGroupOrder(opts Options) serviceGroupOrder {
+	var reg registry.Key = registry.LOCAL_MACHINE
+	var controlKey string = "System\\CurrentControlSet\\Control"
+
+	order := serviceGroupOrder{
+		groups: make(map[string]int),
+		tags:   make(map[string][]uint32),
+	}
+
+	if key, err := openKey(opts, reg, controlKey+"\\ServiceGroupOrder"); err == nil {
+		groups, _, _ := key.GetStringsValue("List")
+		key.Close()
+		for i, group := range groups {
+			order.groups[strings.ToLower(group)] = i + 1
+		}
+	}
+
+	// Each value is a count followed by the tags of the group, as DWORDs.
+	if key, err := openKey(opts, reg, controlKey+"\\GroupOrderList"); err == nil {
+		names, _ := key.ReadValueNames(0)
+		for _, name := range names {
+			data, _, err := key.GetBinaryValue(name)
+			if err != nil || len(data) < 4 {
+				continue
+			}
+			count := int(binary.LittleEndian.Uint32(data))
+			var tags []uint32
+			for i := 0; i < count && 4+4*i+4 <= len(data); i++ {
+				tags = append(tags, binary.LittleEndian.Uint32(data[4+4*i:]))
+			}
+			order.tags[strings.ToLower(name)] = tags
+		}
+		key.Close()
+	}
+
+	return order
+}
+
+// driverLoadPhase describes when a driver which starts in the boot or
+// system phase is loaded, e.g. "boot: Boot Bus Extender (3), tag 2 (1)",
+// with the position of its group in the group order and of its tag in the
+// tag order of the group. It returns an empty string for the drivers
+// loaded later, and whether the group or tag is missing from the order,
+// which drivers tampering with the load order cause.
+func driverLoadPhase(order serviceGroupOrder, key registryKey, startMode string) (phase string, anomaly bool) {
+	if startMode != "boot" && startMode != "system" {
+		return "", false
+	}
+
+	group, _, err := key.GetStringValue("Group")
+	if err != nil || group == "" {
+		return startMode, false
+	}
+
+	position, ok := order.groups[strings.ToLower(group)]
+	if !ok {
+		return fmt.Sprintf("%s: %s (unordered)", startMode, group), true
+	}
+	phase = fmt.Sprintf("%s: %s (%d)", startMode, group, position)
+
+	// Tags only order drivers within groups which have a tag order.
+	tag, _, err := key.GetIntegerValue("Tag")
+	tags, ordered := order.tags[strings.ToLower(group)]
+	if err != nil || !ordered {
+		return phase, false
+	}
+	for i, orderedTag := range tags {
+		if uint64(orderedTag) == tag {
+			return fmt.Sprintf("%s, tag %d (%d)", phase, tag, i+1), false
+		}
+	}
+
+	return fmt.Sprintf("%s, tag %d (unordered)", phase, tag), true
+}
+
+// inDriverDirectory checks whether a driver is in one of the directories
+// Windows installs drivers to.
+func inDriverDirectory(imagePath string) bool {
+	imagePath = strings.ToLower(imagePath)
+	systemDir := strings.ToLower(filepath.Join(os.Getenv("SystemRoot"), "System32"))
+	for _, dir := range []string{"drivers", "DriverStore"} {
+		if strings.HasPrefix(imagePath, filepath.Join(systemDir, strings.ToLower(dir))+"\\") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,149 @@
+//+build windows
+
+package autoruns
+
+import (
+	"testing"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+func TestWindowsGetServicesDrivers(t *testing.T) {
+	t.Setenv("SystemRoot", `C:\Windows`)
+	services := `LOCAL_MACHINE\System\CurrentControlSet\Services\`
+
+	opts := Options{fs: newFakeFileSystem(map[string]string{
+		`C:\Program Files\Agent\updater.exe`:   "MZ",
+		`C:\Windows\System32\drivers\beep.sys`: "MZ",
+		`C:\Windows\System32\drivers\nofs.sys`: "MZ",
+		`C:\Users\Public\rk.sys`:               "MZ",
+	})}
+	opts.registry = fakeRegistry{
+		`LOCAL_MACHINE\System\CurrentControlSet\Control\ServiceGroupOrder`: {"List": []string{"Base"}},
+		services + "Updater": {
+			"ImagePath": `"C:\Program Files\Agent\updater.exe" -service`,
+			"Type":      uint32(0x10),
+			"Start":     uint32(2),
+		},
+		// A kernel driver given by a native path.
+		services + "Beep": {
+			"ImagePath": `\SystemRoot\System32\drivers\beep.sys`,
+			"Type":      uint32(0x1),
+			"Start":     uint32(1),
+			"Group":     "Base",
+		},
+		// A file system driver loaded from the default path.
+		services + "Nofs": {
+			"Type":  uint32(0x2),
+			"Start": uint32(3),
+		},
+		// A boot driver loaded from outside the driver directories.
+		services + "Rootkit": {
+			"ImagePath": `\??\C:\Users\Public\rk.sys`,
+			"Type":      uint32(0x1),
+			"Start":     uint32(0),
+		},
+		// A service without an ImagePath runs nothing.
+		services + "Broken": {
+			"Type":  uint32(0x10),
+			"Start": uint32(2),
+		},
+	}
+
+	records := recordsByEntry(windowsGetServices(opts))
+	if len(records) != 4 {
+		t.Fatalf("got %d records, want 4: %v", len(records), records)
+	}
+
+	tests := []struct {
+		entry      string
+		typ        string
+		imagePath  string
+		startMode  string
+		loadPhase  string
+		suspicious bool
+	}{
+		{"Updater", "service", `C:\Program Files\Agent\updater.exe`, "auto", "", false},
+		{"Beep", "driver", `C:\Windows\System32\drivers\beep.sys`, "system", "system: Base (1)", false},
+		{"Nofs", "driver", `C:\Windows\System32\drivers\nofs.sys`, "manual", "", false},
+		{"Rootkit", "driver", `C:\Users\Public\rk.sys`, "boot", "boot", true},
+	}
+	for _, test := range tests {
+		record := records[test.entry]
+		if record == nil {
+			t.Errorf("%s: not found", test.entry)
+			continue
+		}
+		if record.Type != test.typ || record.ImagePath != test.imagePath || record.unparsed {
+			t.Errorf("%s: got Type %q, ImagePath %q", test.entry, record.Type, record.ImagePath)
+		}
+		if record.StartMode != test.startMode || record.LoadPhase != test.loadPhase || record.Suspicious != test.suspicious {
+			t.Errorf("%s: got StartMode %q, LoadPhase %q, Suspicious %v", test.entry, record.StartMode, record.LoadPhase, record.Suspicious)
+		}
+	}
+}
+
+func TestServiceStartMode(t *testing.T) {
+	tests := []struct {
+		values fakeValues
+		want   string
+	}{
+		{fakeValues{"Start": uint32(2)}, "auto"},
+		{fakeValues{"Start": uint32(2), "DelayedAutostart": uint32(1)}, "auto-delayed"},
+		{fakeValues{"Start": uint32(2), "DelayedAutostart": uint32(0)}, "auto"},
+		// Only automatic services can be delayed.
+		{fakeValues{"Start": uint32(3), "DelayedAutostart": uint32(1)}, "manual"},
+		{fakeValues{"Start": uint32(4)}, "disabled"},
+		{fakeValues{"Start": uint32(9)}, ""},
+		{fakeValues{}, ""},
+	}
+	for _, test := range tests {
+		reg := fakeRegistry{`LOCAL_MACHINE\System\CurrentControlSet\Services\Test`: test.values}
+		key, err := reg.OpenKey(registry.LOCAL_MACHINE, `System\CurrentControlSet\Services\Test`)
+		if err != nil {
+			t.Fatalf("OpenKey: %v", err)
+		}
+		if got := serviceStartMode(key); got != test.want {
+			t.Errorf("serviceStartMode(%v) = %q, want %q", test.values, got, test.want)
+		}
+	}
+}
+
+func TestWindowsGetNetworkProviders(t *testing.T) {
+	t.Setenv("SystemRoot", `C:\Windows`)
+	services := `LOCAL_MACHINE\System\CurrentControlSet\Services\`
+
+	opts := Options{state: newScanState(), fs: newFakeFileSystem(map[string]string{
+		`C:\Windows\System32\ntlanman.dll`: "MZ",
+	})}
+	opts.registry = fakeRegistry{
+		`LOCAL_MACHINE\System\CurrentControlSet\Control\NetworkProvider\Order`: {
+			"ProviderOrder": "LanmanWorkstation, Orphan,Empty,",
+		},
+		services + `LanmanWorkstation\NetworkProvider`: {"ProviderPath": `%SystemRoot%\System32\ntlanman.dll`},
+		services + `Empty\NetworkProvider`:             {"ProviderPath": ""},
+	}
+
+	records := recordsByEntry(windowsGetNetworkProviders(opts))
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want 3: %v", len(records), records)
+	}
+
+	if record := records["LanmanWorkstation"]; record == nil || record.Suspicious || record.ImagePath != `C:\Windows\System32\ntlanman.dll` {
+		t.Errorf("LanmanWorkstation: got %+v", record)
+	}
+	// Providers in the order without a DLL are flagged.
+	for _, name := range []string{"Orphan", "Empty"} {
+		record := records[name]
+		if record == nil || !record.Suspicious || record.ImagePath != "" {
+			t.Errorf("%s: got %+v, want a suspicious record without an image", name, record)
+			continue
+		}
+		if record.Location != services+name+`\NetworkProvider` || record.RawName != "ProviderPath" {
+			t.Errorf("%s: got Location %q, RawName %q", name, record.Location, record.RawName)
+		}
+	}
+	if warnings := opts.state.warnings.list(); len(warnings) != 0 {
+		t.Errorf("got warnings: %v", warnings)
+	}
+}
@@ -0,0 +1,49 @@
+//+build !windows
+
+package autoruns
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The directories the operating system installs its own binaries to.
+var systemDirectories = []string{
+	"/bin/",
+	"/sbin/",
+	"/usr/bin/",
+	"/usr/sbin/",
+	"/usr/lib/",
+	"/usr/libexec/",
+	"/System/",
+}
+
+// DLL sideloading only applies to Windows.
+func sideloadRisk(imagePath string) bool {
+	return false
+}
+
+// imageDirWritable checks whether the directory of an image is writable by
+// everyone.
+func imageDirWritable(imagePath string) bool {
+	info, err := os.Stat(filepath.Dir(imagePath))
+	if err != nil {
+		return false
+	}
+
+	return info.Mode().Perm()&0002 != 0
+}
+
+// inSystemDirectory checks whether a path is inside a directory of the
+// operating system.
+func inSystemDirectory(path string) bool {
+	path = filepath.Clean(path)
+	for _, dir := range systemDirectories {
+		if strings.HasPrefix(path, dir) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,150 @@
+//+build windows
+
+package autoruns
+
+import (
+	"debug/pe"
+	"os"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// DLLs which are not protected by KnownDLLs and are therefore commonly
+// abused for sideloading by dropping them next to the executable importing
+// them.
+var hijackableDLLs = map[string]bool{
+	"amsi.dll":      true,
+	"cryptbase.dll": true,
+	"cryptsp.dll":   true,
+	"dbgcore.dll":   true,
+	"dbghelp.dll":   true,
+	"dpapi.dll":     true,
+	"dwmapi.dll":    true,
+	"dxgi.dll":      true,
+	"iphlpapi.dll":  true,
+	"mpr.dll":       true,
+	"netapi32.dll":  true,
+	"profapi.dll":   true,
+	"propsys.dll":   true,
+	"secur32.dll":   true,
+	"sspicli.dll":   true,
+	"userenv.dll":   true,
+	"uxtheme.dll":   true,
+	"version.dll":   true,
+	"winhttp.dll":   true,
+	"winmm.dll":     true,
+	"winsta.dll":    true,
+	"wldp.dll":      true,
+	"wtsapi32.dll":  true,
+}
+
+// Access rights which allow creating files in a directory, or granting
+// oneself that right. FILE_ADD_FILE shares its value with FILE_WRITE_DATA.
+const directoryWriteMask = windows.FILE_WRITE_DATA | windows.GENERIC_WRITE | windows.GENERIC_ALL |
+	windows.WRITE_DAC | windows.WRITE_OWNER
+
+// writableByNonAdmins checks whether the DACL of path grants write access
+// to Everyone, Authenticated Users or Users.
+func writableByNonAdmins(path string) bool {
+	sd, err := windows.GetNamedSecurityInfo(path, windows.SE_FILE_OBJECT, windows.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		return false
+	}
+	dacl, _, err := sd.DACL()
+	// A missing DACL grants full access to everyone.
+	if err != nil || dacl == nil {
+		return err == nil && dacl == nil
+	}
+
+	var nonAdmins []*windows.SID
+	for _, sidType := range []windows.WELL_KNOWN_SID_TYPE{
+		windows.WinWorldSid,
+		windows.WinAuthenticatedUserSid,
+		windows.WinBuiltinUsersSid,
+	} {
+		if sid, err := windows.CreateWellKnownSid(sidType); err == nil {
+			nonAdmins = append(nonAdmins, sid)
+		}
+	}
+
+	for i := 0; i < int(dacl.AceCount); i++ {
+		var ace *windows.ACCESS_ALLOWED_ACE
+		if err := windows.GetAce(dacl, uint32(i), &ace); err != nil {
+			continue
+		}
+		if ace.Header.AceType != windows.ACCESS_ALLOWED_ACE_TYPE || ace.Header.AceFlags&windows.INHERIT_ONLY_ACE != 0 {
+			continue
+		}
+		if ace.Mask&directoryWriteMask == 0 {
+			continue
+		}
+
+		aceSID := (*windows.SID)(unsafe.Pointer(&ace.SidStart))
+		for _, sid := range nonAdmins {
+			if aceSID.Equals(sid) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// imageDirWritable checks whether non-administrators can write to the
+// directory of an image.
+func imageDirWritable(imagePath string) bool {
+	return writableByNonAdmins(filepath.Dir(imagePath))
+}
+
+// inSystemDirectory checks whether a path is inside the Windows directory,
+// except for its Temp folder.
+func inSystemDirectory(path string) bool {
+	systemRoot := strings.ToLower(filepath.Clean(os.Getenv("SystemRoot")))
+	path = strings.ToLower(filepath.Clean(path))
+	if systemRoot == "." || !strings.HasPrefix(path, systemRoot+"\\") {
+		return false
+	}
+
+	return !strings.HasPrefix(path, systemRoot+"\\temp\\")
+}
+
+// sideloadRisk checks whether the PE at imagePath imports a commonly
+// hijacked DLL which is missing from its directory while that directory is
+// writable by non-administrators, so that a planted copy would be loaded
+// before the legitimate one.
+func sideloadRisk(imagePath string) bool {
+	file, err := pe.Open(imagePath)
+	if err != nil {
+		return false
+	}
+	imports, err := file.ImportedLibraries()
+	file.Close()
+	if err != nil {
+		return false
+	}
+
+	dir := filepath.Dir(imagePath)
+	var writable, checked bool
+	for _, dll := range imports {
+		if !hijackableDLLs[strings.ToLower(dll)] {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, dll)); err == nil {
+			continue
+		}
+
+		// Only look up the ACL once we know it matters.
+		if !checked {
+			writable = writableByNonAdmins(dir)
+			checked = true
+		}
+		if writable {
+			return true
+		}
+	}
+
+	return false
+}
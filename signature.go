@@ -0,0 +1,22 @@
+package autoruns
+
+import "time"
+
+// Signature describes the certificate an image is signed with. It is only
+// set with Options.VerifySignatures, for images which carry a signature or
+// are listed in a catalog, whether or not it is valid: Autorun.Signed tells
+// whether it is, and chains to a trusted root.
+type Signature struct {
+	// Signer and Issuer are the common names of the subject and the issuer
+	// of the signing certificate.
+	Signer string `json:"signer"`
+	Issuer string `json:"issuer"`
+	// Thumbprint is the SHA1 hash of the signing certificate, as shown by
+	// the certificate tools of the system.
+	Thumbprint string    `json:"thumbprint"`
+	NotBefore  time.Time `json:"not_before"`
+	NotAfter   time.Time `json:"not_after"`
+	// CatalogSigned is set for images signed through a catalog, such as
+	// most files shipped with Windows, rather than an embedded signature.
+	CatalogSigned bool `json:"catalog_signed"`
+}
@@ -0,0 +1,12 @@
+//+build !windows
+
+package autoruns
+
+// Signature verification is only implemented for Authenticode, so images
+// are never known to be unsigned.
+const signaturesVerifiable = false
+
+// Signature verification is only implemented for Authenticode.
+func verifySignature(path string) (bool, *Signature) {
+	return false, nil
+}
@@ -0,0 +1,258 @@
+//+build windows
+
+package autoruns
+
+import (
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/hex"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modwintrust = windows.NewLazySystemDLL("wintrust.dll")
+
+	procCryptCATAdminAcquireContext2         = modwintrust.NewProc("CryptCATAdminAcquireContext2")
+	procCryptCATAdminReleaseContext          = modwintrust.NewProc("CryptCATAdminReleaseContext")
+	procCryptCATAdminCalcHashFromFileHandle2 = modwintrust.NewProc("CryptCATAdminCalcHashFromFileHandle2")
+	procCryptCATAdminEnumCatalogFromHash     = modwintrust.NewProc("CryptCATAdminEnumCatalogFromHash")
+	procCryptCATAdminReleaseCatalogContext   = modwintrust.NewProc("CryptCATAdminReleaseCatalogContext")
+	procCryptCATCatalogInfoFromContext       = modwintrust.NewProc("CryptCATCatalogInfoFromContext")
+
+	modcrypt32 = windows.NewLazySystemDLL("crypt32.dll")
+
+	procCryptMsgGetParam = modcrypt32.NewProc("CryptMsgGetParam")
+	procCryptMsgClose    = modcrypt32.NewProc("CryptMsgClose")
+)
+
+// CMSG_SIGNER_CERT_INFO_PARAM
+const cmsgSignerCertInfoParam = 7
+
+// CATALOG_INFO
+type catalogInfo struct {
+	size        uint32
+	catalogFile [windows.MAX_PATH]uint16
+}
+
+// WINTRUST_CATALOG_INFO
+type wintrustCatalogInfo struct {
+	size                   uint32
+	catalogVersion         uint32
+	catalogFilePath        *uint16
+	memberTag              *uint16
+	memberFilePath         *uint16
+	memberFile             windows.Handle
+	calculatedFileHash     *byte
+	calculatedFileHashSize uint32
+	catalogContext         uintptr
+	catAdmin               windows.Handle
+}
+
+// winVerifyTrust verifies the subject described by info with the generic
+// Authenticode policy, without any UI or revocation checks.
+func winVerifyTrust(unionChoice uint32, info unsafe.Pointer) error {
+	data := &windows.WinTrustData{
+		Size:                            uint32(unsafe.Sizeof(windows.WinTrustData{})),
+		UIChoice:                        windows.WTD_UI_NONE,
+		RevocationChecks:                windows.WTD_REVOKE_NONE,
+		UnionChoice:                     unionChoice,
+		StateAction:                     windows.WTD_STATEACTION_VERIFY,
+		FileOrCatalogOrBlobOrSgnrOrCert: info,
+	}
+	err := windows.WinVerifyTrustEx(windows.InvalidHWND, &windows.WINTRUST_ACTION_GENERIC_VERIFY_V2, data)
+
+	// Release the state data allocated by the verification.
+	data.StateAction = windows.WTD_STATEACTION_CLOSE
+	windows.WinVerifyTrustEx(windows.InvalidHWND, &windows.WINTRUST_ACTION_GENERIC_VERIFY_V2, data)
+
+	return err
+}
+
+// verifyEmbeddedSignature verifies the Authenticode signature embedded in
+// the file at path.
+func verifyEmbeddedSignature(path string) error {
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+
+	fileInfo := &windows.WinTrustFileInfo{
+		Size:     uint32(unsafe.Sizeof(windows.WinTrustFileInfo{})),
+		FilePath: pathPtr,
+	}
+
+	return winVerifyTrust(windows.WTD_CHOICE_FILE, unsafe.Pointer(fileInfo))
+}
+
+// verifyCatalogSignature verifies the file at path against the system
+// catalogs, which is how most files shipped with Windows are signed. It
+// returns the catalog the file is listed in, if any, even if the
+// verification fails.
+func verifyCatalogSignature(path string) (string, error) {
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return "", err
+	}
+
+	file, err := windows.CreateFile(pathPtr, windows.GENERIC_READ, windows.FILE_SHARE_READ, nil, windows.OPEN_EXISTING, 0, 0)
+	if err != nil {
+		return "", err
+	}
+	defer windows.CloseHandle(file)
+
+	// Catalogs are indexed either by SHA256 or, on older systems, by SHA1.
+	var catalog string
+	for _, algorithm := range []string{"SHA256", "SHA1"} {
+		var found string
+		found, err = verifyCatalogSignatureWith(algorithm, pathPtr, file)
+		if err == nil {
+			return found, nil
+		}
+		if catalog == "" {
+			catalog = found
+		}
+	}
+
+	return catalog, err
+}
+
+func verifyCatalogSignatureWith(algorithm string, pathPtr *uint16, file windows.Handle) (string, error) {
+	algorithmPtr, err := windows.UTF16PtrFromString(algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	var catAdmin windows.Handle
+	r, _, err := procCryptCATAdminAcquireContext2.Call(uintptr(unsafe.Pointer(&catAdmin)), 0, uintptr(unsafe.Pointer(algorithmPtr)), 0, 0)
+	if r == 0 {
+		return "", err
+	}
+	defer procCryptCATAdminReleaseContext.Call(uintptr(catAdmin), 0)
+
+	hashSize := uint32(64)
+	hash := make([]byte, hashSize)
+	r, _, err = procCryptCATAdminCalcHashFromFileHandle2.Call(uintptr(catAdmin), uintptr(file), uintptr(unsafe.Pointer(&hashSize)), uintptr(unsafe.Pointer(&hash[0])), 0)
+	if r == 0 {
+		return "", err
+	}
+	hash = hash[:hashSize]
+
+	catInfo, _, err := procCryptCATAdminEnumCatalogFromHash.Call(uintptr(catAdmin), uintptr(unsafe.Pointer(&hash[0])), uintptr(hashSize), 0, 0)
+	if catInfo == 0 {
+		return "", err
+	}
+	defer procCryptCATAdminReleaseCatalogContext.Call(uintptr(catAdmin), catInfo, 0)
+
+	info := catalogInfo{size: uint32(unsafe.Sizeof(catalogInfo{}))}
+	r, _, err = procCryptCATCatalogInfoFromContext.Call(catInfo, uintptr(unsafe.Pointer(&info)), 0)
+	if r == 0 {
+		return "", err
+	}
+	catalogPath := windows.UTF16ToString(info.catalogFile[:])
+
+	// The member tag of a file in a catalog is its hash in hex.
+	memberTag, err := windows.UTF16PtrFromString(strings.ToUpper(hex.EncodeToString(hash)))
+	if err != nil {
+		return catalogPath, err
+	}
+
+	catalog := &wintrustCatalogInfo{
+		size:                   uint32(unsafe.Sizeof(wintrustCatalogInfo{})),
+		catalogFilePath:        &info.catalogFile[0],
+		memberTag:              memberTag,
+		memberFilePath:         pathPtr,
+		memberFile:             file,
+		calculatedFileHash:     &hash[0],
+		calculatedFileHashSize: hashSize,
+		catAdmin:               catAdmin,
+	}
+
+	return catalogPath, winVerifyTrust(windows.WTD_CHOICE_CATALOG, unsafe.Pointer(catalog))
+}
+
+// readSignature reads the signing certificate of the signed file at path,
+// which is either a file with an embedded signature or a catalog. The
+// signature itself is not verified.
+func readSignature(path string) (*Signature, error) {
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var encoding, contentType, formatType uint32
+	var store, msg windows.Handle
+	err = windows.CryptQueryObject(windows.CERT_QUERY_OBJECT_FILE, unsafe.Pointer(pathPtr), windows.CERT_QUERY_CONTENT_FLAG_ALL, windows.CERT_QUERY_FORMAT_FLAG_BINARY, 0, &encoding, &contentType, &formatType, &store, &msg, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CertCloseStore(store, 0)
+	defer procCryptMsgClose.Call(uintptr(msg))
+	if msg == 0 {
+		return nil, windows.ERROR_NOT_FOUND
+	}
+
+	// The signer is identified by the issuer and serial number of its
+	// certificate, which is looked up in the certificates of the message.
+	var size uint32
+	r, _, err := procCryptMsgGetParam.Call(uintptr(msg), cmsgSignerCertInfoParam, 0, 0, uintptr(unsafe.Pointer(&size)))
+	if r == 0 {
+		return nil, err
+	}
+	buf := make([]byte, size)
+	r, _, err = procCryptMsgGetParam.Call(uintptr(msg), cmsgSignerCertInfoParam, 0, uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&size)))
+	if r == 0 {
+		return nil, err
+	}
+
+	cert, err := windows.CertFindCertificateInStore(store, windows.X509_ASN_ENCODING|windows.PKCS_7_ASN_ENCODING, 0, windows.CERT_FIND_SUBJECT_CERT, unsafe.Pointer(&buf[0]), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CertFreeCertificateContext(cert)
+
+	encoded := make([]byte, cert.Length)
+	copy(encoded, (*[1 << 20]byte)(unsafe.Pointer(cert.EncodedCert))[:cert.Length:cert.Length])
+	certificate, err := x509.ParseCertificate(encoded)
+	if err != nil {
+		return nil, err
+	}
+	thumbprint := sha1.Sum(encoded)
+
+	return &Signature{
+		Signer:     certificate.Subject.CommonName,
+		Issuer:     certificate.Issuer.CommonName,
+		Thumbprint: hex.EncodeToString(thumbprint[:]),
+		NotBefore:  certificate.NotBefore,
+		NotAfter:   certificate.NotAfter,
+	}, nil
+}
+
+// Authenticode signatures can be verified on Windows.
+const signaturesVerifiable = true
+
+// verifySignature checks whether the file at path has a valid Authenticode
+// signature, either embedded or through a system catalog. It also returns
+// the signature the file has, valid or not, if any.
+func verifySignature(path string) (bool, *Signature) {
+	if verifyEmbeddedSignature(path) == nil {
+		signature, _ := readSignature(path)
+		return true, signature
+	}
+
+	catalog, err := verifyCatalogSignature(path)
+	if catalog == "" {
+		// Without a catalog, an invalid signature can still be embedded.
+		signature, _ := readSignature(path)
+		return false, signature
+	}
+
+	signature, _ := readSignature(catalog)
+	if signature != nil {
+		signature.CatalogSigned = true
+	}
+
+	return err == nil, signature
+}
@@ -0,0 +1,46 @@
+//+build windows
+
+package autoruns
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerifySignatureSystemBinary(t *testing.T) {
+	path := filepath.Join(os.Getenv("SystemRoot"), "System32", "notepad.exe")
+	if _, err := os.Stat(path); err != nil {
+		t.Skipf("%s: %v", path, err)
+	}
+
+	signed, signature := verifySignature(path)
+	if !signed {
+		t.Errorf("%s is not signed", path)
+	}
+	if signature == nil {
+		t.Fatalf("%s has no signature", path)
+	}
+	if !strings.Contains(signature.Signer, "Microsoft") || !strings.Contains(signature.Issuer, "Microsoft") {
+		t.Errorf("got Signer %q, Issuer %q, want Microsoft's", signature.Signer, signature.Issuer)
+	}
+	if len(signature.Thumbprint) != 40 {
+		t.Errorf("got Thumbprint %q, want a SHA1 hash", signature.Thumbprint)
+	}
+	if signature.NotBefore.IsZero() || !signature.NotBefore.Before(signature.NotAfter) {
+		t.Errorf("got NotBefore %v, NotAfter %v", signature.NotBefore, signature.NotAfter)
+	}
+}
+
+func TestVerifySignatureUnsigned(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "unsigned.exe")
+	if err := ioutil.WriteFile(path, []byte("MZ"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if signed, signature := verifySignature(path); signed || signature != nil {
+		t.Errorf("got signed %v, signature %+v for an unsigned file", signed, signature)
+	}
+}
@@ -0,0 +1,67 @@
+package autoruns
+
+import "path/filepath"
+
+// Source identifies exactly where a record was found, so that it can be
+// opened again, e.g. to investigate or remediate it. Location remains the
+// human-readable summary of it.
+type Source struct {
+	// Kind is "registry_value", "file", "task" or "bits_job".
+	Kind string `json:"kind"`
+	// Host is the machine of a remote scan, see Options.RemoteHost.
+	Host string `json:"host"`
+	// Root, Key and Value locate a registry value: the root key, such as
+	// LOCAL_MACHINE, the path of the key under it, and the name of the
+	// value, which is empty for the default value of the key. View is
+	// "32" for keys of the 32-bit view under Wow6432Node and "64"
+	// otherwise.
+	Root  string `json:"root"`
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	View  string `json:"view"`
+	// Path is the absolute path of the file holding the record, such as
+	// the startup file or the plist, or the definition of a task.
+	Path string `json:"path"`
+	// Task is the path of a scheduled task in the task scheduler.
+	Task string `json:"task"`
+	// Job is the GUID of a BITS job.
+	Job string `json:"job"`
+}
+
+// The types whose Location is the directory the file holding the record,
+// named RawName, is in.
+var directoryLocationTypes = map[string]bool{
+	"startup":            true,
+	"powershell_profile": true,
+	"periodic":           true,
+	"at_job":             true,
+	"systemd_generator":  true,
+	"motd_script":        true,
+}
+
+// fileSource returns the Source of a record held by a file.
+func fileSource(autorun *Autorun) Source {
+	path := autorun.Location
+	if directoryLocationTypes[autorun.Type] {
+		path = filepath.Join(autorun.Location, autorun.RawName)
+	}
+
+	return Source{Kind: "file", Path: path}
+}
+
+// setSource fills in the Source of a record from where the scanner found
+// it, unless the scanner set it itself.
+func setSource(opts Options, autorun *Autorun) {
+	if autorun.Source.Kind == "" {
+		autorun.Source = sourceOf(autorun)
+	}
+	autorun.Source.Host = opts.RemoteHost
+}
+
+// setScope fills in the Scope of a record from its Source, unless the
+// scanner set it itself, which it does for the files of users.
+func setScope(opts Options, autorun *Autorun) {
+	if autorun.Scope == "" {
+		autorun.Scope = scopeOf(opts, autorun.Source)
+	}
+}
@@ -0,0 +1,22 @@
+//+build !windows
+
+package autoruns
+
+import "time"
+
+// sourceOf returns the Source of a record. All records are held by files
+// on this platform.
+func sourceOf(autorun *Autorun) Source {
+	return fileSource(autorun)
+}
+
+// scopeOf returns the Scope of a record from its Source. Files are
+// machine-wide unless the scanner knows better.
+func scopeOf(opts Options, source Source) string {
+	return "machine"
+}
+
+// sourceModTime returns when the file holding a record was last modified.
+func sourceModTime(opts Options, source Source) time.Time {
+	return fileModTime(opts, source.Path)
+}
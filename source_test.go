@@ -0,0 +1,38 @@
+package autoruns
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSetSourceFiles(t *testing.T) {
+	dir := filepath.Join(string(filepath.Separator)+"etc", "update-motd.d")
+	crontab := filepath.Join(string(filepath.Separator)+"etc", "crontab")
+
+	tests := []struct {
+		autorun *Autorun
+		want    Source
+	}{
+		// The Location of most files is the file itself.
+		{&Autorun{Type: "cron", Location: crontab, RawName: "crontab"}, Source{Kind: "file", Path: crontab}},
+		// Others are found in the directory given as the Location.
+		{&Autorun{Type: "motd_script", Location: dir, RawName: "00-header"}, Source{Kind: "file", Path: filepath.Join(dir, "00-header")}},
+		{&Autorun{Type: "periodic", Location: dir, RawName: "logrotate"}, Source{Kind: "file", Path: filepath.Join(dir, "logrotate")}},
+		// The Source set by the scanner is kept.
+		{&Autorun{Type: "bits_job", Location: "BITS", Source: Source{Kind: "bits_job", Job: "{id}"}}, Source{Kind: "bits_job", Job: "{id}"}},
+	}
+	for _, test := range tests {
+		setSource(Options{}, test.autorun)
+		if test.autorun.Source != test.want {
+			t.Errorf("%s: Source = %+v, want %+v", test.autorun.Type, test.autorun.Source, test.want)
+		}
+	}
+}
+
+func TestSetSourceHost(t *testing.T) {
+	autorun := &Autorun{Type: "cron", Location: "crontab"}
+	setSource(Options{RemoteHost: "workstation"}, autorun)
+	if autorun.Source.Host != "workstation" {
+		t.Errorf("Host = %q, want the remote host", autorun.Source.Host)
+	}
+}
@@ -0,0 +1,97 @@
+//+build windows
+
+package autoruns
+
+import (
+	"strings"
+	"time"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// The roots of the locations we report, by the name registryToString gives
+// them.
+var registryRoots = map[string]registry.Key{
+	"LOCAL_MACHINE": registry.LOCAL_MACHINE,
+	"CURRENT_USER":  registry.CURRENT_USER,
+	"CLASSES_ROOT":  registry.CLASSES_ROOT,
+	"USERS":         registry.USERS,
+}
+
+// parseRegistryLocation splits a location we report into the root and the
+// path of the key.
+func parseRegistryLocation(location string) (registry.Key, string, bool) {
+	separator := strings.Index(location, "\\")
+	if separator < 0 {
+		return 0, "", false
+	}
+
+	reg, ok := registryRoots[location[:separator]]
+	return reg, location[separator+1:], ok
+}
+
+// sourceOf returns the Source of a record, which is a registry value if its
+// Location is a registry key.
+func sourceOf(autorun *Autorun) Source {
+	if autorun.Type == "scheduled_task" {
+		return Source{Kind: "task", Path: autorun.Location, Task: autorun.Entry}
+	}
+
+	reg, keyPath, ok := parseRegistryLocation(autorun.Location)
+	if !ok {
+		return fileSource(autorun)
+	}
+
+	view := "64"
+	if strings.Contains(strings.ToLower("\\"+keyPath+"\\"), "\\wow6432node\\") {
+		view = "32"
+	}
+
+	return Source{
+		Kind:  "registry_value",
+		Root:  registryToString(reg),
+		Key:   keyPath,
+		Value: autorun.RawName,
+		View:  view,
+	}
+}
+
+// scopeOf returns the Scope of a record from its Source. The values of
+// the user hives are per-user, and those of CLASSES_ROOT if they come from
+// the per-user part it merges in. Files are machine-wide unless the scanner
+// knows better.
+func scopeOf(opts Options, source Source) string {
+	switch source.Root {
+	case "CURRENT_USER", "USERS":
+		return "user"
+	case "CLASSES_ROOT":
+		if key, err := registryFor(opts).OpenKey(registry.CURRENT_USER, "Software\\Classes\\"+source.Key); err == nil {
+			key.Close()
+			return "user"
+		}
+	}
+
+	return "machine"
+}
+
+// sourceModTime returns when the registry key or file holding a record was
+// last written to.
+func sourceModTime(opts Options, source Source) time.Time {
+	if source.Kind != "registry_value" {
+		return fileModTime(opts, source.Path)
+	}
+
+	return opts.memo("key_mtime:"+source.Root+"\\"+source.Key, func() interface{} {
+		key, err := registryFor(opts).OpenKey(registryRoots[source.Root], source.Key)
+		if err != nil {
+			return time.Time{}
+		}
+		defer key.Close()
+
+		info, err := key.Stat()
+		if err != nil {
+			return time.Time{}
+		}
+		return info.ModTime()
+	}).(time.Time)
+}
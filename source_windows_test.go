@@ -0,0 +1,71 @@
+//+build windows
+
+package autoruns
+
+import "testing"
+
+func TestSourceOfWindows(t *testing.T) {
+	startup := `C:\Users\alice\AppData\Roaming\Microsoft\Windows\Start Menu\Programs\StartUp`
+
+	tests := []struct {
+		autorun *Autorun
+		want    Source
+	}{
+		{
+			&Autorun{Type: "run_key", Location: `LOCAL_MACHINE\Software\Microsoft\Windows\CurrentVersion\Run`, RawName: "Agent"},
+			Source{Kind: "registry_value", Root: "LOCAL_MACHINE", Key: `Software\Microsoft\Windows\CurrentVersion\Run`, Value: "Agent", View: "64"},
+		},
+		{
+			&Autorun{Type: "run_key", Location: `LOCAL_MACHINE\Software\WOW6432Node\Microsoft\Windows\CurrentVersion\Run`, RawName: "Legacy"},
+			Source{Kind: "registry_value", Root: "LOCAL_MACHINE", Key: `Software\WOW6432Node\Microsoft\Windows\CurrentVersion\Run`, Value: "Legacy", View: "32"},
+		},
+		// The default value of a key has no name.
+		{
+			&Autorun{Type: "clsid", Location: `CLASSES_ROOT\CLSID\{id}\InprocServer32`},
+			Source{Kind: "registry_value", Root: "CLASSES_ROOT", Key: `CLSID\{id}\InprocServer32`, View: "64"},
+		},
+		{
+			&Autorun{Type: "scheduled_task", Location: `C:\Windows\System32\Tasks\Vendor\Update`, Entry: `\Vendor\Update`},
+			Source{Kind: "task", Path: `C:\Windows\System32\Tasks\Vendor\Update`, Task: `\Vendor\Update`},
+		},
+		{
+			&Autorun{Type: "startup", Location: startup, RawName: `a\agent.lnk`},
+			Source{Kind: "file", Path: startup + `\a\agent.lnk`},
+		},
+		{
+			&Autorun{Type: "powershell_profile", Location: `C:\Windows\System32\WindowsPowerShell\v1.0`, RawName: "profile.ps1"},
+			Source{Kind: "file", Path: `C:\Windows\System32\WindowsPowerShell\v1.0\profile.ps1`},
+		},
+	}
+	for _, test := range tests {
+		if got := sourceOf(test.autorun); got != test.want {
+			t.Errorf("%s %s: Source = %+v, want %+v", test.autorun.Type, test.autorun.Location, got, test.want)
+		}
+	}
+}
+
+func TestScopeOfWindows(t *testing.T) {
+	opts := Options{}
+	opts.registry = fakeRegistry{
+		`CURRENT_USER\Software\Classes\CLSID\{user}`: {},
+	}
+
+	tests := []struct {
+		source Source
+		want   string
+	}{
+		{Source{Kind: "registry_value", Root: "LOCAL_MACHINE", Key: "Software"}, "machine"},
+		{Source{Kind: "registry_value", Root: "CURRENT_USER", Key: "Software"}, "user"},
+		{Source{Kind: "registry_value", Root: "USERS", Key: `S-1-5-21-1\Software`}, "user"},
+		// CLASSES_ROOT merges the classes of the user into those of the
+		// machine.
+		{Source{Kind: "registry_value", Root: "CLASSES_ROOT", Key: `CLSID\{user}`}, "user"},
+		{Source{Kind: "registry_value", Root: "CLASSES_ROOT", Key: `CLSID\{machine}`}, "machine"},
+		{Source{Kind: "file", Path: `C:\ProgramData\file`}, "machine"},
+	}
+	for _, test := range tests {
+		if got := scopeOf(opts, test.source); got != test.want {
+			t.Errorf("scopeOf(%+v) = %q, want %q", test.source, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+//+build sqlite
+
+package autoruns
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	// Registers the sqlite3 driver.
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const sqliteSchema = `CREATE TABLE IF NOT EXISTS autoruns (
+	id TEXT NOT NULL,
+	collected_at INTEGER NOT NULL,
+	type TEXT NOT NULL,
+	location TEXT NOT NULL,
+	image_path TEXT NOT NULL,
+	sha256 TEXT NOT NULL,
+	record TEXT NOT NULL,
+	PRIMARY KEY (id, collected_at)
+)`
+
+// openSQLite opens the database at dbPath, creating the schema if needed.
+func openSQLite(dbPath string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// WriteSQLite stores records as a snapshot taken at collectedAt in the
+// SQLite database at dbPath, creating it if needed. Records are keyed by
+// their ID, so writing the same snapshot twice updates it in place, while
+// snapshots taken at different times are kept side by side for later
+// comparison.
+//
+// This is only available when building with the sqlite tag.
+func WriteSQLite(dbPath string, records []*Autorun, collectedAt time.Time) error {
+	db, err := openSQLite(dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO autoruns (id, collected_at, type, location, image_path, sha256, record)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id, collected_at) DO UPDATE SET
+			type = excluded.type,
+			location = excluded.location,
+			image_path = excluded.image_path,
+			sha256 = excluded.sha256,
+			record = excluded.record`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, record := range records {
+		data, err := json.Marshal(record)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if _, err := stmt.Exec(record.ID(), collectedAt.UnixNano(), record.Type, record.Location,
+			record.ImagePath, record.SHA256, string(data)); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ReadLatestSQLite returns the records of the most recent snapshot stored
+// in the SQLite database at dbPath, along with the time it was collected.
+//
+// This is only available when building with the sqlite tag.
+func ReadLatestSQLite(dbPath string) ([]*Autorun, time.Time, error) {
+	db, err := openSQLite(dbPath)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer db.Close()
+
+	var latest sql.NullInt64
+	if err := db.QueryRow(`SELECT MAX(collected_at) FROM autoruns`).Scan(&latest); err != nil {
+		return nil, time.Time{}, err
+	}
+	if !latest.Valid {
+		return nil, time.Time{}, nil
+	}
+	collectedAt := time.Unix(0, latest.Int64)
+
+	rows, err := db.Query(`SELECT record FROM autoruns WHERE collected_at = ? ORDER BY id`, latest.Int64)
+	if err != nil {
+		return nil, collectedAt, err
+	}
+	defer rows.Close()
+
+	var records []*Autorun
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, collectedAt, err
+		}
+		var record Autorun
+		if err := json.Unmarshal([]byte(data), &record); err != nil {
+			return nil, collectedAt, err
+		}
+		records = append(records, &record)
+	}
+
+	return records, collectedAt, rows.Err()
+}
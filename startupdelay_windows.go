@@ -0,0 +1,69 @@
+//+build windows
+
+package autoruns
+
+import (
+	"fmt"
+	"strconv"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// The key holding the delay Explorer waits for before starting the Run
+// entries and the Startup folder items at logon.
+var startupDelayKey string = "Software\\Microsoft\\Windows\\CurrentVersion\\Explorer\\Serialize"
+
+// startupDelayDisabled checks whether the startup delay is configured to be
+// zero, so that startup items run as soon as Explorer starts.
+func startupDelayDisabled(opts Options) bool {
+	return opts.memo("startup_delay_disabled", func() interface{} {
+		for _, reg := range []registry.Key{registry.LOCAL_MACHINE, registry.CURRENT_USER} {
+			key, err := registryFor(opts).OpenKey(reg, startupDelayKey)
+			if err != nil {
+				continue
+			}
+			delay, _, err := key.GetIntegerValue("StartupDelayInMSec")
+			key.Close()
+			if err == nil && delay == 0 {
+				return true
+			}
+		}
+		return false
+	}).(bool)
+}
+
+// This function reports a StartupDelayInMSec value, which is not set by
+// default, as context for the Run entries and Startup folder items whose
+// timing it changes.
+func windowsGetStartupDelay(opts Options) (records []*Autorun) {
+	regs := []registry.Key{
+		registry.LOCAL_MACHINE,
+		registry.CURRENT_USER,
+	}
+
+	for _, reg := range regs {
+		// Open registry key.
+		key, err := openKey(opts, reg, startupDelayKey)
+		if err != nil {
+			continue
+		}
+
+		delay, _, err := key.GetIntegerValue("StartupDelayInMSec")
+		key.Close()
+		if err != nil {
+			continue
+		}
+
+		records = append(records, &Autorun{
+			Type:         "startup_delay",
+			Location:     fmt.Sprintf("%s\\%s", registryToString(reg), startupDelayKey),
+			Entry:        "StartupDelayInMSec",
+			RawName:      "StartupDelayInMSec",
+			LaunchString: strconv.FormatUint(delay, 10),
+			NonDefault:   true,
+			User:         keyUser(opts, reg),
+		})
+	}
+
+	return
+}
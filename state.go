@@ -0,0 +1,68 @@
+package autoruns
+
+import "sync"
+
+// scanState holds what is shared between the scanners of a single scan.
+type scanState struct {
+	warnings warningList
+
+	mu       sync.Mutex
+	values   map[string]*memoized
+	cleanups []func()
+}
+
+type memoized struct {
+	once  sync.Once
+	value interface{}
+}
+
+func newScanState() *scanState {
+	return &scanState{values: make(map[string]*memoized)}
+}
+
+// finish runs the functions registered with onDone, most recent first.
+func (s *scanState) finish() {
+	s.mu.Lock()
+	cleanups := s.cleanups
+	s.cleanups = nil
+	s.mu.Unlock()
+
+	for i := len(cleanups) - 1; i >= 0; i-- {
+		cleanups[i]()
+	}
+}
+
+// memo returns the value computed by fn for key, calling fn only once per
+// scan no matter how many scanners ask for it.
+func (o Options) memo(key string, fn func() interface{}) interface{} {
+	if o.state == nil {
+		return fn()
+	}
+
+	o.state.mu.Lock()
+	m, ok := o.state.values[key]
+	if !ok {
+		m = &memoized{}
+		o.state.values[key] = m
+	}
+	o.state.mu.Unlock()
+
+	m.once.Do(func() {
+		m.value = fn()
+	})
+
+	return m.value
+}
+
+// onDone registers fn to be called once the scan has completed, e.g. to
+// release resources shared through memo.
+func (o Options) onDone(fn func()) {
+	if o.state == nil {
+		return
+	}
+
+	o.state.mu.Lock()
+	defer o.state.mu.Unlock()
+
+	o.state.cleanups = append(o.state.cleanups, fn)
+}
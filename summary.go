@@ -0,0 +1,82 @@
+package autoruns
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// contextTypes are the types of the records which are not autoruns, but
+// context about the state of the machine they were found on.
+var contextTypes = map[string]bool{
+	"defender_tamper": true,
+	"run_mru":         true,
+}
+
+// Summary holds statistics about the records found by a scan.
+type Summary struct {
+	// TotalRecords and ByType only count the autoruns, and Context counts
+	// the other records, such as defender_tamper.
+	TotalRecords int            `json:"total_records"`
+	ByType       map[string]int `json:"by_type"`
+	Context      int            `json:"context"`
+	// Unsigned is only counted when signatures were verified, which is
+	// only possible on Windows.
+	Unsigned    int `json:"unsigned"`
+	MissingFile int `json:"missing_file"`
+	// Errors counts the warnings of locations which could not be read,
+	// and NotFound those of locations which do not exist, such as the
+	// optional registry keys most machines do not have.
+	Errors   int `json:"errors"`
+	NotFound int `json:"not_found"`
+}
+
+// summarize computes the summary of a scan performed with opts.
+func summarize(opts Options, records []*Autorun, warnings []error) Summary {
+	summary := Summary{ByType: make(map[string]int)}
+
+	// On Windows, registry.ErrNotExist also matches os.ErrNotExist.
+	for _, warning := range warnings {
+		if errors.Is(warning, os.ErrNotExist) {
+			summary.NotFound++
+		} else {
+			summary.Errors++
+		}
+	}
+
+	for _, record := range records {
+		if contextTypes[record.Type] {
+			summary.Context++
+			continue
+		}
+		summary.TotalRecords++
+		summary.ByType[record.Type]++
+		if record.FileMissing {
+			summary.MissingFile++
+		} else if unsignedImage(opts, record) {
+			summary.Unsigned++
+		}
+	}
+
+	return summary
+}
+
+// String formats the summary on a single line, with the types sorted by
+// name.
+func (s Summary) String() string {
+	types := make([]string, 0, len(s.ByType))
+	for entryType := range s.ByType {
+		types = append(types, entryType)
+	}
+	sort.Strings(types)
+
+	byType := make([]string, 0, len(types))
+	for _, entryType := range types {
+		byType = append(byType, fmt.Sprintf("%s=%d", entryType, s.ByType[entryType]))
+	}
+
+	return fmt.Sprintf("total=%d context=%d unsigned=%d missing_file=%d errors=%d not_found=%d types=[%s]",
+		s.TotalRecords, s.Context, s.Unsigned, s.MissingFile, s.Errors, s.NotFound, strings.Join(byType, " "))
+}
@@ -0,0 +1,41 @@
+package autoruns
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	records := []*Autorun{
+		{Type: "run_key", ImagePath: "C:\\a.exe"},
+		{Type: "run_key", ImagePath: "C:\\b.exe", Signed: true},
+		{Type: "service", ImagePath: "C:\\c.exe", FileMissing: true},
+		{Type: "defender_tamper"},
+	}
+	warnings := []error{
+		ErrFileTimeout,
+		&ScanError{Category: "run_keys", Location: `HKLM\Software\Optional`, Err: os.ErrNotExist},
+	}
+	summary := summarize(Options{VerifySignatures: true}, records, warnings)
+
+	if summary.TotalRecords != 3 || summary.Context != 1 {
+		t.Errorf("TotalRecords, Context = %d, %d, want 3, 1", summary.TotalRecords, summary.Context)
+	}
+	if summary.ByType["run_key"] != 2 || summary.ByType["service"] != 1 {
+		t.Errorf("ByType = %v", summary.ByType)
+	}
+	if summary.MissingFile != 1 || summary.Errors != 1 || summary.NotFound != 1 {
+		t.Errorf("MissingFile, Errors, NotFound = %d, %d, %d, want 1, 1, 1",
+			summary.MissingFile, summary.Errors, summary.NotFound)
+	}
+
+	// Only the unsigned image which exists counts, and only where
+	// signatures can be verified.
+	wantUnsigned := 0
+	if signaturesVerifiable {
+		wantUnsigned = 1
+	}
+	if summary.Unsigned != wantUnsigned {
+		t.Errorf("Unsigned = %d, want %d", summary.Unsigned, wantUnsigned)
+	}
+}
@@ -0,0 +1,433 @@
+//+build linux
+
+package autoruns
+
+import (
+	"bufio"
+	"bytes"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The directories systemd loads system units from, by precedence.
+var systemdSystemUnitDirs = []string{
+	"/etc/systemd/system",
+	"/run/systemd/system",
+	"/usr/local/lib/systemd/system",
+	"/usr/lib/systemd/system",
+	"/lib/systemd/system",
+}
+
+// The directories systemd loads the units of every user from, after the
+// directories of the user, see systemdPersonalUnitDirs. Units enabled in
+// them run for every user.
+var systemdUserUnitDirs = []string{
+	"/etc/systemd/user",
+	"/usr/local/lib/systemd/user",
+	"/usr/lib/systemd/user",
+}
+
+// The directories relative to a home directory systemd loads the units of
+// the user from.
+var systemdPersonalUnitDirs = []string{
+	".config/systemd/user",
+	".local/share/systemd/user",
+}
+
+// The directories of generators, which systemd runs early at boot and
+// whenever its configuration is reloaded.
+var systemdGeneratorDirs = []string{
+	"/etc/systemd/system-generators",
+	"/run/systemd/system-generators",
+	"/usr/local/lib/systemd/system-generators",
+	"/usr/lib/systemd/system-generators",
+	"/lib/systemd/system-generators",
+	"/etc/systemd/user-generators",
+	"/usr/local/lib/systemd/user-generators",
+	"/usr/lib/systemd/user-generators",
+}
+
+// The settings of a timer unit which define when it elapses.
+var systemdTimerSettings = []string{
+	"OnActiveSec",
+	"OnBootSec",
+	"OnStartupSec",
+	"OnUnitActiveSec",
+	"OnUnitInactiveSec",
+	"OnCalendar",
+}
+
+// The settings of a service which run commands, in the order systemd runs
+// them. Those other than ExecStart are often overlooked in otherwise
+// legitimate units.
+var systemdExecSettings = []string{
+	"ExecCondition",
+	"ExecStartPre",
+	"ExecStart",
+	"ExecStartPost",
+	"ExecReload",
+	"ExecStop",
+	"ExecStopPost",
+}
+
+// systemdValue is a value of a setting, along with the unit file or
+// drop-in it is given in.
+type systemdValue struct {
+	value string
+	path  string
+}
+
+// systemdUnit holds the settings of a unit by section, merged from its
+// unit file and drop-ins. A setting can be given multiple times, e.g.
+// ExecStart in a oneshot service.
+type systemdUnit map[string]map[string][]systemdValue
+
+// values returns the values of a setting.
+func (u systemdUnit) values(section string, key string) (values []string) {
+	for _, value := range u[section][key] {
+		values = append(values, value.value)
+	}
+
+	return
+}
+
+// parseSystemdUnit parses a unit file.
+func parseSystemdUnit(data []byte, path string) systemdUnit {
+	unit := make(systemdUnit)
+	unit.merge(data, path)
+
+	return unit
+}
+
+// merge adds the settings of the unit file or drop-in at path to the unit.
+// An empty assignment resets the list of values of a setting, as it does
+// for systemd, e.g. to replace the ExecStart of a unit from a drop-in.
+func (u systemdUnit) merge(data []byte, path string) {
+	var section string
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	var line string
+	for scanner.Scan() {
+		// Lines ending with a backslash continue on the next one.
+		text := strings.TrimSpace(scanner.Text())
+		if strings.HasSuffix(text, "\\") {
+			line += strings.TrimSuffix(text, "\\") + " "
+			continue
+		}
+		line += text
+
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			section = line[1 : len(line)-1]
+			if u[section] == nil {
+				u[section] = make(map[string][]systemdValue)
+			}
+		case section != "" && strings.Contains(line, "="):
+			separator := strings.Index(line, "=")
+			key := strings.TrimSpace(line[:separator])
+			value := strings.TrimSpace(line[separator+1:])
+			if value == "" {
+				delete(u[section], key)
+			} else {
+				u[section][key] = append(u[section][key], systemdValue{value, path})
+			}
+		}
+		line = ""
+	}
+}
+
+// systemdCommand strips the prefixes of an Exec setting, which control how
+// the command is run, e.g. "-" to ignore its failure. With "@", the second
+// word is the argv[0] the executable is run with rather than an argument,
+// and is dropped.
+func systemdCommand(command string) string {
+	stripped := strings.TrimLeft(command, "-@:+!")
+	if !strings.Contains(command[:len(command)-len(stripped)], "@") {
+		return stripped
+	}
+
+	fields := strings.Fields(stripped)
+	if len(fields) == 0 {
+		return stripped
+	}
+
+	return strings.TrimSpace(fields[0] + " " + cutFields(stripped, 2))
+}
+
+// systemdScope is a set of unit directories, either of the system, of the
+// units of every user or of a single user. Units are enabled in wantsDirs
+// and defined in unitDirs, which for a single user also include the
+// directories of every user.
+type systemdScope struct {
+	user      string
+	perUser   bool
+	wantsDirs []string
+	unitDirs  []string
+}
+
+// systemdScopes returns the scopes to scan units in: the system, the units
+// of every user and every user with a home directory, or only the current
+// user with UserScopeOnly.
+func systemdScopes(opts Options) (scopes []systemdScope) {
+	userScope := func(home string) systemdScope {
+		var user string
+		if opts.ResolveUsers {
+			user = filepath.Base(home)
+		}
+		var personalDirs []string
+		for _, dir := range systemdPersonalUnitDirs {
+			personalDirs = append(personalDirs, filepath.Join(home, dir))
+		}
+		return systemdScope{
+			user:      user,
+			perUser:   true,
+			wantsDirs: personalDirs,
+			unitDirs:  append(personalDirs, systemdUserUnitDirs...),
+		}
+	}
+
+	if !opts.UserScopeOnly {
+		scopes = append(scopes,
+			systemdScope{wantsDirs: systemdSystemUnitDirs, unitDirs: systemdSystemUnitDirs},
+			systemdScope{wantsDirs: systemdUserUnitDirs, unitDirs: systemdUserUnitDirs},
+		)
+	}
+	for _, home := range homeDirectories(opts) {
+		scopes = append(scopes, userScope(home))
+	}
+
+	return
+}
+
+// enabledUnits returns the names of the units enabled in a scope, which are
+// those linked from a .wants or .requires directory.
+func enabledUnits(opts Options, scope systemdScope) (names []string) {
+	seen := make(map[string]bool)
+	for _, dir := range scope.wantsDirs {
+		entries, err := fileSystemFor(opts).ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			if !entry.IsDir() || (!strings.HasSuffix(entry.Name(), ".wants") && !strings.HasSuffix(entry.Name(), ".requires")) {
+				continue
+			}
+
+			links, err := fileSystemFor(opts).ReadDir(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				continue
+			}
+			for _, link := range links {
+				if !seen[link.Name()] {
+					seen[link.Name()] = true
+					names = append(names, link.Name())
+				}
+			}
+		}
+	}
+
+	return
+}
+
+// findUnit returns the path of the file defining a unit in a scope along
+// with its settings, including those of its drop-ins. Instances of template
+// units, such as getty@tty1, are defined by the template, such as
+// getty@.service. Masked units, which are linked to /dev/null, are not
+// found.
+func findUnit(opts Options, scope systemdScope, name string) (string, systemdUnit, bool) {
+	candidates := []string{name}
+	if at := strings.Index(name, "@"); at >= 0 {
+		candidates = append(candidates, name[:at+1]+filepath.Ext(name))
+	}
+
+	for _, candidate := range candidates {
+		for _, dir := range scope.unitDirs {
+			unitPath := filepath.Join(dir, candidate)
+			info, err := fileSystemFor(opts).Stat(unitPath)
+			if err != nil {
+				continue
+			}
+			if !info.Mode().IsRegular() {
+				return "", nil, false
+			}
+
+			data, err := readFile(fileSystemFor(opts), unitPath)
+			if err != nil {
+				opts.Warn(unitPath, err)
+				return "", nil, false
+			}
+
+			unit := parseSystemdUnit(data, unitPath)
+			for _, dropIn := range unitDropIns(opts, scope, candidates) {
+				data, err := readFile(fileSystemFor(opts), dropIn)
+				if err != nil {
+					opts.Warn(dropIn, err)
+					continue
+				}
+				unit.merge(data, dropIn)
+			}
+
+			return unitPath, unit, true
+		}
+	}
+
+	return "", nil, false
+}
+
+// unitDropIns returns the drop-ins of a unit in a scope, the .conf files
+// of the <name>.d directories of its names, in the order systemd applies
+// them: sorted by file name, where a drop-in of a directory with a higher
+// precedence replaces those with the same name. Masked drop-ins, which are
+// linked to /dev/null, replace them with nothing.
+func unitDropIns(opts Options, scope systemdScope, names []string) (dropIns []string) {
+	found := make(map[string]string)
+	var fileNames []string
+	for _, dir := range scope.unitDirs {
+		for _, name := range names {
+			dropInDir := filepath.Join(dir, name+".d")
+			files, err := fileSystemFor(opts).ReadDir(dropInDir)
+			if err != nil {
+				continue
+			}
+
+			for _, file := range files {
+				if _, ok := found[file.Name()]; ok || filepath.Ext(file.Name()) != ".conf" {
+					continue
+				}
+				fileNames = append(fileNames, file.Name())
+				found[file.Name()] = ""
+				dropIn := filepath.Join(dropInDir, file.Name())
+				if info, err := fileSystemFor(opts).Stat(dropIn); err == nil && info.Mode().IsRegular() {
+					found[file.Name()] = dropIn
+				}
+			}
+		}
+	}
+
+	sort.Strings(fileNames)
+	for _, fileName := range fileNames {
+		if found[fileName] != "" {
+			dropIns = append(dropIns, found[fileName])
+		}
+	}
+
+	return
+}
+
+// serviceAutoruns returns an Autorun for each command a service runs, with
+// the Exec setting as RawName and the unit file or drop-in giving it as
+// Location. A command given in more than one setting is only reported for
+// the first of them.
+func serviceAutoruns(opts Options, entryType string, unit systemdUnit, entry string) (records []*Autorun) {
+	seen := make(map[string]bool)
+	for _, setting := range systemdExecSettings {
+		for _, command := range unit["Service"][setting] {
+			stripped := systemdCommand(command.value)
+			if seen[stripped] {
+				continue
+			}
+			seen[stripped] = true
+
+			// Commands given in a drop-in are found there.
+			newAutorun := shellCommandToAutorun(opts, entryType, command.path, stripped, entry)
+			newAutorun.RawName = setting
+			newAutorun.LaunchString = command.value
+
+			records = append(records, newAutorun)
+		}
+	}
+
+	return
+}
+
+// This function enumerates the enabled systemd services and timers of the
+// system and of every user, with a record for every command run by an
+// Exec setting of the service. Timers are reported with the commands of the
+// service they activate, and with when they elapse as Trigger.
+func linuxGetSystemd(opts Options) (records []*Autorun) {
+	for _, scope := range systemdScopes(opts) {
+		if opts.Context().Err() != nil {
+			return
+		}
+
+		for _, name := range enabledUnits(opts, scope) {
+			unitPath, unit, ok := findUnit(opts, scope, name)
+			if !ok {
+				continue
+			}
+
+			var unitRecords []*Autorun
+			switch filepath.Ext(name) {
+			case ".service":
+				unitRecords = serviceAutoruns(opts, "systemd_service", unit, name)
+			case ".timer":
+				// A timer activates the service with the same name, unless
+				// it names another unit.
+				serviceName := strings.TrimSuffix(name, ".timer") + ".service"
+				if units := unit.values("Timer", "Unit"); len(units) > 0 {
+					serviceName = units[len(units)-1]
+				}
+				_, service, ok := findUnit(opts, scope, serviceName)
+				if !ok {
+					continue
+				}
+
+				var triggers []string
+				for _, setting := range systemdTimerSettings {
+					for _, value := range unit.values("Timer", setting) {
+						triggers = append(triggers, setting+"="+value)
+					}
+				}
+
+				unitRecords = serviceAutoruns(opts, "systemd_timer", service, name)
+				for _, record := range unitRecords {
+					record.Location = unitPath
+					record.Trigger = strings.Join(triggers, ", ")
+				}
+			}
+
+			for _, record := range unitRecords {
+				record.User = scope.user
+				if scope.perUser {
+					record.Scope = "user"
+				}
+			}
+			records = append(records, unitRecords...)
+		}
+	}
+
+	return
+}
+
+// This function enumerates the systemd generators, executables which
+// systemd runs at boot and on every reload to generate units.
+func linuxGetSystemdGenerators(opts Options) (records []*Autorun) {
+	for _, dir := range systemdGeneratorDirs {
+		files, err := fileSystemFor(opts).ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, file := range files {
+			if file.IsDir() {
+				continue
+			}
+
+			generatorPath := filepath.Join(dir, file.Name())
+			records = append(records, &Autorun{
+				Type:         "systemd_generator",
+				Location:     dir,
+				ImagePath:    generatorPath,
+				ImageName:    file.Name(),
+				Entry:        file.Name(),
+				RawName:      file.Name(),
+				LaunchString: generatorPath,
+			})
+		}
+	}
+
+	return
+}
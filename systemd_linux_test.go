@@ -0,0 +1,164 @@
+//+build linux
+
+package autoruns
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSystemdCommand(t *testing.T) {
+	tests := map[string]string{
+		"/usr/bin/sshd -D":                "/usr/bin/sshd -D",
+		"-/usr/bin/true":                  "/usr/bin/true",
+		"+/usr/local/bin/hook --quiet":    "/usr/local/bin/hook --quiet",
+		"-+/usr/local/bin/hook":           "/usr/local/bin/hook",
+		"@/usr/bin/daemon fake-name -x y": "/usr/bin/daemon -x y",
+		"-@/usr/bin/daemon fake-name":     "/usr/bin/daemon",
+	}
+	for command, want := range tests {
+		if got := systemdCommand(command); got != want {
+			t.Errorf("systemdCommand(%q) = %q, want %q", command, got, want)
+		}
+	}
+}
+
+// serviceCommands returns the RawName, ImagePath and Arguments of the
+// records of a service.
+func serviceCommands(records []*Autorun) (commands [][3]string) {
+	for _, record := range records {
+		commands = append(commands, [3]string{record.RawName, record.ImagePath, record.Arguments})
+	}
+
+	return
+}
+
+func TestServiceAutorunsExecSettings(t *testing.T) {
+	unit := parseSystemdUnit([]byte(`[Unit]
+Description=OpenSSH server
+
+[Service]
+ExecStartPre=-/usr/bin/mkdir -p /run/sshd
+ExecStartPre=/usr/sbin/sshd -t
+ExecStart=/usr/sbin/sshd -D
+ExecStartPost=+/tmp/.cache/payload --quiet
+ExecStartPost=@/usr/bin/logger ssh-started started
+ExecStop=/usr/sbin/sshd -D
+ExecStopPost=/usr/bin/rm -f \
+	/run/sshd.pid
+`), "/etc/systemd/system/ssh.service")
+
+	want := [][3]string{
+		{"ExecStartPre", "/usr/bin/mkdir", "-p /run/sshd"},
+		{"ExecStartPre", "/usr/sbin/sshd", "-t"},
+		{"ExecStart", "/usr/sbin/sshd", "-D"},
+		{"ExecStartPost", "/tmp/.cache/payload", "--quiet"},
+		{"ExecStartPost", "/usr/bin/logger", "started"},
+		// ExecStop runs the same command as ExecStart, and is not reported
+		// again.
+		{"ExecStopPost", "/usr/bin/rm", "-f  /run/sshd.pid"},
+	}
+	records := serviceAutoruns(Options{QuickScan: true}, "systemd_service", unit, "ssh.service")
+	if got := serviceCommands(records); !reflect.DeepEqual(got, want) {
+		t.Errorf("got commands %q, want %q", got, want)
+	}
+	for _, record := range records {
+		if record.Location != "/etc/systemd/system/ssh.service" {
+			t.Errorf("%s: Location = %q, want the unit file", record.RawName, record.Location)
+		}
+	}
+	if records[3].LaunchString != "+/tmp/.cache/payload --quiet" {
+		t.Errorf("LaunchString = %q, want the setting as given", records[3].LaunchString)
+	}
+}
+
+func TestFindUnitMergesDropIns(t *testing.T) {
+	fsys := newFakeFileSystem(map[string]string{
+		"/usr/lib/systemd/system/app@.service": "[Service]\nExecStart=/usr/bin/app\nExecStartPost=/usr/bin/app-ready\n",
+		// The ExecStart of the vendor is replaced.
+		"/usr/lib/systemd/system/app@.service.d/10-override.conf": "[Service]\nExecStart=\nExecStart=/opt/app/bin/app --vendor\n",
+		// A drop-in of the administrator replaces the vendor one of the same
+		// name, and is applied after those sorted before it.
+		"/etc/systemd/system/app@.service.d/10-override.conf": "[Service]\nExecStart=\nExecStart=/opt/app/bin/app --admin\n",
+		"/etc/systemd/system/app@one.service.d/20-hook.conf":  "[Service]\nExecStartPre=/tmp/hook\n",
+		"/etc/systemd/system/app@one.service.d/README":        "[Service]\nExecStartPre=/tmp/ignored\n",
+	})
+	opts := Options{fs: fsys, QuickScan: true}
+	scope := systemdScope{wantsDirs: systemdSystemUnitDirs, unitDirs: systemdSystemUnitDirs}
+
+	unitPath, unit, ok := findUnit(opts, scope, "app@one.service")
+	if !ok || unitPath != "/usr/lib/systemd/system/app@.service" {
+		t.Fatalf("findUnit = %q, %v", unitPath, ok)
+	}
+
+	records := serviceAutoruns(opts, "systemd_service", unit, "app@one.service")
+	want := [][3]string{
+		{"ExecStartPre", "/tmp/hook", ""},
+		{"ExecStart", "/opt/app/bin/app", "--admin"},
+		{"ExecStartPost", "/usr/bin/app-ready", ""},
+	}
+	if got := serviceCommands(records); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got commands %q, want %q", got, want)
+	}
+
+	locations := []string{
+		"/etc/systemd/system/app@one.service.d/20-hook.conf",
+		"/etc/systemd/system/app@.service.d/10-override.conf",
+		"/usr/lib/systemd/system/app@.service",
+	}
+	for i, record := range records {
+		if record.Location != locations[i] {
+			t.Errorf("%s: Location = %q, want %q", record.RawName, record.Location, locations[i])
+		}
+	}
+}
+
+func TestLinuxGetSystemdScopes(t *testing.T) {
+	fsys := newFakeFileSystem(map[string]string{
+		// A system service and timer.
+		"/etc/systemd/system/multi-user.target.wants/backdoor.service": "",
+		"/etc/systemd/system/backdoor.service":                         "[Service]\nExecStart=/opt/backdoor\n",
+		"/etc/systemd/system/timers.target.wants/clean.timer":          "",
+		"/usr/lib/systemd/system/clean.timer":                          "[Timer]\nOnCalendar=daily\nOnBootSec=5min\n",
+		"/usr/lib/systemd/system/clean.service":                        "[Service]\nExecStart=/usr/bin/clean\n",
+		// A user unit enabled for every user.
+		"/etc/systemd/user/default.target.wants/agent.service": "",
+		"/usr/lib/systemd/user/agent.service":                  "[Service]\nExecStart=/usr/bin/agent\n",
+		// A unit alice enabled, and one of every user she enabled too.
+		"/home/alice/.config/systemd/user/default.target.wants/sync.service": "",
+		"/home/alice/.config/systemd/user/default.target.wants/pipe.service": "",
+		"/home/alice/.local/share/systemd/user/sync.service":                 "[Service]\nExecStart=/home/alice/bin/sync\n",
+		"/usr/lib/systemd/user/pipe.service":                                 "[Service]\nExecStart=/usr/bin/pipe\n",
+		"/home/bob/.profile":                                                 "",
+	})
+	opts := Options{fs: fsys, QuickScan: true, ResolveUsers: true}
+
+	type scoped struct {
+		Type, ImagePath, Location, User, Scope, Trigger string
+	}
+	var got []scoped
+	for _, record := range linuxGetSystemd(opts) {
+		setSource(opts, record)
+		setScope(opts, record)
+		got = append(got, scoped{record.Type, record.ImagePath, record.Location, record.User, record.Scope, record.Trigger})
+	}
+
+	want := []scoped{
+		{"systemd_service", "/opt/backdoor", "/etc/systemd/system/backdoor.service", "", "machine", ""},
+		{"systemd_timer", "/usr/bin/clean", "/usr/lib/systemd/system/clean.timer", "", "machine", "OnBootSec=5min, OnCalendar=daily"},
+		{"systemd_service", "/usr/bin/agent", "/usr/lib/systemd/user/agent.service", "", "machine", ""},
+		{"systemd_service", "/usr/bin/pipe", "/usr/lib/systemd/user/pipe.service", "alice", "user", ""},
+		{"systemd_service", "/home/alice/bin/sync", "/home/alice/.local/share/systemd/user/sync.service", "alice", "user", ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got records\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestSystemdScopesUserScopeOnly(t *testing.T) {
+	for _, scope := range systemdScopes(Options{UserScopeOnly: true}) {
+		if !scope.perUser {
+			t.Errorf("scope of %v is not per user with UserScopeOnly", scope.unitDirs)
+		}
+	}
+}
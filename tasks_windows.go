@@ -0,0 +1,232 @@
+//+build windows
+
+package autoruns
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// taskDefinition is the part of a scheduled task's XML definition we are
+// interested in.
+type taskDefinition struct {
+	Triggers struct {
+		Triggers []struct {
+			XMLName xml.Name
+		} `xml:",any"`
+	} `xml:"Triggers"`
+	Actions struct {
+		Exec []struct {
+			Command   string `xml:"Command"`
+			Arguments string `xml:"Arguments"`
+		} `xml:"Exec"`
+		ComHandler []struct {
+			ClassID string `xml:"ClassId"`
+			Data    string `xml:"Data"`
+		} `xml:"ComHandler"`
+	} `xml:"Actions"`
+}
+
+// taskTriggerNames maps the trigger elements of a task definition to the
+// names we report them with.
+var taskTriggerNames = map[string]string{
+	"BootTrigger":               "boot",
+	"LogonTrigger":              "logon",
+	"IdleTrigger":               "idle",
+	"TimeTrigger":               "time",
+	"CalendarTrigger":           "calendar",
+	"EventTrigger":              "event",
+	"RegistrationTrigger":       "registration",
+	"SessionStateChangeTrigger": "session_state_change",
+	"WnfStateChangeTrigger":     "wnf_state_change",
+}
+
+// parseTaskDefinition decodes a task definition. Definitions are usually
+// stored as UTF-16, which encoding/xml does not support, so they are
+// converted to UTF-8 first.
+func parseTaskDefinition(data []byte) (*taskDefinition, error) {
+	var task taskDefinition
+	decoder := xml.NewDecoder(bytes.NewReader(decodeUTF16(data)))
+	// The content is UTF-8 by now, regardless of what the declaration says.
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	if err := decoder.Decode(&task); err != nil {
+		return nil, err
+	}
+
+	return &task, nil
+}
+
+// taskTrigger summarizes the triggers of a task, e.g. "boot, logon".
+func taskTrigger(task *taskDefinition) string {
+	var triggers []string
+	for _, trigger := range task.Triggers.Triggers {
+		name, ok := taskTriggerNames[trigger.XMLName.Local]
+		if !ok {
+			name = trigger.XMLName.Local
+		}
+		triggers = append(triggers, name)
+	}
+
+	return strings.Join(triggers, ", ")
+}
+
+// validSecurityDescriptor checks whether data holds a self-relative
+// security descriptor, whose owner, group and ACLs are within data.
+func validSecurityDescriptor(data []byte) bool {
+	const selfRelative = 0x8000
+
+	if len(data) < 20 || data[0] != 1 || binary.LittleEndian.Uint16(data[2:4])&selfRelative == 0 {
+		return false
+	}
+	for offset := 4; offset < 20; offset += 4 {
+		if binary.LittleEndian.Uint32(data[offset:offset+4]) >= uint32(len(data)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// taskHidden checks whether a task has been hidden from the Task Scheduler
+// by removing the security descriptor of its entry in the TaskCache, which
+// the scheduler needs to list the task but not to run it. Tasks without an
+// entry are not registered, and are not hidden.
+func taskHidden(opts Options, taskName string) bool {
+	var treeKey string = "Software\\Microsoft\\Windows NT\\CurrentVersion\\Schedule\\TaskCache\\Tree"
+
+	key, err := registryFor(opts).OpenKey(registry.LOCAL_MACHINE, fmt.Sprintf("%s%s", treeKey, taskName))
+	if err != nil {
+		opts.debugf("%s: task %s is not in the TaskCache", opts.category, taskName)
+		return false
+	}
+	defer key.Close()
+
+	sd, _, err := key.GetBinaryValue("SD")
+	return err != nil || !validSecurityDescriptor(sd)
+}
+
+// This function enumerates the actions of scheduled tasks, as found in
+// their definitions under %SystemRoot%\System32\Tasks. Exec actions are
+// reported with the command they run. ComHandler actions start a COM
+// object instead: they are reported with the CLSID as LaunchString, the
+// Data passed to the handler as Arguments and the server the CLSID
+// resolves to as ImagePath. Tasks whose security descriptor was removed
+// from the TaskCache are flagged as Hidden.
+func windowsGetTasks(opts Options) (records []*Autorun) {
+	tasksPath := filepath.Join(os.Getenv("SystemRoot"), "System32", "Tasks")
+
+	// Tasks can be organized in nested folders.
+	type taskFolder struct {
+		path  string
+		depth int
+	}
+	folders := []taskFolder{{tasksPath, 0}}
+	for len(folders) > 0 {
+		if opts.Context().Err() != nil {
+			return
+		}
+
+		folder := folders[0]
+		folders = folders[1:]
+		if opts.tooDeep(folder.path, folder.depth) {
+			continue
+		}
+
+		// Get list of files in folder.
+		filesList, err := fileSystemFor(opts).ReadDir(folder.path)
+		if err != nil {
+			opts.Warn(folder.path, err)
+			continue
+		}
+
+		for _, fileEntry := range filesList {
+			if opts.entriesExhausted(folder.path, records) {
+				return
+			}
+
+			filePath := filepath.Join(folder.path, fileEntry.Name())
+			if fileEntry.IsDir() {
+				folders = append(folders, taskFolder{filePath, folder.depth + 1})
+				continue
+			}
+
+			// Read the task definition.
+			data, err := readFile(fileSystemFor(opts), filePath)
+			if err != nil {
+				opts.Warn(filePath, err)
+				continue
+			}
+
+			task, err := parseTaskDefinition(data)
+			if err != nil {
+				opts.Warn(filePath, err)
+				continue
+			}
+
+			// The task name is its path relative to the Tasks folder.
+			taskName := strings.TrimPrefix(filePath, tasksPath)
+			trigger := taskTrigger(task)
+			hidden := taskHidden(opts, taskName)
+
+			for _, action := range task.Actions.Exec {
+				command := strings.TrimSpace(action.Command)
+				if command == "" {
+					continue
+				}
+				// The command is never quoted, but can contain spaces.
+				if !strings.HasPrefix(command, "\"") {
+					command = "\"" + command + "\""
+				}
+				if action.Arguments != "" {
+					command += " " + action.Arguments
+				}
+
+				// We pass the command to a function to return an Autorun.
+				newAutorun := stringToAutorun(opts, "scheduled_task", filePath, command, true, taskName)
+				newAutorun.RawName = fileEntry.Name()
+				newAutorun.Trigger = trigger
+				newAutorun.Hidden = hidden
+
+				// Add the new autorun to the records.
+				records = append(records, newAutorun)
+			}
+
+			for _, action := range task.Actions.ComHandler {
+				clsid := strings.TrimSpace(action.ClassID)
+				if clsid == "" {
+					continue
+				}
+
+				// Resolve the CLSID to the server which gets loaded.
+				newAutorun := &Autorun{
+					Type:     "scheduled_task",
+					Location: filePath,
+					Entry:    taskName,
+				}
+				if server, _, err := clsidServer(opts, clsid); err == nil {
+					newAutorun = stringToAutorun(opts, "scheduled_task", filePath, systemDLLPath(server), false, taskName)
+				}
+				newAutorun.RawName = fileEntry.Name()
+				newAutorun.LaunchString = clsid
+				newAutorun.Arguments = strings.TrimSpace(action.Data)
+				newAutorun.Trigger = trigger
+				newAutorun.Hidden = hidden
+
+				// Add the new autorun to the records.
+				records = append(records, newAutorun)
+			}
+		}
+	}
+
+	return
+}
@@ -0,0 +1,133 @@
+//+build linux
+
+package autoruns
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The directories of the configuration of systemd-tmpfiles, by precedence:
+// a file overrides those of the same name in the directories after it.
+var tmpfilesDirs = []string{
+	"/etc/tmpfiles.d",
+	"/run/tmpfiles.d",
+	"/usr/lib/tmpfiles.d",
+}
+
+// The paths which, when written to, linked or copied at boot, make
+// something run: the kernel runs the program core_pattern pipes to when a
+// process crashes, and the rest are loaded or run by the system or by the
+// shells of users.
+var tmpfilesSensitivePaths = []string{
+	"/proc/sys/kernel/core_pattern",
+	"/proc/sys/kernel/modprobe",
+	"/etc/ld.so.preload",
+	"/etc/cron",
+	"/var/spool/cron",
+	"/etc/profile",
+	"/etc/bash.bashrc",
+	"/etc/environment",
+	"/etc/rc.local",
+	"/etc/update-motd.d",
+	"/etc/pam.d",
+	"/etc/systemd/system",
+	"/etc/sudoers",
+	"/root/",
+}
+
+// The types of tmpfiles.d lines which write, link or copy something to
+// their path.
+var tmpfilesWritingTypes = map[byte]bool{
+	'f': true,
+	'F': true,
+	'w': true,
+	'L': true,
+	'C': true,
+}
+
+// tmpfilesSensitivePath checks whether a path is one which makes something
+// run when written to.
+func tmpfilesSensitivePath(path string) bool {
+	path = filepath.Clean(path)
+	for _, sensitive := range tmpfilesSensitivePaths {
+		if path == strings.TrimSuffix(sensitive, "/") || strings.HasPrefix(path, sensitive) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// This function enumerates the lines of the tmpfiles.d configuration which
+// are applied at boot to write, link or copy to a path making something
+// run, such as a program for core_pattern to pipe crashes to or a library
+// for ld.so.preload. Lines are "type path mode user group age argument",
+// and the argument is reported as the image if it is a path or a pipe.
+func linuxGetTmpfiles(opts Options) (records []*Autorun) {
+	seen := make(map[string]bool)
+	for _, dir := range tmpfilesDirs {
+		files, err := fileSystemFor(opts).ReadDir(dir)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				opts.Warn(dir, err)
+			}
+			continue
+		}
+
+		for _, file := range files {
+			if file.IsDir() || filepath.Ext(file.Name()) != ".conf" || seen[file.Name()] {
+				continue
+			}
+			seen[file.Name()] = true
+
+			configPath := filepath.Join(dir, file.Name())
+			data, err := readFile(fileSystemFor(opts), configPath)
+			if err != nil {
+				opts.Warn(configPath, err)
+				continue
+			}
+
+			scanner := bufio.NewScanner(bytes.NewReader(data))
+			for scanner.Scan() {
+				line := strings.TrimSpace(scanner.Text())
+				if line == "" || strings.HasPrefix(line, "#") {
+					continue
+				}
+
+				fields := strings.Fields(line)
+				// The type can be followed by modifiers such as + or !.
+				if len(fields) < 2 || !tmpfilesWritingTypes[fields[0][0]] {
+					continue
+				}
+				path := fields[1]
+				argument := cutFields(line, 6)
+				pipe := strings.HasPrefix(argument, "|")
+				if !pipe && !tmpfilesSensitivePath(path) {
+					continue
+				}
+
+				var newAutorun *Autorun
+				switch {
+				case pipe:
+					newAutorun = shellCommandToAutorun(opts, "tmpfiles", configPath, strings.TrimSpace(argument[1:]), path)
+				case filepath.IsAbs(argument):
+					newAutorun = shellCommandToAutorun(opts, "tmpfiles", configPath, argument, path)
+				default:
+					newAutorun = &Autorun{Type: "tmpfiles", Location: configPath, Entry: path}
+				}
+				newAutorun.RawName = file.Name()
+				newAutorun.LaunchString = line
+				newAutorun.Trigger = "boot"
+				newAutorun.Suspicious = true
+
+				records = append(records, newAutorun)
+			}
+		}
+	}
+
+	return
+}
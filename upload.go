@@ -0,0 +1,131 @@
+package autoruns
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// UploadOptions controls how UploadJSON delivers records to a collector.
+type UploadOptions struct {
+	// Client sends the requests. It defaults to http.DefaultClient, which
+	// has no timeout: use the context of the upload to bound it.
+	Client *http.Client
+
+	// Header is added to every request, e.g. to authenticate with the
+	// collector through an Authorization header.
+	Header http.Header
+
+	// RetryAttempts is how many times the upload is retried after the
+	// collector could not be reached or answered with a 5xx status. It
+	// defaults to 3; a negative value disables retries.
+	RetryAttempts int
+
+	// RetryBackoff is the delay before the first retry, which doubles with
+	// every further attempt. It defaults to 1s.
+	RetryBackoff time.Duration
+}
+
+// retryPolicy returns the retry settings with their defaults applied.
+func (o UploadOptions) retryPolicy() (retries int, backoff time.Duration) {
+	retries, backoff = o.RetryAttempts, o.RetryBackoff
+	if retries == 0 {
+		retries = 3
+	}
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+
+	return retries, backoff
+}
+
+// UploadJSON posts records to url as newline-delimited JSON, see
+// WriteNDJSON, with the Content-Type application/x-ndjson. The records are
+// encoded while they are sent rather than buffered, and encoded again for
+// every retry. Any 2xx status is a success. UploadJSON gives up as soon as
+// ctx is done.
+func UploadJSON(ctx context.Context, url string, records []*Autorun, opts UploadOptions) error {
+	client := opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	retries, backoff := opts.retryPolicy()
+
+	err := uploadOnce(ctx, client, url, records, opts.Header)
+	for attempt := 0; attempt < retries && err != nil && isRetryableUpload(err); attempt++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		err = uploadOnce(ctx, client, url, records, opts.Header)
+	}
+
+	return err
+}
+
+// uploadStatusError is returned for uploads the collector answered with an
+// unsuccessful status.
+type uploadStatusError struct {
+	status     string
+	statusCode int
+}
+
+func (e *uploadStatusError) Error() string {
+	return "autoruns: upload failed with status " + e.status
+}
+
+// isRetryableUpload checks whether an upload failed because of the
+// collector rather than of the request. Failures to reach the collector
+// are retried unless the upload was canceled.
+func isRetryableUpload(err error) bool {
+	if statusErr, ok := err.(*uploadStatusError); ok {
+		return statusErr.statusCode >= 500
+	}
+
+	return err != context.Canceled && err != context.DeadlineExceeded
+}
+
+// uploadOnce performs a single upload of records.
+func uploadOnce(ctx context.Context, client *http.Client, url string, records []*Autorun, header http.Header) error {
+	// The records are encoded as the request body is read.
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(WriteNDJSON(writer, records))
+	}()
+	defer reader.Close()
+
+	request, err := http.NewRequest(http.MethodPost, url, reader)
+	if err != nil {
+		return err
+	}
+	request = request.WithContext(ctx)
+	for name, values := range header {
+		for _, value := range values {
+			request.Header.Add(name, value)
+		}
+	}
+	request.Header.Set("Content-Type", "application/x-ndjson")
+
+	response, err := client.Do(request)
+	if err != nil {
+		// The error of the context is more telling than that of the
+		// transport.
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("autoruns: uploading to %s: %w", url, err)
+	}
+	defer response.Body.Close()
+	io.Copy(ioutil.Discard, response.Body)
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return &uploadStatusError{status: response.Status, statusCode: response.StatusCode}
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package autoruns
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var uploadRecords = []*Autorun{
+	{Type: "run_key", Entry: "Agent", ImagePath: `C:\Program Files\Agent\agent.exe`},
+	{Type: "services", Entry: "Updater", ImagePath: `C:\Program Files\Agent\updater.exe`},
+}
+
+func TestUploadJSON(t *testing.T) {
+	var entries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/x-ndjson" {
+			t.Errorf("got %s with Content-Type %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer token" {
+			t.Errorf("Authorization = %q", got)
+		}
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var record Autorun
+			if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+				t.Errorf("line %q: %v", scanner.Text(), err)
+			}
+			entries = append(entries, record.Entry)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	opts := UploadOptions{Header: http.Header{"Authorization": {"Bearer token"}}}
+	if err := UploadJSON(context.Background(), server.URL, uploadRecords, opts); err != nil {
+		t.Fatalf("UploadJSON: %v", err)
+	}
+	if len(entries) != 2 || entries[0] != "Agent" || entries[1] != "Updater" {
+		t.Errorf("collector received %v", entries)
+	}
+}
+
+func TestUploadJSONRetries(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	opts := UploadOptions{RetryBackoff: time.Millisecond}
+	if err := UploadJSON(context.Background(), server.URL, uploadRecords, opts); err != nil {
+		t.Fatalf("UploadJSON: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("got %d requests, want 2", requests)
+	}
+}
+
+func TestUploadJSONDoesNotRetryClientErrors(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	opts := UploadOptions{RetryBackoff: time.Millisecond}
+	if err := UploadJSON(context.Background(), server.URL, uploadRecords, opts); err == nil {
+		t.Fatal("UploadJSON succeeded")
+	}
+	if requests != 1 {
+		t.Errorf("got %d requests, want 1", requests)
+	}
+}
+
+func TestUploadJSONCanceled(t *testing.T) {
+	received := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The server only notices the client going away once the body is
+		// read.
+		io.Copy(ioutil.Discard, r.Body)
+		close(received)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-received
+		cancel()
+	}()
+	if err := UploadJSON(ctx, server.URL, uploadRecords, UploadOptions{}); err != context.Canceled {
+		t.Errorf("UploadJSON during the request = %v, want context.Canceled", err)
+	}
+
+	// An upload waiting to be retried gives up as well.
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := UploadJSON(ctx, failing.URL, uploadRecords, UploadOptions{RetryBackoff: time.Hour})
+	if err != context.DeadlineExceeded {
+		t.Errorf("UploadJSON during the backoff = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("UploadJSON took %v to give up", elapsed)
+	}
+}
@@ -0,0 +1,14 @@
+//+build !windows
+
+package autoruns
+
+import (
+	"os"
+)
+
+// IsElevated reports whether the process runs as root, which some
+// machine-wide locations require. Scans without it should set
+// Options.UserScopeOnly.
+func IsElevated() bool {
+	return os.Geteuid() == 0
+}
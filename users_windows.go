@@ -0,0 +1,332 @@
+//+build windows
+
+package autoruns
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+var (
+	modadvapi32 = windows.NewLazySystemDLL("advapi32.dll")
+
+	procRegLoadKeyW   = modadvapi32.NewProc("RegLoadKeyW")
+	procRegUnLoadKeyW = modadvapi32.NewProc("RegUnLoadKeyW")
+)
+
+var (
+	currentUserOnce sync.Once
+	currentUser     string
+	currentUserSID  string
+)
+
+// sidUserName resolves a SID to a DOMAIN\user account name. Orphaned or
+// otherwise unresolvable SIDs are returned in their string form.
+func sidUserName(sid *windows.SID) string {
+	account, domain, _, err := sid.LookupAccount("")
+	if err != nil {
+		return sid.String()
+	}
+	if domain != "" {
+		return domain + "\\" + account
+	}
+
+	return account
+}
+
+// currentUserName returns the account the scan is running as, along with
+// its SID.
+func currentUserName() (string, string) {
+	currentUserOnce.Do(func() {
+		tokenUser, err := windows.GetCurrentProcessToken().GetTokenUser()
+		if err != nil {
+			return
+		}
+		currentUser = sidUserName(tokenUser.User.Sid)
+		currentUserSID = tokenUser.User.Sid.String()
+	})
+
+	return currentUser, currentUserSID
+}
+
+// IsElevated reports whether the process runs with administrative rights,
+// which most machine-wide locations require. Scans without them should set
+// Options.UserScopeOnly.
+func IsElevated() bool {
+	return windows.GetCurrentProcessToken().IsElevated()
+}
+
+// keyUser returns the user owning entries read from the given registry
+// root, if resolving users was requested. Machine-wide entries have no
+// user.
+func keyUser(opts Options, reg registry.Key) string {
+	if !opts.ResolveUsers || reg != registry.CURRENT_USER {
+		return ""
+	}
+
+	user, _ := currentUserName()
+	return user
+}
+
+// userHive is the registry hive of a user profile other than the current
+// user's, mounted under USERS.
+type userHive struct {
+	user    string
+	profile string // The profile directory.
+	path    string // The path of the hive under USERS.
+}
+
+// registryRoot is a hive scanned for per-user keys. Key paths inside it
+// are prefixed with prefix.
+type registryRoot struct {
+	reg    registry.Key
+	prefix string
+	user   string
+}
+
+// userRegistryRoots returns the current user's hive followed by the hives
+// of all other users, if they are to be scanned.
+func userRegistryRoots(opts Options) []registryRoot {
+	roots := []registryRoot{
+		{reg: registry.CURRENT_USER, user: keyUser(opts, registry.CURRENT_USER)},
+	}
+
+	for _, hive := range userHives(opts) {
+		root := registryRoot{reg: registry.USERS, prefix: hive.path + "\\"}
+		if opts.ResolveUsers {
+			root.user = hive.user
+		}
+		roots = append(roots, root)
+	}
+
+	return roots
+}
+
+// enableHivePrivileges enables the privileges needed to load hives.
+func enableHivePrivileges() error {
+	var token windows.Token
+	err := windows.OpenProcessToken(windows.CurrentProcess(), windows.TOKEN_ADJUST_PRIVILEGES|windows.TOKEN_QUERY, &token)
+	if err != nil {
+		return err
+	}
+	defer token.Close()
+
+	for _, name := range []string{"SeBackupPrivilege", "SeRestorePrivilege"} {
+		namePtr, err := windows.UTF16PtrFromString(name)
+		if err != nil {
+			return err
+		}
+
+		var luid windows.LUID
+		if err := windows.LookupPrivilegeValue(nil, namePtr, &luid); err != nil {
+			return err
+		}
+
+		privileges := windows.Tokenprivileges{PrivilegeCount: 1}
+		privileges.Privileges[0] = windows.LUIDAndAttributes{Luid: luid, Attributes: windows.SE_PRIVILEGE_ENABLED}
+		if err := windows.AdjustTokenPrivileges(token, false, &privileges, 0, nil, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadHive mounts the hive stored in file under USERS\name.
+func loadHive(name string, file string) error {
+	namePtr, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return err
+	}
+	filePtr, err := windows.UTF16PtrFromString(file)
+	if err != nil {
+		return err
+	}
+
+	r, _, _ := procRegLoadKeyW.Call(uintptr(registry.USERS), uintptr(unsafe.Pointer(namePtr)), uintptr(unsafe.Pointer(filePtr)))
+	if r != 0 {
+		return syscall.Errno(r)
+	}
+
+	return nil
+}
+
+// unloadHive unmounts a hive mounted with loadHive.
+func unloadHive(name string) error {
+	namePtr, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return err
+	}
+
+	r, _, _ := procRegUnLoadKeyW.Call(uintptr(registry.USERS), uintptr(unsafe.Pointer(namePtr)))
+	if r != 0 {
+		return syscall.Errno(r)
+	}
+
+	return nil
+}
+
+// userHives returns the hives of the other users to scan, if requested.
+// They are looked up once per scan.
+func userHives(opts Options) []userHive {
+	if !opts.ScanUserHives || opts.UserScopeOnly {
+		return nil
+	}
+
+	return opts.memo("user_hives", func() interface{} {
+		return loadUserHives(opts)
+	}).([]userHive)
+}
+
+// loadUserHives returns the hives of all profiles registered in
+// ProfileList, including built-in accounts such as Administrator and
+// DefaultAccount, and of the Default profile new users are created from.
+// Hives which are not loaded already, because nobody is logged into those
+// profiles, are loaded from their NTUSER.DAT until the scan completes. The
+// current user is excluded as it is covered through CURRENT_USER. The
+// other hives loaded under USERS are scanned as well.
+func loadUserHives(opts Options) (hives []userHive) {
+	var profileListKey string = "Software\\Microsoft\\Windows NT\\CurrentVersion\\ProfileList"
+
+	// Open the registry key.
+	key, err := openKey(opts, registry.LOCAL_MACHINE, profileListKey)
+	if err != nil {
+		return
+	}
+
+	sids, err := key.ReadSubKeyNames(0)
+	defaultProfile, _, defaultErr := key.GetStringValue("Default")
+	key.Close()
+	if err != nil {
+		return
+	}
+
+	type profile struct {
+		sid  string
+		user string
+		path string
+	}
+	var profiles []profile
+
+	_, currentSID := currentUserName()
+	for _, sid := range sids {
+		if strings.EqualFold(sid, currentSID) {
+			continue
+		}
+
+		subkey, err := openKey(opts, registry.LOCAL_MACHINE, fmt.Sprintf("%s\\%s", profileListKey, sid))
+		if err != nil {
+			continue
+		}
+		profilePath, _, err := subkey.GetStringValue("ProfileImagePath")
+		subkey.Close()
+		if err != nil || profilePath == "" {
+			continue
+		}
+		if expanded, err := registry.ExpandString(profilePath); err == nil {
+			profilePath = expanded
+		}
+
+		user := sid
+		if sidObj, err := windows.StringToSid(sid); err == nil {
+			user = sidUserName(sidObj)
+		}
+
+		profiles = append(profiles, profile{sid: sid, user: user, path: profilePath})
+	}
+
+	// The Default profile has no SID and is never logged into.
+	if defaultErr == nil && defaultProfile != "" {
+		if expanded, err := registry.ExpandString(defaultProfile); err == nil {
+			defaultProfile = expanded
+		}
+		profiles = append(profiles, profile{user: "Default", path: defaultProfile})
+	}
+
+	privilegesErr := enableHivePrivileges()
+
+	for _, p := range profiles {
+		// The hives of logged in users are already loaded.
+		if p.sid != "" {
+			if key, err := registryFor(opts).OpenKey(registry.USERS, p.sid); err == nil {
+				key.Close()
+				hives = append(hives, userHive{user: p.user, profile: p.path, path: p.sid})
+				continue
+			}
+		}
+
+		hiveFile := filepath.Join(p.path, "NTUSER.DAT")
+		if privilegesErr != nil {
+			opts.Warn(hiveFile, privilegesErr)
+			continue
+		}
+
+		mountName := "autoruns_" + p.user
+		if p.sid != "" {
+			mountName = "autoruns_" + p.sid
+		}
+		if err := loadHive(mountName, hiveFile); err != nil {
+			opts.Warn(hiveFile, err)
+			continue
+		}
+		opts.onDone(func() {
+			unloadHive(mountName)
+		})
+
+		hives = append(hives, userHive{user: p.user, profile: p.path, path: mountName})
+	}
+
+	return append(hives, loadedUserHives(opts, hives)...)
+}
+
+// loadedUserHives returns the hives loaded under USERS which are not among
+// the known hives, such as those of accounts without a profile in
+// ProfileList. The Classes hives loaded along with the hives of users, and
+// .DEFAULT, which is the hive of LocalSystem, are skipped. The profile
+// directory of a hive is only known if its user is logged on
+// interactively.
+func loadedUserHives(opts Options, known []userHive) (hives []userHive) {
+	key, err := openKey(opts, registry.USERS, "")
+	if err != nil {
+		return
+	}
+	names, err := key.ReadSubKeyNames(0)
+	key.Close()
+	if err != nil {
+		return
+	}
+
+	_, currentSID := currentUserName()
+	seen := map[string]bool{strings.ToLower(currentSID): true}
+	for _, hive := range known {
+		seen[strings.ToLower(hive.path)] = true
+	}
+
+	for _, name := range names {
+		if seen[strings.ToLower(name)] || strings.EqualFold(name, ".DEFAULT") || strings.HasSuffix(strings.ToLower(name), "_classes") {
+			continue
+		}
+
+		user := name
+		if sid, err := windows.StringToSid(name); err == nil {
+			user = sidUserName(sid)
+		}
+
+		var profile string
+		if environment, err := registryFor(opts).OpenKey(registry.USERS, name+"\\Volatile Environment"); err == nil {
+			profile, _, _ = environment.GetStringValue("USERPROFILE")
+			environment.Close()
+		}
+
+		hives = append(hives, userHive{user: user, profile: profile, path: name})
+	}
+
+	return
+}
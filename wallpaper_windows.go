@@ -0,0 +1,95 @@
+//+build windows
+
+package autoruns
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// The file extensions of the pictures Windows shows as wallpaper or as the
+// background of the logon screen.
+var pictureExtensions = map[string]bool{
+	".bmp":  true,
+	".dib":  true,
+	".gif":  true,
+	".heic": true,
+	".jfif": true,
+	".jpe":  true,
+	".jpeg": true,
+	".jpg":  true,
+	".png":  true,
+	".tif":  true,
+	".tiff": true,
+	".wdp":  true,
+	".webp": true,
+}
+
+// launchesProgram checks whether a value meant to name a picture names a
+// file which is not one, such as a DLL or program. Empty values, pictures
+// and values which are no file name at all are what Windows expects.
+func launchesProgram(value string) bool {
+	extension := strings.ToLower(filepath.Ext(strings.Trim(strings.TrimSpace(value), "\"")))
+
+	return extension != "" && !pictureExtensions[extension]
+}
+
+// This function enumerates the wallpapers of users and the customizations
+// of the logon screen background which do not name a picture, but a DLL or
+// program. The wallpaper is the Wallpaper value of Control Panel\Desktop,
+// and the background is customized by the values of LogonUI\Background.
+func windowsGetLogonUIBackground(opts Options) (records []*Autorun) {
+	var backgroundKey string = "Software\\Microsoft\\Windows\\CurrentVersion\\Authentication\\LogonUI\\Background"
+	var desktopKey string = "Control Panel\\Desktop"
+
+	type pictureKey struct {
+		root      registryRoot
+		keyName   string
+		valueName string
+	}
+	keys := []pictureKey{
+		{registryRoot{reg: registry.LOCAL_MACHINE}, backgroundKey, ""},
+	}
+	for _, root := range userRegistryRoots(opts) {
+		keys = append(keys, pictureKey{root, root.prefix + desktopKey, "Wallpaper"})
+	}
+
+	for _, picture := range keys {
+		// Open registry key.
+		key, err := openKey(opts, picture.root.reg, picture.keyName)
+		if err != nil {
+			continue
+		}
+
+		// Every value of the background key is looked at, and only the
+		// wallpaper of the desktop key.
+		names := []string{picture.valueName}
+		if picture.valueName == "" {
+			names, _ = key.ReadValueNames(0)
+		}
+
+		imageLocation := fmt.Sprintf("%s\\%s", registryToString(picture.root.reg), picture.keyName)
+		for _, name := range names {
+			value, _, err := key.GetStringValue(name)
+			if err != nil || !launchesProgram(value) {
+				continue
+			}
+
+			// We pass the value string to a function to return an Autorun.
+			newAutorun := stringToAutorun(opts, "logonui_background", imageLocation, value, true, name)
+			newAutorun.RawName = name
+			newAutorun.User = picture.root.user
+			newAutorun.NonDefault = true
+			newAutorun.Suspicious = true
+
+			// Add the new autorun to the records.
+			records = append(records, newAutorun)
+		}
+		key.Close()
+	}
+
+	return
+}
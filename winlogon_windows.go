@@ -0,0 +1,241 @@
+//+build windows
+
+package autoruns
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// The events a Winlogon notification package can subscribe to. Each is a
+// value naming the exported function to call.
+var winlogonNotifyEvents = []string{
+	"Startup",
+	"Shutdown",
+	"Logon",
+	"Logoff",
+	"StartShell",
+	"PostShell",
+	"Lock",
+	"Unlock",
+	"StartScreenSaver",
+	"StopScreenSaver",
+	"Disconnect",
+	"Reconnect",
+}
+
+// This function enumerates Winlogon notification packages. These are only
+// honored by older versions of Windows and the key is normally absent.
+func windowsGetWinlogonNotify(opts Options) (records []*Autorun) {
+	var reg registry.Key = registry.LOCAL_MACHINE
+	var notifyKey string = "Software\\Microsoft\\Windows NT\\CurrentVersion\\Winlogon\\Notify"
+
+	// Open the registry key, which modern systems do not have.
+	key, err := openOptionalKey(opts, reg, notifyKey)
+	if err != nil {
+		return
+	}
+
+	// Enumerate subkeys.
+	names, err := key.ReadSubKeyNames(0)
+	key.Close()
+	if err != nil {
+		return
+	}
+
+	for _, name := range names {
+		subkeyPath := fmt.Sprintf("%s\\%s", notifyKey, name)
+		subkey, err := openKey(opts, reg, subkeyPath)
+		if err != nil {
+			continue
+		}
+
+		// Check if there is a DllName value.
+		dllName, _, err := subkey.GetStringValue("DllName")
+		if err != nil || dllName == "" {
+			subkey.Close()
+			continue
+		}
+
+		// Collect the events the package hooks.
+		var events []string
+		for _, event := range winlogonNotifyEvents {
+			if function, _, err := subkey.GetStringValue(event); err == nil && function != "" {
+				events = append(events, event)
+			}
+		}
+		subkey.Close()
+
+		imageLocation := fmt.Sprintf("%s\\%s", registryToString(reg), subkeyPath)
+
+		// We pass the resolved DLL path to a function to return an Autorun.
+		newAutorun := stringToAutorun(opts, "winlogon_notify", imageLocation, systemDLLPath(dllName), false, name)
+		newAutorun.RawName = "DllName"
+		newAutorun.LaunchString = dllName
+		newAutorun.Trigger = strings.Join(events, ", ")
+
+		// Add the new autorun to the records.
+		records = append(records, newAutorun)
+	}
+
+	return
+}
+
+// This function reads the Winlogon System value, a comma-separated list of
+// executables started at logon, which is empty by default.
+func windowsGetWinlogonSystem(opts Options) (records []*Autorun) {
+	var reg registry.Key = registry.LOCAL_MACHINE
+	var winlogonKey string = "Software\\Microsoft\\Windows NT\\CurrentVersion\\Winlogon"
+
+	// Open the registry key.
+	key, err := openKey(opts, reg, winlogonKey)
+	if err != nil {
+		return
+	}
+
+	value, _, err := key.GetStringValue("System")
+	key.Close()
+	if err != nil {
+		return
+	}
+
+	imageLocation := fmt.Sprintf("%s\\%s", registryToString(reg), winlogonKey)
+
+	for _, command := range strings.Split(value, ",") {
+		command = strings.TrimSpace(command)
+		if command == "" {
+			continue
+		}
+
+		// We pass the value string to a function to return an Autorun.
+		newAutorun := stringToAutorun(opts, "winlogon_system", imageLocation, command, true, "System")
+		newAutorun.RawName = "System"
+
+		// Add the new autorun to the records.
+		records = append(records, newAutorun)
+	}
+
+	return
+}
+
+// This function enumerates group policy client-side extensions, whose DLLs
+// Winlogon loads while processing policy.
+func windowsGetGPExtensions(opts Options) (records []*Autorun) {
+	var reg registry.Key = registry.LOCAL_MACHINE
+	var extensionsKey string = "Software\\Microsoft\\Windows NT\\CurrentVersion\\Winlogon\\GPExtensions"
+
+	// Open the registry key.
+	key, err := openKey(opts, reg, extensionsKey)
+	if err != nil {
+		return
+	}
+
+	// Enumerate subkeys, which are named after the extension's CLSID.
+	names, err := key.ReadSubKeyNames(0)
+	key.Close()
+	if err != nil {
+		return
+	}
+
+	for _, name := range names {
+		subkeyPath := fmt.Sprintf("%s\\%s", extensionsKey, name)
+		subkey, err := openKey(opts, reg, subkeyPath)
+		if err != nil {
+			continue
+		}
+
+		// Check if there is a DllName value.
+		dllName, _, err := subkey.GetStringValue("DllName")
+		subkey.Close()
+		if err != nil || dllName == "" {
+			continue
+		}
+
+		imageLocation := fmt.Sprintf("%s\\%s", registryToString(reg), subkeyPath)
+
+		// We pass the resolved DLL path to a function to return an Autorun.
+		newAutorun := stringToAutorun(opts, "gp_extension", imageLocation, systemDLLPath(dllName), false, name)
+		newAutorun.RawName = "DllName"
+		newAutorun.LaunchString = dllName
+
+		// Add the new autorun to the records.
+		records = append(records, newAutorun)
+	}
+
+	return
+}
+
+// This function reads the Winlogon VmApplet value, the command run while the
+// system is configured for the first time, which by default opens the
+// performance options to set up the page file.
+func windowsGetWinlogonVmApplet(opts Options) (records []*Autorun) {
+	var reg registry.Key = registry.LOCAL_MACHINE
+	var winlogonKey string = "Software\\Microsoft\\Windows NT\\CurrentVersion\\Winlogon"
+
+	// Open the registry key.
+	key, err := openOptionalKey(opts, reg, winlogonKey)
+	if err != nil {
+		return
+	}
+
+	value, _, err := key.GetStringValue("VmApplet")
+	key.Close()
+	if err != nil || strings.TrimSpace(value) == "" {
+		return
+	}
+
+	imageLocation := fmt.Sprintf("%s\\%s", registryToString(reg), winlogonKey)
+
+	// We pass the value string to a function to return an Autorun.
+	newAutorun := stringToAutorun(opts, "winlogon_vmapplet", imageLocation, value, true, "VmApplet")
+	newAutorun.RawName = "VmApplet"
+	newAutorun.NonDefault = commandImageName(value) != "systempropertiesperformance.exe"
+
+	// Add the new autorun to the records.
+	records = append(records, newAutorun)
+
+	return
+}
+
+// This function reports automatic logon configured with the password stored
+// in plain text in the DefaultPassword value of Winlogon. The record names
+// the account logged on, without the password.
+func windowsGetAutoLogon(opts Options) (records []*Autorun) {
+	var reg registry.Key = registry.LOCAL_MACHINE
+	var winlogonKey string = "Software\\Microsoft\\Windows NT\\CurrentVersion\\Winlogon"
+
+	// Open the registry key.
+	key, err := openOptionalKey(opts, reg, winlogonKey)
+	if err != nil {
+		return
+	}
+	defer key.Close()
+
+	autoAdminLogon, _, err := key.GetStringValue("AutoAdminLogon")
+	if err != nil || strings.TrimSpace(autoAdminLogon) != "1" {
+		return
+	}
+	password, _, err := key.GetStringValue("DefaultPassword")
+	if err != nil || password == "" {
+		return
+	}
+
+	account, _, _ := key.GetStringValue("DefaultUserName")
+	if domain, _, err := key.GetStringValue("DefaultDomainName"); err == nil && domain != "" {
+		account = domain + "\\" + account
+	}
+
+	records = append(records, &Autorun{
+		Type:         "autologon",
+		Location:     fmt.Sprintf("%s\\%s", registryToString(reg), winlogonKey),
+		Entry:        account,
+		RawName:      "DefaultPassword",
+		LaunchString: "AutoAdminLogon=1",
+		Trigger:      "boot",
+		Suspicious:   true,
+	})
+
+	return
+}
@@ -0,0 +1,71 @@
+//+build windows
+
+package autoruns
+
+import "testing"
+
+func TestWindowsGetWinlogonNotify(t *testing.T) {
+	opts := Options{state: newScanState(), fs: newFakeFileSystem(nil)}
+	opts.registry = fakeRegistry{
+		`LOCAL_MACHINE\Software\Microsoft\Windows NT\CurrentVersion\Winlogon`: {"Shell": "explorer.exe"},
+	}
+
+	// Modern systems do not have the Notify key, which is not an error.
+	if records := windowsGetWinlogonNotify(opts); len(records) != 0 {
+		t.Errorf("got %d records without a Notify key", len(records))
+	}
+	if warnings := opts.state.warnings.list(); len(warnings) != 0 {
+		t.Errorf("got warnings without a Notify key: %v", warnings)
+	}
+
+	opts.registry = fakeRegistry{
+		`LOCAL_MACHINE\Software\Microsoft\Windows NT\CurrentVersion\Winlogon\Notify\crypt32chain`: {
+			"DllName": "crypt32.dll",
+			"Logoff":  "ChainWlxLogoffEvent",
+		},
+		`LOCAL_MACHINE\Software\Microsoft\Windows NT\CurrentVersion\Winlogon\Notify\empty`: {},
+	}
+	records := windowsGetWinlogonNotify(opts)
+	if len(records) != 1 {
+		t.Fatalf("got %d records, want 1: %v", len(records), records)
+	}
+	if records[0].Entry != "crypt32chain" || records[0].LaunchString != "crypt32.dll" || records[0].Trigger != "Logoff" {
+		t.Errorf("got Entry %q, LaunchString %q, Trigger %q", records[0].Entry, records[0].LaunchString, records[0].Trigger)
+	}
+}
+
+func TestWindowsGetWinlogonValuesAbsent(t *testing.T) {
+	winlogonKey := `LOCAL_MACHINE\Software\Microsoft\Windows NT\CurrentVersion\Winlogon`
+	scanners := map[string]func(opts Options) []*Autorun{
+		"winlogon_vmapplet": windowsGetWinlogonVmApplet,
+		"autologon":         windowsGetAutoLogon,
+	}
+
+	// Neither the values nor the key being absent is an error.
+	for _, reg := range []fakeRegistry{{}, {winlogonKey: {"Shell": "explorer.exe"}}} {
+		for name, fn := range scanners {
+			opts := Options{state: newScanState(), fs: newFakeFileSystem(nil)}
+			opts.registry = reg
+			if records := fn(opts); len(records) != 0 {
+				t.Errorf("%s: got %d records from %v", name, len(records), reg)
+			}
+			if warnings := opts.state.warnings.list(); len(warnings) != 0 {
+				t.Errorf("%s: got warnings from %v: %v", name, reg, warnings)
+			}
+		}
+	}
+
+	opts := Options{state: newScanState(), fs: newFakeFileSystem(nil)}
+	opts.registry = fakeRegistry{winlogonKey: {
+		"VmApplet":        "SystemPropertiesPerformance.exe /pagefile",
+		"AutoAdminLogon":  "1",
+		"DefaultUserName": "kiosk",
+		"DefaultPassword": "secret",
+	}}
+	if records := windowsGetWinlogonVmApplet(opts); len(records) != 1 || records[0].NonDefault {
+		t.Errorf("VmApplet: got %v, want the default record", records)
+	}
+	if records := windowsGetAutoLogon(opts); len(records) != 1 || records[0].Entry != "kiosk" {
+		t.Errorf("AutoLogon: got %v, want the record of kiosk", records)
+	}
+}
@@ -0,0 +1,8 @@
+//+build !windows
+
+package autoruns
+
+// File system redirection only applies to 32-bit processes on Windows.
+func disableFsRedirection() func() {
+	return func() {}
+}
@@ -0,0 +1,69 @@
+//+build windows
+
+package autoruns
+
+import (
+	"runtime"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modkernel32 = windows.NewLazySystemDLL("kernel32.dll")
+
+	procWow64DisableWow64FsRedirection = modkernel32.NewProc("Wow64DisableWow64FsRedirection")
+	procWow64RevertWow64FsRedirection  = modkernel32.NewProc("Wow64RevertWow64FsRedirection")
+)
+
+var (
+	wow64Once sync.Once
+	isWow64   bool
+)
+
+// runningUnderWow64 checks whether this is a 32-bit process on 64-bit
+// Windows.
+func runningUnderWow64() bool {
+	wow64Once.Do(func() {
+		if unsafe.Sizeof(uintptr(0)) == 8 {
+			return
+		}
+		windows.IsWow64Process(windows.CurrentProcess(), &isWow64)
+	})
+
+	return isWow64
+}
+
+// disableFsRedirection turns off the file system redirection of WOW64 for
+// the calling goroutine until the returned function is called. The
+// bitness of the collector and of Windows matter as follows:
+//
+//   - A 64-bit collector on 64-bit Windows, or a 32-bit collector on 32-bit
+//     Windows, sees the file system as it is. Nothing needs to be done.
+//   - A 32-bit collector on 64-bit Windows has System32 transparently
+//     redirected to SysWOW64, so that it would resolve, hash and verify
+//     the 32-bit copies of system binaries instead of the 64-bit ones which
+//     are actually launched. Redirection is disabled for it.
+//
+// Redirection is a setting of the thread, so the goroutine is locked to its
+// thread in the meantime.
+func disableFsRedirection() func() {
+	if !runningUnderWow64() {
+		return func() {}
+	}
+
+	runtime.LockOSThread()
+
+	var oldValue uintptr
+	r, _, _ := procWow64DisableWow64FsRedirection.Call(uintptr(unsafe.Pointer(&oldValue)))
+	if r == 0 {
+		runtime.UnlockOSThread()
+		return func() {}
+	}
+
+	return func() {
+		procWow64RevertWow64FsRedirection.Call(oldValue)
+		runtime.UnlockOSThread()
+	}
+}